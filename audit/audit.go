@@ -0,0 +1,185 @@
+// 数据变更审计
+// * 通过 mgo.Store 的写入钩子记录 Store.InsertOne / UpdateOne / ReplaceOne / DeleteOne, Store.Bulk 及 Store.ImportFile 的写入操作
+// * 记录保存在审计集合中, 通过 TTL 索引自动清理过期记录
+package audit
+
+import (
+	"context"
+	"github.com/cbwfree/micro-core/fn"
+	mgo "github.com/cbwfree/micro-core/store/mongo"
+	log "github.com/micro/go-micro/v2/logger"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"reflect"
+	"time"
+)
+
+const (
+	DefaultTableName = "audit_log"         // 默认审计集合名称
+	DefaultRetention = 90 * 24 * time.Hour // 默认保留时间
+)
+
+// 字段变化
+type Change struct {
+	From interface{} `bson:"from,omitempty" json:"from,omitempty"`
+	To   interface{} `bson:"to,omitempty" json:"to,omitempty"`
+}
+
+// 审计记录
+type Record struct {
+	Id         string             `bson:"_id" json:"id"`
+	Actor      string             `bson:"actor" json:"actor"`           // 操作者
+	Op         string             `bson:"op" json:"op"`                 // 写入操作
+	Db         string             `bson:"db" json:"db"`                 // 数据库
+	Collection string             `bson:"col" json:"col"`               // 集合
+	DocumentId interface{}        `bson:"doc_id" json:"doc_id"`         // 文档ID
+	Diff       map[string]*Change `bson:"diff" json:"diff"`             // 字段变化
+	RequestId  string             `bson:"request_id" json:"request_id"` // 请求ID
+	Time       time.Time          `bson:"time" json:"time"`             // 操作时间
+}
+
+type Option func(o *Options)
+
+type Options struct {
+	Table     string        // 审计集合名称
+	Retention time.Duration // 保留时间
+	Include   []string      // 仅记录指定集合, 为空时记录全部
+	Exclude   []string      // 不记录的集合
+}
+
+func WithTable(name string) Option {
+	return func(o *Options) {
+		o.Table = name
+	}
+}
+
+func WithRetention(t time.Duration) Option {
+	return func(o *Options) {
+		o.Retention = t
+	}
+}
+
+func WithInclude(cols ...string) Option {
+	return func(o *Options) {
+		o.Include = append(o.Include, cols...)
+	}
+}
+
+func WithExclude(cols ...string) Option {
+	return func(o *Options) {
+		o.Exclude = append(o.Exclude, cols...)
+	}
+}
+
+// 审计
+type Auditor struct {
+	store *mgo.Store
+	opts  *Options
+}
+
+func (a *Auditor) Opts() *Options {
+	return a.opts
+}
+
+// C 获取审计集合
+func (a *Auditor) C() *mongo.Collection {
+	return a.store.C(a.opts.Table)
+}
+
+// Table 获取审计数据表定义 (包含TTL索引), 可加入 mgo.Tables 自动初始化
+func (a *Auditor) Table() *mgo.Table {
+	tab := mgo.NewTable(a.opts.Table, Record{})
+	tab.SetIndex(a.indexes())
+	return tab
+}
+
+func (a *Auditor) indexes() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "time", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(a.opts.Retention / time.Second)),
+		},
+		{Keys: bson.D{{Key: "col", Value: 1}, {Key: "doc_id", Value: 1}}},
+		{Keys: bson.D{{Key: "actor", Value: 1}}},
+		{Keys: bson.D{{Key: "request_id", Value: 1}}},
+	}
+}
+
+// EnsureIndex 创建审计集合索引
+func (a *Auditor) EnsureIndex(ctx context.Context) error {
+	_, err := a.C().Indexes().CreateMany(ctx, a.indexes())
+	return err
+}
+
+// 写入钩子
+func (a *Auditor) hook(ctx context.Context, ev *mgo.WriteEvent) {
+	if ev.Collection == a.opts.Table {
+		return
+	}
+	if len(a.opts.Include) > 0 && !fn.InStrSlice(ev.Collection, a.opts.Include) {
+		return
+	}
+	if fn.InStrSlice(ev.Collection, a.opts.Exclude) {
+		return
+	}
+
+	record := &Record{
+		Id:         fn.UUID(),
+		Actor:      Actor(ctx),
+		Op:         ev.Op,
+		Db:         ev.Db,
+		Collection: ev.Collection,
+		DocumentId: ev.DocumentId,
+		RequestId:  RequestId(ctx),
+		Time:       time.Now(),
+	}
+	// 更新/替换后未能查询到最新文档时不记录差异
+	if ev.After != nil || ev.Op == mgo.WriteDelete {
+		record.Diff = Diff(ev.Before, ev.After)
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if _, err := a.C().InsertOne(ctx, record); err != nil {
+		log.Errorf("write audit log [%s][%s] failure, error: %s", ev.Collection, ev.Op, err.Error())
+	}
+}
+
+// New 实例化审计并注册 store 的写入钩子
+func New(store *mgo.Store, opts ...Option) *Auditor {
+	o := &Options{
+		Table:     DefaultTableName,
+		Retention: DefaultRetention,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	a := &Auditor{
+		store: store,
+		opts:  o,
+	}
+	store.AddWriteHook(a.hook)
+
+	return a
+}
+
+// Diff 比较文档差异
+func Diff(before, after bson.M) map[string]*Change {
+	diff := make(map[string]*Change)
+	for k, v := range before {
+		if nv, ok := after[k]; !ok {
+			diff[k] = &Change{From: v}
+		} else if !reflect.DeepEqual(v, nv) {
+			diff[k] = &Change{From: v, To: nv}
+		}
+	}
+	for k, v := range after {
+		if _, ok := before[k]; !ok {
+			diff[k] = &Change{To: v}
+		}
+	}
+	return diff
+}
@@ -0,0 +1,113 @@
+package audit
+
+import (
+	"context"
+	"github.com/cbwfree/micro-core/conv"
+	"github.com/cbwfree/micro-core/jwt"
+	"github.com/cbwfree/micro-core/meta"
+	"github.com/cbwfree/micro-core/web"
+	"github.com/labstack/echo/v4"
+	"github.com/micro/go-micro/v2/metadata"
+)
+
+const (
+	MetaActor     = "Audit-Actor"  // 操作者 meta 名称
+	MetaRequestId = "X-Request-Id" // 请求ID meta 名称
+)
+
+type ctxActorKey struct{}
+type ctxRequestIdKey struct{}
+type ctxClaimsKey struct{}
+
+// WithActor 在 context 中设置操作者
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, ctxActorKey{}, actor)
+}
+
+// WithRequestId 在 context 中设置请求ID
+func WithRequestId(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxRequestIdKey{}, id)
+}
+
+// SetMetaActor 在 meta 中设置操作者, 通过 m.Context() 调用服务时随 metadata 传递
+func SetMetaActor(m *meta.Meta, actor string) {
+	m.SetValue(MetaActor, actor)
+}
+
+// WithClaims 在 context 中设置JWT声明, 以 Subject 作为操作者
+func WithClaims(ctx context.Context, claims *jwt.Claims) context.Context {
+	return context.WithValue(ctx, ctxClaimsKey{}, claims)
+}
+
+// Actor 获取操作者
+// 依次从 context, JWT声明, meta 的 Audit-Actor (SetMetaActor 设置) 及 Ws-User-Id (Socket连接认证用户) 中获取
+func Actor(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	if actor, ok := ctx.Value(ctxActorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	if claims, ok := ctx.Value(ctxClaimsKey{}).(*jwt.Claims); ok && claims != nil {
+		if claims.Subject != "" {
+			return claims.Subject
+		}
+		if claims.Data != nil {
+			return conv.String(claims.Data)
+		}
+	}
+	if m, err := meta.FromMeta(ctx); err == nil {
+		if actor := m.Get(MetaActor); actor != "" {
+			return actor
+		}
+		if actor := m.Get(web.MetaUserId); actor != "" {
+			return actor
+		}
+	}
+	return ""
+}
+
+// RequestId 获取请求ID
+func RequestId(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	if id, ok := ctx.Value(ctxRequestIdKey{}).(string); ok && id != "" {
+		return id
+	}
+	if md, ok := metadata.FromContext(ctx); ok {
+		if id, ok := md.Get(MetaRequestId); ok {
+			return id
+		}
+	}
+	return ""
+}
+
+// ActorFunc 从请求中获取操作者
+type ActorFunc func(c echo.Context) string
+
+// Middleware 将操作者及请求ID写入请求的 context, 处理函数应使用 c.Request().Context() 调用写入函数
+func Middleware(actor ActorFunc) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			ctx := c.Request().Context()
+			if actor != nil {
+				if a := actor(c); a != "" {
+					ctx = WithActor(ctx, a)
+				}
+			}
+
+			id := c.Request().Header.Get(echo.HeaderXRequestID)
+			if id == "" {
+				id = c.Response().Header().Get(echo.HeaderXRequestID)
+			}
+			if id != "" {
+				ctx = WithRequestId(ctx, id)
+			}
+
+			c.SetRequest(c.Request().WithContext(ctx))
+
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package audit
+
+import (
+	"context"
+	mgo "github.com/cbwfree/micro-core/store/mongo"
+	"github.com/cbwfree/micro-core/web"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"strconv"
+	"time"
+)
+
+// 审计记录查询条件
+type Filter struct {
+	Actor      string `query:"actor" json:"actor"`
+	Op         string `query:"op" json:"op"`
+	Collection string `query:"col" json:"col"`
+	DocumentId string `query:"doc_id" json:"doc_id"`
+	RequestId  string `query:"request_id" json:"request_id"`
+	Start      int64  `query:"start" json:"start"` // 开始时间 (unix时间戳)
+	End        int64  `query:"end" json:"end"`     // 结束时间 (unix时间戳)
+	Cur        int64  `query:"cur" json:"cur"`
+	Size       int64  `query:"size" json:"size"`
+}
+
+func (f *Filter) bson() bson.M {
+	filter := bson.M{}
+	if f.Actor != "" {
+		filter["actor"] = f.Actor
+	}
+	if f.Op != "" {
+		filter["op"] = f.Op
+	}
+	if f.Collection != "" {
+		filter["col"] = f.Collection
+	}
+	if f.DocumentId != "" {
+		// 文档ID可能为字符串, ObjectID 或数字
+		ids := []interface{}{f.DocumentId}
+		if oid, err := primitive.ObjectIDFromHex(f.DocumentId); err == nil {
+			ids = append(ids, oid)
+		}
+		if n, err := strconv.ParseInt(f.DocumentId, 10, 64); err == nil {
+			ids = append(ids, n)
+		}
+		filter["doc_id"] = bson.M{"$in": ids}
+	}
+	if f.RequestId != "" {
+		filter["request_id"] = f.RequestId
+	}
+	if f.Start > 0 || f.End > 0 {
+		t := bson.M{}
+		if f.Start > 0 {
+			t["$gte"] = time.Unix(f.Start, 0)
+		}
+		if f.End > 0 {
+			t["$lte"] = time.Unix(f.End, 0)
+		}
+		filter["time"] = t
+	}
+	return filter
+}
+
+// 审计记录查询结果
+type QueryResult struct {
+	List []*Record `json:"list"`
+	Scan *mgo.Scan `json:"scan"`
+}
+
+// Query 查询审计记录 (按时间倒序)
+func (a *Auditor) Query(ctx context.Context, f *Filter) *QueryResult {
+	var rows []*Record
+	scan := mgo.FindScan(ctx, a.C(), f.Cur, f.Size, f.bson(), &rows, func(opts *options.FindOptions) *options.FindOptions {
+		return opts.SetSort(bson.D{{Key: "time", Value: -1}})
+	})
+	return &QueryResult{List: rows, Scan: scan}
+}
+
+// History 查询文档变更历史
+func (a *Auditor) History(ctx context.Context, col string, docId interface{}) ([]*Record, error) {
+	var rows []*Record
+	opts := options.Find().SetSort(bson.D{{Key: "time", Value: 1}})
+	if err := mgo.FindAll(ctx, a.C(), bson.M{"col": col, "doc_id": docId}, &rows, opts); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// Route 注册审计记录查询路由
+func (a *Auditor) Route(path string, m ...echo.MiddlewareFunc) web.Route {
+	return func(g *echo.Group) {
+		g.GET(path, func(c echo.Context) error {
+			ctx := web.ExtendCtx(c)
+
+			var f = new(Filter)
+			if err := ctx.Bind(f); err != nil {
+				return ctx.Error(err)
+			}
+
+			return ctx.JsonSuccess(a.Query(c.Request().Context(), f))
+		}, m...)
+	}
+}
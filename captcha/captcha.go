@@ -0,0 +1,106 @@
+package captcha
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"github.com/google/uuid"
+	"github.com/steambap/captcha"
+	"io"
+	"strings"
+	"time"
+)
+
+// 验证码存储
+type Store interface {
+	// Save 保存验证码答案
+	Save(ctx context.Context, id, answer string, expire time.Duration) error
+	// Verify 校验验证码, 校验成功或超出尝试次数时删除验证码, 验证码不存在时返回 false
+	Verify(ctx context.Context, id, answer string, maxAttempts int) (bool, error)
+}
+
+// 验证码
+type Captcha struct {
+	Id     string `json:"id"`
+	Image  string `json:"image"`
+	Expire int64  `json:"expire"`
+}
+
+// 验证码服务
+type Service struct {
+	opts  *Options
+	store Store
+}
+
+func (s *Service) Opts() *Options {
+	return s.opts
+}
+
+// Generate 生成验证码图片并保存答案
+func (s *Service) Generate(ctx context.Context) (string, *captcha.Data, error) {
+	var data *captcha.Data
+	var err error
+	if s.opts.MathExpr {
+		data, err = captcha.NewMathExpr(s.opts.Width, s.opts.Height, s.opts.Style)
+	} else {
+		data, err = captcha.New(s.opts.Width, s.opts.Height, s.opts.Style)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	id := strings.ReplaceAll(uuid.New().String(), "-", "")
+	if err := s.store.Save(ctx, id, normalize(data.Text), s.opts.Expire); err != nil {
+		return "", nil, err
+	}
+
+	return id, data, nil
+}
+
+// WriteImage 生成验证码并写入PNG图片, 返回验证码ID
+func (s *Service) WriteImage(ctx context.Context, w io.Writer) (string, error) {
+	id, data, err := s.Generate(ctx)
+	if err != nil {
+		return "", err
+	}
+	return id, data.WriteImage(w)
+}
+
+// New 生成验证码, 图片为BASE64编码的Data URI
+func (s *Service) New(ctx context.Context) (*Captcha, error) {
+	id, data, err := s.Generate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := data.WriteImage(&buf); err != nil {
+		return nil, err
+	}
+
+	return &Captcha{
+		Id:     id,
+		Image:  "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()),
+		Expire: time.Now().Add(s.opts.Expire).Unix(),
+	}, nil
+}
+
+// Verify 校验验证码 (验证码仅能成功使用一次)
+func (s *Service) Verify(ctx context.Context, id, answer string) (bool, error) {
+	if id == "" || answer == "" {
+		return false, nil
+	}
+	return s.store.Verify(ctx, id, normalize(answer), s.opts.MaxAttempts)
+}
+
+// NewService 实例化验证码服务
+func NewService(store Store, opts ...Option) *Service {
+	return &Service{
+		opts:  newOptions(opts...),
+		store: store,
+	}
+}
+
+func normalize(answer string) string {
+	return strings.ToLower(strings.TrimSpace(answer))
+}
@@ -0,0 +1,84 @@
+package captcha
+
+import (
+	"github.com/steambap/captcha"
+	"image/color"
+	"time"
+)
+
+const (
+	DefaultWidth       = 120
+	DefaultHeight      = 40
+	DefaultExpire      = 5 * time.Minute
+	DefaultMaxAttempts = 5
+)
+
+type Option func(o *Options)
+
+type Options struct {
+	Width       int               // 图片宽度
+	Height      int               // 图片高度
+	Expire      time.Duration     // 有效期
+	MaxAttempts int               // 单个验证码最多尝试次数
+	MathExpr    bool              // 是否使用算式验证码
+	Style       captcha.SetOption // 图片样式
+}
+
+func (o *Options) Init(opts ...Option) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+func newOptions(opts ...Option) *Options {
+	o := &Options{
+		Width:       DefaultWidth,
+		Height:      DefaultHeight,
+		Expire:      DefaultExpire,
+		MaxAttempts: DefaultMaxAttempts,
+		Style: func(opt *captcha.Options) {
+			opt.BackgroundColor = color.White
+			opt.CharPreset = "0123456789"
+			opt.CurveNumber = 1
+			opt.FontDPI = 80
+		},
+	}
+	o.Init(opts...)
+	return o
+}
+
+// 图片尺寸
+func Size(width, height int) Option {
+	return func(o *Options) {
+		o.Width = width
+		o.Height = height
+	}
+}
+
+// 有效期
+func Expire(expire time.Duration) Option {
+	return func(o *Options) {
+		o.Expire = expire
+	}
+}
+
+// 最多尝试次数
+func MaxAttempts(n int) Option {
+	return func(o *Options) {
+		o.MaxAttempts = n
+	}
+}
+
+// 使用算式验证码
+func MathExpr() Option {
+	return func(o *Options) {
+		o.MathExpr = true
+	}
+}
+
+// 图片样式
+func Style(style captcha.SetOption) Option {
+	return func(o *Options) {
+		o.Style = style
+	}
+}
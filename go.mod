@@ -9,7 +9,9 @@ require (
 	github.com/go-playground/universal-translator v0.17.0
 	github.com/go-redis/redis/v7 v7.2.0
 	github.com/golang/freetype v0.0.0-20170609003504-e2365dfdc4a0 // indirect
+	github.com/golang/protobuf v1.3.5
 	github.com/google/uuid v1.1.1
+	github.com/gorilla/securecookie v1.1.1
 	github.com/gorilla/sessions v1.2.0
 	github.com/gorilla/websocket v1.4.1
 	github.com/labstack/echo-contrib v0.9.0
@@ -0,0 +1,33 @@
+// ID生成器
+// * Segment 号段模式, 每次从存储中预留一段ID, 在本地依次分配
+// * Snowflake 雪花算法, 由时间戳+节点ID+序列号组成, 无需访问存储
+package idgen
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrInvalidStep   = errors.New("invalid id step")
+	ErrInvalidId     = errors.New("invalid id")
+	ErrClockBackward = errors.New("clock moved backwards")
+	ErrNoWorker      = errors.New("no free snowflake worker id")
+	ErrWorkerLease   = errors.New("snowflake worker lease expired")
+)
+
+// Generator ID生成器接口
+type Generator interface {
+	// Next 获取指定名称的下一个ID
+	Next(ctx context.Context, name string) (int64, error)
+}
+
+// GeneratorFunc 函数形式的ID生成器
+type GeneratorFunc func(ctx context.Context, name string) (int64, error)
+
+func (f GeneratorFunc) Next(ctx context.Context, name string) (int64, error) {
+	return f(ctx, name)
+}
+
+// Allocator 号段分配函数, 将存储中的计数增加 step 并返回增加后的值
+type Allocator func(ctx context.Context, name string, step int64) (int64, error)
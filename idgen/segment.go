@@ -0,0 +1,103 @@
+package idgen
+
+import (
+	"context"
+	"sync"
+)
+
+const (
+	DefaultSegmentStep int64 = 1000 // 默认号段大小
+)
+
+// 号段
+type segment struct {
+	sync.Mutex
+	cur int64 // 当前已分配的ID
+	max int64 // 当前号段最大ID
+}
+
+// Segment 号段ID生成器
+type Segment struct {
+	sync.RWMutex
+	alloc    Allocator
+	step     int64
+	segments map[string]*segment
+}
+
+func (s *Segment) Step() int64 {
+	return s.step
+}
+
+func (s *Segment) get(name string) *segment {
+	s.RLock()
+	seg, ok := s.segments[name]
+	s.RUnlock()
+	if ok {
+		return seg
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	if seg, ok = s.segments[name]; !ok {
+		seg = new(segment)
+		s.segments[name] = seg
+	}
+
+	return seg
+}
+
+// Next 获取下一个ID, 当前号段用完时重新申请号段
+func (s *Segment) Next(ctx context.Context, name string) (int64, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	seg := s.get(name)
+
+	seg.Lock()
+	defer seg.Unlock()
+
+	if seg.cur >= seg.max {
+		max, err := s.alloc(ctx, name, s.step)
+		if err != nil {
+			return 0, err
+		} else if max < s.step {
+			return 0, ErrInvalidId
+		}
+		seg.cur = max - s.step
+		seg.max = max
+	}
+
+	seg.cur++
+
+	return seg.cur, nil
+}
+
+// Reset 丢弃本地剩余号段
+func (s *Segment) Reset(name ...string) {
+	s.Lock()
+	defer s.Unlock()
+
+	if len(name) == 0 {
+		s.segments = make(map[string]*segment)
+		return
+	}
+
+	for _, n := range name {
+		delete(s.segments, n)
+	}
+}
+
+// NewSegment 实例化号段ID生成器
+func NewSegment(alloc Allocator, step ...int64) *Segment {
+	s := &Segment{
+		alloc:    alloc,
+		step:     DefaultSegmentStep,
+		segments: make(map[string]*segment),
+	}
+	if len(step) > 0 && step[0] > 0 {
+		s.step = step[0]
+	}
+	return s
+}
@@ -0,0 +1,159 @@
+package idgen
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	SnowflakeWorkerBits   = 10 // 节点ID位数
+	SnowflakeSequenceBits = 12 // 序列号位数
+
+	SnowflakeMaxWorker   = -1 ^ (-1 << SnowflakeWorkerBits)
+	SnowflakeMaxSequence = -1 ^ (-1 << SnowflakeSequenceBits)
+
+	snowflakeTimeShift   = SnowflakeWorkerBits + SnowflakeSequenceBits
+	snowflakeWorkerShift = SnowflakeSequenceBits
+)
+
+var (
+	// 起始时间 2020-01-01 00:00:00 UTC
+	SnowflakeEpoch = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// WorkerLease 节点ID租约, 用于从存储中分配不重复的节点ID
+type WorkerLease interface {
+	// Worker 租约的节点ID
+	Worker() int64
+	// Alive 租约是否有效, 失效后节点ID可能已分配给其他节点
+	Alive() bool
+	// Release 停止续约并释放节点ID
+	Release() error
+}
+
+// WorkerLeaser 租用节点ID
+type WorkerLeaser func() (WorkerLease, error)
+
+// Snowflake 雪花算法ID生成器
+type Snowflake struct {
+	sync.Mutex
+	epoch    int64        // 起始时间 (毫秒)
+	worker   int64        // 节点ID
+	lease    WorkerLease  // 节点ID租约, 为空时节点ID由配置指定
+	leaser   WorkerLeaser // 租约失效时重新租用节点ID
+	last     int64        // 上次生成ID的时间 (毫秒)
+	sequence int64        // 当前毫秒内的序列号
+}
+
+func (s *Snowflake) Worker() int64 {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.worker
+}
+
+// Next 生成ID, 雪花算法不区分名称
+func (s *Snowflake) Next(_ context.Context, _ string) (int64, error) {
+	return s.Generate()
+}
+
+// Generate 生成ID
+func (s *Snowflake) Generate() (int64, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.lease != nil && !s.lease.Alive() {
+		if err := s.renew(); err != nil {
+			return 0, err
+		}
+	}
+
+	now := s.now()
+	if now < s.last {
+		// 时钟小幅回拨时等待追平, 否则返回错误
+		if s.last-now > 5 {
+			return 0, ErrClockBackward
+		}
+		for now < s.last {
+			time.Sleep(time.Millisecond)
+			now = s.now()
+		}
+	}
+
+	if now == s.last {
+		s.sequence = (s.sequence + 1) & SnowflakeMaxSequence
+		if s.sequence == 0 {
+			// 当前毫秒序列号已用完, 等待下一毫秒
+			for now <= s.last {
+				now = s.now()
+			}
+		}
+	} else {
+		s.sequence = 0
+	}
+
+	s.last = now
+
+	return (now-s.epoch)<<snowflakeTimeShift | s.worker<<snowflakeWorkerShift | s.sequence, nil
+}
+
+// Release 释放节点ID租约, 释放后不能再生成ID
+func (s *Snowflake) Release() error {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.lease == nil {
+		return nil
+	}
+	s.leaser = nil
+	return s.lease.Release()
+}
+
+// 租约失效后释放旧租约并重新租用节点ID, 失败时保留旧租约以便下次重试
+func (s *Snowflake) renew() error {
+	if s.leaser == nil {
+		return ErrWorkerLease
+	}
+
+	_ = s.lease.Release()
+	lease, err := s.leaser()
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrWorkerLease, err)
+	}
+	s.lease = lease
+	s.worker = lease.Worker() & SnowflakeMaxWorker
+	return nil
+}
+
+func (s *Snowflake) now() int64 {
+	return time.Now().UnixNano() / int64(time.Millisecond)
+}
+
+// NewSnowflake 实例化雪花算法ID生成器, 节点ID需由配置指定且各节点唯一
+func NewSnowflake(worker int64) *Snowflake {
+	return &Snowflake{
+		epoch:  SnowflakeEpoch.UnixNano() / int64(time.Millisecond),
+		worker: worker & SnowflakeMaxWorker,
+	}
+}
+
+// NewLeasedSnowflake 使用租约的节点ID实例化雪花算法ID生成器
+// 租约失效后在下次生成ID时重新租用节点ID, 租用失败时返回 ErrWorkerLease
+func NewLeasedSnowflake(leaser WorkerLeaser) (*Snowflake, error) {
+	lease, err := leaser()
+	if err != nil {
+		return nil, err
+	}
+	s := NewSnowflake(lease.Worker())
+	s.lease = lease
+	s.leaser = leaser
+	return s, nil
+}
+
+// SnowflakeTime 解析ID的生成时间
+func SnowflakeTime(id int64) time.Time {
+	ms := id >> snowflakeTimeShift
+	return SnowflakeEpoch.Add(time.Duration(ms) * time.Millisecond)
+}
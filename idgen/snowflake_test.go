@@ -0,0 +1,139 @@
+package idgen
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+type testLease struct {
+	sync.Mutex
+	worker   int64
+	alive    bool
+	released bool
+}
+
+func (l *testLease) Worker() int64 {
+	return l.worker
+}
+
+func (l *testLease) Alive() bool {
+	l.Lock()
+	defer l.Unlock()
+	return l.alive
+}
+
+func (l *testLease) Release() error {
+	l.Lock()
+	defer l.Unlock()
+	l.alive = false
+	l.released = true
+	return nil
+}
+
+func (l *testLease) lose() {
+	l.Lock()
+	defer l.Unlock()
+	l.alive = false
+}
+
+// 按顺序分配节点ID, fail 为true时租用失败
+type testLeaser struct {
+	leases []*testLease
+	fail   bool
+}
+
+func (tl *testLeaser) lease() (WorkerLease, error) {
+	if tl.fail {
+		return nil, errors.New("redis unavailable")
+	}
+	l := &testLease{worker: int64(len(tl.leases) + 1), alive: true}
+	tl.leases = append(tl.leases, l)
+	return l, nil
+}
+
+func snowflakeWorker(id int64) int64 {
+	return id >> snowflakeWorkerShift & SnowflakeMaxWorker
+}
+
+func TestSnowflakeUnique(t *testing.T) {
+	s := NewSnowflake(1)
+	seen := make(map[int64]bool)
+	for i := 0; i < 100000; i++ {
+		id, err := s.Generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if seen[id] {
+			t.Fatalf("duplicate id %d", id)
+		}
+		seen[id] = true
+		if w := snowflakeWorker(id); w != 1 {
+			t.Fatalf("want worker 1, got %d", w)
+		}
+	}
+}
+
+func TestSnowflakeLeaseLost(t *testing.T) {
+	tl := &testLeaser{}
+	s, err := NewLeasedSnowflake(tl.lease)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := s.Generate()
+	if err != nil || snowflakeWorker(id) != 1 {
+		t.Fatalf("want worker 1, got %d, %v", snowflakeWorker(id), err)
+	}
+
+	// 租约丢失且无法重新租用时返回错误
+	tl.leases[0].lose()
+	tl.fail = true
+	if _, err := s.Generate(); !errors.Is(err, ErrWorkerLease) {
+		t.Fatalf("want ErrWorkerLease, got %v", err)
+	}
+	if !tl.leases[0].released {
+		t.Fatal("lost lease not released")
+	}
+
+	// 存储恢复后重新租用新的节点ID
+	tl.fail = false
+	id, err = s.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := snowflakeWorker(id); w != 2 || s.Worker() != 2 {
+		t.Fatalf("want worker 2, got %d", w)
+	}
+	if len(tl.leases) != 2 {
+		t.Fatalf("want 2 leases, got %d", len(tl.leases))
+	}
+
+	// 租约有效时不重新租用
+	for i := 0; i < 10; i++ {
+		if _, err := s.Generate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(tl.leases) != 2 {
+		t.Fatalf("unexpected lease count %d", len(tl.leases))
+	}
+
+	// 释放后不再重新租用
+	if err := s.Release(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Generate(); err != ErrWorkerLease {
+		t.Fatalf("want ErrWorkerLease after release, got %v", err)
+	}
+	if len(tl.leases) != 2 {
+		t.Fatalf("leased after release: %d", len(tl.leases))
+	}
+}
+
+func TestSnowflakeLeaseError(t *testing.T) {
+	tl := &testLeaser{fail: true}
+	if _, err := NewLeasedSnowflake(tl.lease); err == nil {
+		t.Fatal("want error")
+	}
+}
@@ -1,8 +1,30 @@
 package jwt
 
-import "github.com/dgrijalva/jwt-go"
+import (
+	"github.com/dgrijalva/jwt-go"
+	"time"
+)
+
+const (
+	TypeRefresh = "refresh" // 刷新令牌类型
+)
 
 type Claims struct {
 	jwt.StandardClaims
-	Data interface{} `json:"data,omitempty"`
+	Type   string      `json:"typ,omitempty"`    // 令牌类型, 访问令牌为空
+	Family string      `json:"fam,omitempty"`    // 刷新令牌家族ID
+	IatMs  int64       `json:"iat_ms,omitempty"` // 签发时间 (毫秒), 用于与主体吊销时间比较
+	Data   interface{} `json:"data,omitempty"`
+}
+
+// 签发时间 (毫秒), 没有 iat_ms 的令牌使用 iat (秒)
+func (c *Claims) issuedAtMs() int64 {
+	if c.IatMs > 0 {
+		return c.IatMs
+	}
+	return c.IssuedAt * 1000
+}
+
+func unixMilli(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
 }
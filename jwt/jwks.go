@@ -0,0 +1,233 @@
+package jwt
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"github.com/dgrijalva/jwt-go"
+	"math/big"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultJWKSCache    = 10 * time.Minute // 默认远程JWKS缓存时间
+	DefaultJWKSMinFetch = 10 * time.Second // 未找到秘钥时两次拉取的最小间隔
+)
+
+// 秘钥提供者
+type KeyProvider interface {
+	SigningKey() *Key
+	Lookup(kid string) (*Key, error)
+}
+
+// JSON Web Key
+type JWK struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Alg string `json:"alg,omitempty"`
+	Use string `json:"use,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JSON Web Key Set
+type JWKS struct {
+	Keys []*JWK `json:"keys"`
+}
+
+// NewJWK 将公钥转换为JWK
+func NewJWK(k *Key) (*JWK, error) {
+	jwk := &JWK{
+		Kid: k.Id,
+		Alg: k.Method.Alg(),
+		Use: "sig",
+	}
+
+	switch pk := k.Public.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = encodeBase64(pk.N.Bytes())
+		jwk.E = encodeBase64(big.NewInt(int64(pk.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (pk.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = pk.Curve.Params().Name
+		jwk.X = encodeBase64(padBytes(pk.X.Bytes(), size))
+		jwk.Y = encodeBase64(padBytes(pk.Y.Bytes(), size))
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = encodeBase64(pk)
+	default:
+		return nil, ErrInvalidKeyType
+	}
+
+	return jwk, nil
+}
+
+// Key 将JWK转换为验证秘钥
+func (j *JWK) Key() (*Key, error) {
+	method := jwt.GetSigningMethod(j.Alg)
+	if method == nil {
+		return nil, ErrInvalidKey
+	}
+
+	var public interface{}
+	switch j.Kty {
+	case "RSA":
+		n, err := decodeBase64(j.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBase64(j.E)
+		if err != nil {
+			return nil, err
+		}
+		public = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	case "EC":
+		var curve elliptic.Curve
+		switch j.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, ErrInvalidKey
+		}
+		x, err := decodeBase64(j.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBase64(j.Y)
+		if err != nil {
+			return nil, err
+		}
+		public = &ecdsa.PublicKey{
+			Curve: curve,
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}
+	case "OKP":
+		x, err := decodeBase64(j.X)
+		if err != nil {
+			return nil, err
+		}
+		if j.Crv != "Ed25519" || len(x) != ed25519.PublicKeySize {
+			return nil, ErrInvalidKey
+		}
+		public = ed25519.PublicKey(x)
+	default:
+		return nil, ErrInvalidKeyType
+	}
+
+	return NewPublicKey(j.Kid, method, public)
+}
+
+// JWKSFetcher 拉取远程JWKS
+type JWKSFetcher func(ctx context.Context) (*JWKS, error)
+
+// 远程秘钥集合 (仅用于验证)
+type RemoteKeySet struct {
+	sync.RWMutex
+	fetch     JWKSFetcher
+	cache     time.Duration
+	keys      map[string]*Key
+	fetchTime time.Time
+}
+
+func (rk *RemoteKeySet) SigningKey() *Key {
+	return nil
+}
+
+// Lookup 获取验证秘钥, 缓存过期或未找到秘钥时重新拉取
+func (rk *RemoteKeySet) Lookup(kid string) (*Key, error) {
+	rk.RLock()
+	key, ok := rk.keys[kid]
+	expired := time.Since(rk.fetchTime) > rk.cache
+	allow := time.Since(rk.fetchTime) > DefaultJWKSMinFetch
+	rk.RUnlock()
+
+	if ok && !expired {
+		return key, nil
+	}
+
+	if expired || allow {
+		if err := rk.Refresh(context.Background()); err != nil && !ok {
+			return nil, err
+		}
+	}
+
+	rk.RLock()
+	defer rk.RUnlock()
+
+	if key, ok := rk.keys[kid]; ok {
+		return key, nil
+	}
+
+	return nil, ErrKeyNotFound
+}
+
+// Refresh 重新拉取远程JWKS
+func (rk *RemoteKeySet) Refresh(ctx context.Context) error {
+	set, err := rk.fetch(ctx)
+
+	rk.Lock()
+	defer rk.Unlock()
+
+	rk.fetchTime = time.Now()
+	if err != nil {
+		return err
+	}
+
+	keys := make(map[string]*Key)
+	for _, jwk := range set.Keys {
+		if k, err := jwk.Key(); err == nil {
+			keys[k.Id] = k
+		}
+	}
+	rk.keys = keys
+
+	return nil
+}
+
+// NewRemoteKeySet 实例化远程秘钥集合
+func NewRemoteKeySet(fetch JWKSFetcher, cache ...time.Duration) *RemoteKeySet {
+	rk := &RemoteKeySet{
+		fetch: fetch,
+		cache: DefaultJWKSCache,
+		keys:  make(map[string]*Key),
+	}
+	if len(cache) > 0 && cache[0] > 0 {
+		rk.cache = cache[0]
+	}
+	return rk
+}
+
+func encodeBase64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeBase64(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(s)
+}
+
+func padBytes(b []byte, size int) []byte {
+	if len(b) >= size {
+		return b
+	}
+	p := make([]byte, size)
+	copy(p[size-len(b):], b)
+	return p
+}
@@ -0,0 +1,210 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/dgrijalva/jwt-go"
+	"io/ioutil"
+	"time"
+)
+
+var (
+	ErrInvalidKey     = errors.New("invalid key")
+	ErrInvalidKeyType = errors.New("invalid key type")
+	ErrKeyNotFound    = errors.New("key not found")
+)
+
+// 签名秘钥
+type Key struct {
+	Id      string            // 秘钥ID (kid)
+	Method  jwt.SigningMethod // 签名方式
+	Private interface{}       // 签名秘钥, HMAC 为 []byte
+	Public  interface{}       // 验证秘钥, HMAC 为 []byte
+	Created time.Time         // 创建时间
+}
+
+// CanSign 是否可以用于签名
+func (k *Key) CanSign() bool {
+	return k.Private != nil
+}
+
+// IsSymmetric 是否为对称秘钥 (对称秘钥不会发布到JWKS)
+func (k *Key) IsSymmetric() bool {
+	_, ok := k.Public.([]byte)
+	return ok
+}
+
+// NewKey 实例化秘钥, 自动根据签名秘钥计算验证秘钥
+func NewKey(kid string, method jwt.SigningMethod, private interface{}) (*Key, error) {
+	if kid == "" {
+		kid = genKeyId()
+	}
+
+	k := &Key{
+		Id:      kid,
+		Method:  method,
+		Private: private,
+		Created: time.Now(),
+	}
+
+	switch pk := private.(type) {
+	case []byte:
+		k.Public = pk
+	case *rsa.PrivateKey:
+		k.Public = &pk.PublicKey
+	case *ecdsa.PrivateKey:
+		k.Public = &pk.PublicKey
+	case ed25519.PrivateKey:
+		k.Public = pk.Public()
+	default:
+		return nil, ErrInvalidKeyType
+	}
+
+	return k, nil
+}
+
+// NewPublicKey 实例化仅用于验证的秘钥
+func NewPublicKey(kid string, method jwt.SigningMethod, public interface{}) (*Key, error) {
+	switch public.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey:
+	default:
+		return nil, ErrInvalidKeyType
+	}
+	return &Key{
+		Id:      kid,
+		Method:  method,
+		Public:  public,
+		Created: time.Now(),
+	}, nil
+}
+
+// NewHMACKey 实例化HMAC秘钥
+func NewHMACKey(kid string, method jwt.SigningMethod, secret string) *Key {
+	k, _ := NewKey(kid, method, []byte(secret))
+	return k
+}
+
+// ParsePrivateKeyPEM 解析PEM格式的私钥 (支持 RSA, ECDSA, Ed25519)
+func ParsePrivateKeyPEM(data []byte) (crypto.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrInvalidKey
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse private key error: %s", err.Error())
+	}
+
+	return key, nil
+}
+
+// ParsePublicKeyPEM 解析PEM格式的公钥 (支持 RSA, ECDSA, Ed25519)
+func ParsePublicKeyPEM(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrInvalidKey
+	}
+
+	if key, err := x509.ParsePKIXPublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if cert, err := x509.ParseCertificate(block.Bytes); err == nil {
+		return cert.PublicKey, nil
+	}
+
+	key, err := x509.ParsePKCS1PublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse public key error: %s", err.Error())
+	}
+
+	return key, nil
+}
+
+// LoadKeyFile 从PEM文件载入签名秘钥
+func LoadKeyFile(kid string, method jwt.SigningMethod, file string) (*Key, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	pk, err := ParsePrivateKeyPEM(b)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewKey(kid, method, pk)
+}
+
+// LoadPublicKeyFile 从PEM文件载入验证秘钥
+func LoadPublicKeyFile(kid string, method jwt.SigningMethod, file string) (*Key, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	pk, err := ParsePublicKeyPEM(b)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewPublicKey(kid, method, pk)
+}
+
+// KeyGenerator 秘钥生成函数, 用于秘钥轮换
+type KeyGenerator func() (*Key, error)
+
+// RSAKeyGenerator RSA秘钥生成 (RS256)
+func RSAKeyGenerator(bits int) KeyGenerator {
+	return func() (*Key, error) {
+		pk, err := rsa.GenerateKey(rand.Reader, bits)
+		if err != nil {
+			return nil, err
+		}
+		return NewKey("", jwt.SigningMethodRS256, pk)
+	}
+}
+
+// ECKeyGenerator ECDSA秘钥生成 (ES256)
+func ECKeyGenerator() KeyGenerator {
+	return func() (*Key, error) {
+		pk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		return NewKey("", jwt.SigningMethodES256, pk)
+	}
+}
+
+// Ed25519KeyGenerator Ed25519秘钥生成 (EdDSA)
+func Ed25519KeyGenerator() KeyGenerator {
+	return func() (*Key, error) {
+		_, pk, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		return NewKey("", SigningMethodEdDSA, pk)
+	}
+}
+
+func genKeyId() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,329 @@
+package jwt
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultMaxKeys         = 3           // 默认保留的验证秘钥数量
+	DefaultKeySyncInterval = time.Minute // 默认秘钥同步间隔
+)
+
+// 秘钥集合 (一个签名秘钥, 多个验证秘钥)
+type KeySet struct {
+	sync.RWMutex
+	signing *Key
+	keys    map[string]*Key
+	maxKeys int
+	exit    chan struct{}
+	store   KeyStore      // 秘钥存储
+	version string        // 已同步的存储版本
+	publish time.Duration // 新秘钥发布后延迟启用签名的时间
+}
+
+// Add 添加秘钥, sign 为 true 时设置为签名秘钥
+func (ks *KeySet) Add(key *Key, sign ...bool) {
+	ks.Lock()
+	defer ks.Unlock()
+
+	ks.keys[key.Id] = key
+	if len(sign) > 0 && sign[0] && key.CanSign() {
+		ks.signing = key
+	}
+}
+
+// Remove 移除验证秘钥 (不能移除当前签名秘钥)
+func (ks *KeySet) Remove(kid string) {
+	ks.Lock()
+	defer ks.Unlock()
+
+	if ks.signing != nil && ks.signing.Id == kid {
+		return
+	}
+	delete(ks.keys, kid)
+}
+
+// SigningKey 获取签名秘钥
+func (ks *KeySet) SigningKey() *Key {
+	ks.RLock()
+	defer ks.RUnlock()
+
+	return ks.signing
+}
+
+// Lookup 根据秘钥ID获取验证秘钥
+func (ks *KeySet) Lookup(kid string) (*Key, error) {
+	ks.RLock()
+	defer ks.RUnlock()
+
+	if k, ok := ks.keys[kid]; ok {
+		return k, nil
+	}
+	return nil, ErrKeyNotFound
+}
+
+// Keys 获取所有验证秘钥 (按创建时间倒序)
+func (ks *KeySet) Keys() []*Key {
+	ks.RLock()
+	defer ks.RUnlock()
+
+	keys := make([]*Key, 0, len(ks.keys))
+	for _, k := range ks.keys {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].Created.After(keys[j].Created)
+	})
+
+	return keys
+}
+
+// Rotate 生成新的签名秘钥, 旧秘钥保留用于验证, 超出保留数量的旧秘钥将被移除
+func (ks *KeySet) Rotate(gen KeyGenerator) error {
+	key, err := gen()
+	if err != nil {
+		return err
+	}
+
+	ks.Add(key, true)
+
+	keys := ks.Keys()
+	if len(keys) > ks.maxKeys {
+		ks.Lock()
+		for _, k := range keys[ks.maxKeys:] {
+			delete(ks.keys, k.Id)
+		}
+		ks.Unlock()
+	}
+
+	return nil
+}
+
+// SetStore 设置秘钥存储, 设置后秘钥集合以存储中的数据为准
+// Add, Remove 及 Rotate 只修改本地秘钥集合, 下次同步时会被存储中的数据覆盖
+func (ks *KeySet) SetStore(store KeyStore) {
+	ks.Lock()
+	defer ks.Unlock()
+
+	ks.store = store
+	ks.version = ""
+}
+
+// Sync 从存储同步秘钥集合, 存储为空时保存当前秘钥集合
+func (ks *KeySet) Sync(ctx context.Context) error {
+	ks.RLock()
+	store := ks.store
+	ks.RUnlock()
+
+	if store == nil {
+		return ErrNoKeyStore
+	}
+
+	data, version, err := store.Load(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(data) == 0 {
+		keys := ks.Keys()
+		if len(keys) == 0 {
+			return nil
+		}
+		if data, err = marshalKeys(keys); err != nil {
+			return err
+		}
+		ok, err := store.Save(ctx, "", keys[0].Id, data)
+		if err != nil {
+			return err
+		}
+		if ok {
+			ks.apply(keys, keys[0].Id)
+			return nil
+		}
+		// 其他实例已先保存, 以存储中的数据为准
+		if data, version, err = store.Load(ctx); err != nil {
+			return err
+		}
+	}
+
+	keys, err := unmarshalKeys(data)
+	if err != nil {
+		return err
+	}
+	ks.apply(keys, version)
+
+	return nil
+}
+
+// 替换秘钥集合, 签名秘钥为发布时间超过延迟时间的最新秘钥, 确保其他实例已同步该秘钥
+func (ks *KeySet) apply(keys []*Key, version string) {
+	ks.Lock()
+	defer ks.Unlock()
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].Created.After(keys[j].Created)
+	})
+
+	ks.keys = make(map[string]*Key, len(keys))
+	ks.signing = nil
+	ks.version = version
+
+	ready := time.Now().Add(-ks.publish)
+	for _, k := range keys {
+		ks.keys[k.Id] = k
+		if ks.signing == nil && k.CanSign() && !k.Created.After(ready) {
+			ks.signing = k
+		}
+	}
+
+	// 全部为新秘钥时 (首次生成), 使用最早的秘钥签名
+	for i := len(keys) - 1; ks.signing == nil && i >= 0; i-- {
+		if keys[i].CanSign() {
+			ks.signing = keys[i]
+		}
+	}
+}
+
+// 同步秘钥集合, 最新秘钥超过轮换间隔时生成新秘钥并保存到存储
+func (ks *KeySet) rotateShared(ctx context.Context, interval time.Duration, gen KeyGenerator) error {
+	if err := ks.Sync(ctx); err != nil {
+		return err
+	}
+
+	keys := ks.Keys()
+	if len(keys) > 0 && time.Since(keys[0].Created) < interval {
+		return nil
+	}
+
+	key, err := gen()
+	if err != nil {
+		return err
+	}
+
+	ks.RLock()
+	store, prev, maxKeys := ks.store, ks.version, ks.maxKeys
+	ks.RUnlock()
+
+	keys = append([]*Key{key}, keys...)
+	if len(keys) > maxKeys {
+		keys = keys[:maxKeys]
+	}
+
+	data, err := marshalKeys(keys)
+	if err != nil {
+		return err
+	}
+
+	ok, err := store.Save(ctx, prev, key.Id, data)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		// 其他实例已完成轮换
+		return ks.Sync(ctx)
+	}
+	ks.apply(keys, key.Id)
+
+	return nil
+}
+
+// StartRotation 定时轮换签名秘钥, 必须先通过 SetStore 设置秘钥存储
+// 所有实例定时从存储同步秘钥集合, 由先检测到秘钥过期的实例生成新秘钥, 新秘钥在一个同步间隔后才用于签名
+// 多实例部署时需使用共享存储 (如 Redis), LocalKeyStore 只适用于单实例
+func (ks *KeySet) StartRotation(interval time.Duration, gen KeyGenerator, onError ...func(error)) error {
+	every := DefaultKeySyncInterval
+	if interval/2 < every {
+		every = interval / 2
+	}
+	if every <= 0 {
+		return ErrRotationInterval
+	}
+
+	ks.Lock()
+	if ks.store == nil {
+		ks.Unlock()
+		return ErrNoKeyStore
+	}
+	if ks.exit != nil {
+		ks.Unlock()
+		return nil
+	}
+	ks.exit = make(chan struct{})
+	ks.publish = every
+	exit := ks.exit
+	ks.Unlock()
+
+	if err := ks.rotateShared(context.Background(), interval, gen); err != nil {
+		ks.StopRotation()
+		return err
+	}
+
+	go func() {
+		ticker := time.NewTicker(every)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-exit:
+				return
+			case <-ticker.C:
+				if err := ks.rotateShared(context.Background(), interval, gen); err != nil && len(onError) > 0 {
+					onError[0](err)
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+// StopRotation 停止轮换
+func (ks *KeySet) StopRotation() {
+	ks.Lock()
+	defer ks.Unlock()
+
+	if ks.exit != nil {
+		close(ks.exit)
+		ks.exit = nil
+	}
+}
+
+// JWKS 获取可发布的公钥集合 (不包含对称秘钥)
+func (ks *KeySet) JWKS() *JWKS {
+	set := &JWKS{Keys: []*JWK{}}
+	for _, k := range ks.Keys() {
+		if k.IsSymmetric() {
+			continue
+		}
+		if jwk, err := NewJWK(k); err == nil {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	return set
+}
+
+// NewKeySet 实例化秘钥集合, 第一个可签名的秘钥作为签名秘钥
+func NewKeySet(keys ...*Key) *KeySet {
+	ks := &KeySet{
+		keys:    make(map[string]*Key),
+		maxKeys: DefaultMaxKeys,
+		publish: DefaultKeySyncInterval,
+	}
+	for _, k := range keys {
+		ks.Add(k, ks.signing == nil)
+	}
+	return ks
+}
+
+// SetMaxKeys 设置轮换时保留的验证秘钥数量
+func (ks *KeySet) SetMaxKeys(n int) {
+	ks.Lock()
+	defer ks.Unlock()
+
+	if n > 0 {
+		ks.maxKeys = n
+	}
+}
@@ -0,0 +1,133 @@
+package jwt
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"github.com/dgrijalva/jwt-go"
+	"testing"
+	"time"
+)
+
+func TestKeysMarshal(t *testing.T) {
+	ec, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	ecKey, _ := NewKey("ec", jwt.SigningMethodES256, ec)
+	pubKey, _ := NewPublicKey("pub", jwt.SigningMethodES256, &ec.PublicKey)
+	edKey, _ := Ed25519KeyGenerator()()
+	keys := []*Key{NewHMACKey("hs", jwt.SigningMethodHS256, "secret"), ecKey, pubKey, edKey}
+
+	data, err := marshalKeys(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := unmarshalKeys(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != len(keys) {
+		t.Fatalf("want %d keys, got %d", len(keys), len(decoded))
+	}
+
+	for i, k := range decoded {
+		if k.Id != keys[i].Id || k.Method.Alg() != keys[i].Method.Alg() || !k.Created.Equal(keys[i].Created) {
+			t.Fatalf("key %s mismatch", keys[i].Id)
+		}
+		if k.CanSign() != keys[i].CanSign() || k.IsSymmetric() != keys[i].IsSymmetric() {
+			t.Fatalf("key %s type mismatch", keys[i].Id)
+		}
+		if !k.CanSign() {
+			continue
+		}
+
+		// 解码后的秘钥签名可被原秘钥验证
+		sig, err := k.Method.Sign("payload", k.Private)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := keys[i].Method.Verify("payload", sig, keys[i].Public); err != nil {
+			t.Fatalf("key %s: %v", k.Id, err)
+		}
+	}
+}
+
+func TestKeySetRotationRequiresStore(t *testing.T) {
+	ks := NewKeySet(NewHMACKey("hs", jwt.SigningMethodHS256, "secret"))
+	if err := ks.StartRotation(time.Hour, Ed25519KeyGenerator()); err != ErrNoKeyStore {
+		t.Fatalf("want ErrNoKeyStore, got %v", err)
+	}
+
+	ks.SetStore(NewLocalKeyStore())
+	if err := ks.StartRotation(0, Ed25519KeyGenerator()); err != ErrRotationInterval {
+		t.Fatalf("want ErrRotationInterval, got %v", err)
+	}
+	if err := ks.StartRotation(time.Hour, Ed25519KeyGenerator()); err != nil {
+		t.Fatal(err)
+	}
+	ks.StopRotation()
+}
+
+func TestKeySetSharedRotation(t *testing.T) {
+	ctx := context.Background()
+	store := NewLocalKeyStore()
+
+	// 两个实例启动时各自生成了不同的秘钥, 以先保存的为准
+	a := NewKeySet(NewHMACKey("a", jwt.SigningMethodHS256, "secret-a"))
+	b := NewKeySet(NewHMACKey("b", jwt.SigningMethodHS256, "secret-b"))
+	a.SetStore(store)
+	b.SetStore(store)
+	if err := a.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if b.SigningKey().Id != "a" {
+		t.Fatalf("want signing key a, got %s", b.SigningKey().Id)
+	}
+	if _, err := b.Lookup("b"); err != ErrKeyNotFound {
+		t.Fatalf("local key b not replaced: %v", err)
+	}
+
+	// 实例 a 轮换, 新秘钥在同步间隔内只用于验证
+	a.publish, b.publish = time.Hour, time.Hour
+	if err := a.rotateShared(ctx, time.Nanosecond, Ed25519KeyGenerator()); err != nil {
+		t.Fatal(err)
+	}
+	next := a.Keys()[0]
+	if next.Id == "a" || a.SigningKey().Id != "a" {
+		t.Fatalf("new key %s used for signing before publish", next.Id)
+	}
+	if err := b.rotateShared(ctx, time.Hour, Ed25519KeyGenerator()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Lookup(next.Id); err != nil {
+		t.Fatalf("new key not synced: %v", err)
+	}
+	if len(b.Keys()) != 2 {
+		t.Fatalf("unexpected rotation on b: %d keys", len(b.Keys()))
+	}
+
+	// 同步间隔后两个实例都使用新秘钥签名, 并能互相验证
+	a.publish, b.publish = 0, 0
+	for _, ks := range []*KeySet{a, b} {
+		if err := ks.Sync(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if ks.SigningKey().Id != next.Id {
+			t.Fatalf("want signing key %s, got %s", next.Id, ks.SigningKey().Id)
+		}
+	}
+	str, err := NewToken(Keys(a)).EncryptFor("u1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims, _, err := NewToken(Keys(b)).Parse(str, nil); err != nil || claims.Subject != "u1" {
+		t.Fatalf("verify on b failed: %v", err)
+	}
+
+	// 基于过期版本的保存被拒绝
+	if ok, err := store.Save(ctx, "a", "stale", []byte("[]")); ok || err != nil {
+		t.Fatalf("stale save accepted: %v, %v", ok, err)
+	}
+}
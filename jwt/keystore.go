@@ -0,0 +1,131 @@
+package jwt
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"github.com/dgrijalva/jwt-go"
+	"sync"
+	"time"
+)
+
+var (
+	ErrNoKeyStore       = errors.New("key store not set")
+	ErrRotationInterval = errors.New("invalid rotation interval")
+)
+
+// 秘钥存储, 多个实例通过共享存储同步秘钥集合 (包含签名私钥, 需妥善保护存储的访问权限)
+type KeyStore interface {
+	// Load 载入秘钥集合数据及版本, 未保存时返回空数据
+	Load(ctx context.Context) (data []byte, version string, err error)
+	// Save 当前版本与 prev 一致时保存为新版本, 返回是否保存成功
+	Save(ctx context.Context, prev, version string, data []byte) (bool, error)
+}
+
+// 本地秘钥存储, 仅保存在当前进程内存中, 只适用于单实例部署
+type LocalKeyStore struct {
+	sync.Mutex
+	data    []byte
+	version string
+}
+
+func (s *LocalKeyStore) Load(context.Context) ([]byte, string, error) {
+	s.Lock()
+	defer s.Unlock()
+	return s.data, s.version, nil
+}
+
+func (s *LocalKeyStore) Save(_ context.Context, prev, version string, data []byte) (bool, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.version != prev {
+		return false, nil
+	}
+	s.data, s.version = data, version
+	return true, nil
+}
+
+// NewLocalKeyStore 实例化本地秘钥存储
+func NewLocalKeyStore() *LocalKeyStore {
+	return &LocalKeyStore{}
+}
+
+// 存储的秘钥数据
+type storedKey struct {
+	Id      string    `json:"kid"`
+	Alg     string    `json:"alg"`
+	Private []byte    `json:"private,omitempty"` // PKCS8, HMAC 为原始秘钥
+	Public  []byte    `json:"public,omitempty"`  // PKIX, 仅用于验证的秘钥
+	Created time.Time `json:"created"`
+}
+
+// 编码秘钥集合
+func marshalKeys(keys []*Key) ([]byte, error) {
+	list := make([]*storedKey, 0, len(keys))
+	for _, k := range keys {
+		sk := &storedKey{Id: k.Id, Alg: k.Method.Alg(), Created: k.Created}
+		switch {
+		case k.IsSymmetric():
+			sk.Private = k.Public.([]byte)
+		case k.CanSign():
+			b, err := x509.MarshalPKCS8PrivateKey(k.Private)
+			if err != nil {
+				return nil, err
+			}
+			sk.Private = b
+		default:
+			b, err := x509.MarshalPKIXPublicKey(k.Public)
+			if err != nil {
+				return nil, err
+			}
+			sk.Public = b
+		}
+		list = append(list, sk)
+	}
+	return json.Marshal(list)
+}
+
+// 解码秘钥集合
+func unmarshalKeys(data []byte) ([]*Key, error) {
+	var list []*storedKey
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+
+	keys := make([]*Key, 0, len(list))
+	for _, sk := range list {
+		method := jwt.GetSigningMethod(sk.Alg)
+		if method == nil {
+			return nil, ErrInvalidKeyType
+		}
+
+		var key *Key
+		var err error
+		switch {
+		case len(sk.Private) > 0:
+			if _, ok := method.(*jwt.SigningMethodHMAC); ok {
+				key, err = NewKey(sk.Id, method, sk.Private)
+				break
+			}
+			var pk interface{}
+			if pk, err = x509.ParsePKCS8PrivateKey(sk.Private); err == nil {
+				key, err = NewKey(sk.Id, method, pk)
+			}
+		default:
+			var pk interface{}
+			if pk, err = x509.ParsePKIXPublicKey(sk.Public); err == nil {
+				key, err = NewPublicKey(sk.Id, method, pk)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		key.Created = sk.Created
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
@@ -10,15 +10,25 @@ type Option func(*Options)
 type Options struct {
 	SigningMethod jwt.SigningMethod
 	SecretKey     []byte
+	Keys          KeyProvider // 秘钥集合, 设置后忽略 SigningMethod 及 SecretKey
 	Issuer        string
 	Expire        time.Duration
 	Refresh       time.Duration
+	RefreshExpire time.Duration          // 刷新令牌有效期
+	Store         Store                  // 令牌存储 (刷新令牌及吊销列表)
+	Audience      string                 // 接收方, 签发时写入并在验证时校验
+	RequireSub    bool                   // 是否必须包含主体
+	SubjectCheck  func(sub string) error // 主体验证
 }
 
 func (o *Options) Init(opts ...Option) {
 	for _, opt := range opts {
 		opt(o)
 	}
+
+	if o.RefreshExpire <= 0 {
+		o.RefreshExpire = DefaultRefreshExpire
+	}
 }
 
 // 签名方式
@@ -55,3 +65,41 @@ func Refresh(refresh time.Duration) Option {
 		o.Refresh = refresh
 	}
 }
+
+// 秘钥集合 (支持非对称秘钥及秘钥轮换)
+func Keys(keys KeyProvider) Option {
+	return func(o *Options) {
+		o.Keys = keys
+	}
+}
+
+// 刷新令牌有效期
+func RefreshExpire(expire time.Duration) Option {
+	return func(o *Options) {
+		o.RefreshExpire = expire
+	}
+}
+
+// 令牌存储 (用于刷新令牌轮换及吊销)
+func WithStore(store Store) Option {
+	return func(o *Options) {
+		o.Store = store
+	}
+}
+
+// 接收方
+func Audience(aud string) Option {
+	return func(o *Options) {
+		o.Audience = aud
+	}
+}
+
+// 必须包含主体, 可选设置主体验证函数
+func RequireSubject(check ...func(sub string) error) Option {
+	return func(o *Options) {
+		o.RequireSub = true
+		if len(check) > 0 {
+			o.SubjectCheck = check[0]
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package jwt
+
+import (
+	"context"
+	"errors"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/google/uuid"
+	"time"
+)
+
+const (
+	DefaultRefreshExpire = 7 * 24 * time.Hour // 默认刷新令牌有效期
+)
+
+var (
+	ErrNoStore         = errors.New("token store not set")
+	ErrTokenType       = errors.New("invalid token type")
+	ErrTokenRevoked    = errors.New("token has been revoked")
+	ErrInvalidAudience = errors.New("invalid token audience")
+	ErrInvalidSubject  = errors.New("invalid token subject")
+	ErrRefreshInvalid  = errors.New("refresh token is invalid or expired")
+	ErrRefreshReused   = errors.New("refresh token reuse detected")
+)
+
+// 令牌存储
+type Store interface {
+	// Revoke 将令牌ID加入吊销列表, ttl 为令牌剩余有效期 (0 为永久)
+	Revoke(ctx context.Context, jti string, ttl time.Duration) error
+	// IsRevoked 令牌ID是否已吊销
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+	// RevokeSubject 吊销主体在指定时间 (毫秒) 之前签发的全部令牌, 并删除其全部刷新令牌家族
+	RevokeSubject(ctx context.Context, sub string, at int64, ttl time.Duration) error
+	// SubjectRevokedAt 获取主体的吊销时间 (毫秒), 未吊销时返回0
+	SubjectRevokedAt(ctx context.Context, sub string) (int64, error)
+	// SaveRefresh 保存新的刷新令牌家族
+	SaveRefresh(ctx context.Context, sub, family, jti string, ttl time.Duration) error
+	// RotateRefresh 轮换刷新令牌. 家族不存在返回 ErrRefreshInvalid, 旧令牌已被使用时删除家族并返回 ErrRefreshReused
+	RotateRefresh(ctx context.Context, family, oldJti, newJti string, ttl time.Duration) error
+	// RevokeRefresh 删除刷新令牌家族
+	RevokeRefresh(ctx context.Context, family string) error
+}
+
+// 访问令牌及刷新令牌
+type Pair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// Issue 签发访问令牌及刷新令牌
+func (t *Token) Issue(ctx context.Context, sub string, data interface{}) (*Pair, error) {
+	return t.issue(ctx, sub, uuid.New().String(), data, "")
+}
+
+// RefreshPair 使用刷新令牌换取新的令牌对, 旧的刷新令牌同时失效
+func (t *Token) RefreshPair(ctx context.Context, str string, result interface{}) (*Pair, error) {
+	claims, err := t.parse(ctx, str, result, TypeRefresh)
+	if err != nil {
+		return nil, err
+	}
+	return t.issue(ctx, claims.Subject, claims.Family, claims.Data, claims.Id)
+}
+
+// 签发令牌对, oldJti 不为空时轮换刷新令牌
+func (t *Token) issue(ctx context.Context, sub, family string, data interface{}, oldJti string) (*Pair, error) {
+	rc := &Claims{Type: TypeRefresh, Family: family, Data: data}
+	rc.Subject = sub
+	refresh, err := t.sign(rc, t.opts.RefreshExpire)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.opts.Store != nil {
+		if oldJti == "" {
+			err = t.opts.Store.SaveRefresh(ctx, sub, family, rc.Id, t.opts.RefreshExpire)
+		} else {
+			err = t.opts.Store.RotateRefresh(ctx, family, oldJti, rc.Id, t.opts.RefreshExpire)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	access, err := t.EncryptFor(sub, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Pair{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(t.opts.Expire / time.Second),
+	}, nil
+}
+
+// Revoke 吊销令牌 (访问令牌或刷新令牌), 已过期的令牌直接忽略
+func (t *Token) Revoke(ctx context.Context, str string) error {
+	if t.opts.Store == nil {
+		return ErrNoStore
+	}
+
+	claims := new(Claims)
+	if _, err := jwt.ParseWithClaims(str, claims, t.keyFunc); err != nil {
+		if ve, ok := err.(*jwt.ValidationError); ok && ve.Errors == jwt.ValidationErrorExpired {
+			return nil
+		}
+		return err
+	}
+
+	if claims.Type == TypeRefresh && claims.Family != "" {
+		if err := t.opts.Store.RevokeRefresh(ctx, claims.Family); err != nil {
+			return err
+		}
+	}
+
+	if claims.Id == "" {
+		return nil
+	}
+
+	var ttl time.Duration
+	if claims.ExpiresAt > 0 {
+		ttl = time.Until(time.Unix(claims.ExpiresAt, 0)) + time.Second
+	}
+
+	return t.opts.Store.Revoke(ctx, claims.Id, ttl)
+}
+
+// RevokeAll 吊销主体已签发的全部令牌 (退出所有设备)
+// 按毫秒比较签发时间, 与吊销同一毫秒内签发的令牌同样被吊销
+func (t *Token) RevokeAll(ctx context.Context, sub string) error {
+	if t.opts.Store == nil {
+		return ErrNoStore
+	}
+	if sub == "" {
+		return ErrInvalidSubject
+	}
+
+	ttl := t.opts.RefreshExpire
+	if t.opts.Expire > ttl {
+		ttl = t.opts.Expire
+	}
+
+	return t.opts.Store.RevokeSubject(ctx, sub, unixMilli(time.Now()), ttl)
+}
+
+// 检查令牌是否已吊销
+func (t *Token) checkRevoked(ctx context.Context, claims *Claims) error {
+	if t.opts.Store == nil {
+		return nil
+	}
+
+	if claims.Id != "" {
+		revoked, err := t.opts.Store.IsRevoked(ctx, claims.Id)
+		if err != nil {
+			return err
+		}
+		if revoked {
+			return ErrTokenRevoked
+		}
+	}
+
+	if claims.Subject != "" {
+		at, err := t.opts.Store.SubjectRevokedAt(ctx, claims.Subject)
+		if err != nil {
+			return err
+		}
+		if at > 0 && claims.issuedAtMs() <= at {
+			return ErrTokenRevoked
+		}
+	}
+
+	return nil
+}
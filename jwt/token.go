@@ -11,7 +11,9 @@
 package jwt
 
 import (
+	"context"
 	"github.com/dgrijalva/jwt-go"
+	"github.com/google/uuid"
 	"time"
 )
 
@@ -19,22 +21,50 @@ type Token struct {
 	opts *Options
 }
 
+func (t *Token) Opts() *Options {
+	return t.opts
+}
+
 func (t *Token) Encrypt(data interface{}) (string, error) {
+	return t.EncryptFor("", data)
+}
+
+// EncryptFor 签发指定主体的访问令牌
+func (t *Token) EncryptFor(sub string, data interface{}) (string, error) {
+	claims := &Claims{Data: data}
+	claims.Subject = sub
+	return t.sign(claims, t.opts.Expire)
+}
+
+// 签名
+func (t *Token) sign(claims *Claims, expire time.Duration) (string, error) {
 	now := time.Now()
 
 	// JWT声明
-	claims := new(Claims)
-	claims.Data = data
+	claims.Id = uuid.New().String()
 	claims.NotBefore = now.Unix()
 	claims.Issuer = t.opts.Issuer
+	claims.Audience = t.opts.Audience
 	claims.IssuedAt = now.Unix()
+	claims.IatMs = unixMilli(now)
 
-	if t.opts.Expire > 0 {
-		claims.ExpiresAt = now.Add(t.opts.Expire).Unix()
+	if expire > 0 {
+		claims.ExpiresAt = now.Add(expire).Unix()
 	}
 
-	token := jwt.NewWithClaims(t.opts.SigningMethod, claims)
-	ts, err := token.SignedString(t.opts.SecretKey)
+	var ts string
+	var err error
+	if t.opts.Keys != nil {
+		key := t.opts.Keys.SigningKey()
+		if key == nil {
+			return "", ErrKeyNotFound
+		}
+		token := jwt.NewWithClaims(key.Method, claims)
+		token.Header["kid"] = key.Id
+		ts, err = token.SignedString(key.Private)
+	} else {
+		ts, err = jwt.NewWithClaims(t.opts.SigningMethod, claims).SignedString(t.opts.SecretKey)
+	}
 	if err != nil {
 		return "", err
 	}
@@ -42,24 +72,86 @@ func (t *Token) Encrypt(data interface{}) (string, error) {
 }
 
 func (t *Token) Verify(str string, result interface{}) (string, error) {
+	_, refresh, err := t.Parse(str, result)
+	return refresh, err
+}
+
+// Parse 验证并解析Token, 返回声明信息及刷新后的Token (无需刷新时为空)
+func (t *Token) Parse(str string, result interface{}) (*Claims, string, error) {
+	return t.ParseContext(context.Background(), str, result)
+}
+
+// ParseContext 验证并解析访问令牌, 同 Parse
+func (t *Token) ParseContext(ctx context.Context, str string, result interface{}) (*Claims, string, error) {
+	claims, err := t.parse(ctx, str, result, "")
+	if err != nil {
+		return nil, "", err
+	}
+
+	// 检查是否需要刷新
+	if t.opts.Refresh > 0 && time.Now().Add(-t.opts.Refresh).Unix() > claims.IssuedAt {
+		refresh, err := t.EncryptFor(claims.Subject, claims.Data)
+		if err != nil {
+			return nil, "", err
+		}
+		return claims, refresh, nil
+	}
+
+	return claims, "", nil
+}
+
+// 验证并解析指定类型的令牌
+func (t *Token) parse(ctx context.Context, str string, result interface{}, typ string) (*Claims, error) {
 	claims := &Claims{Data: result}
-	token, err := jwt.ParseWithClaims(str, claims, func(_ *jwt.Token) (interface{}, error) {
-		return t.opts.SecretKey, nil
-	})
+	token, err := jwt.ParseWithClaims(str, claims, t.keyFunc)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	if !token.Valid {
-		return "", jwt.ErrSignatureInvalid
+		return nil, jwt.ErrSignatureInvalid
 	}
 
-	// 检查是否需要刷新
-	if t.opts.Refresh > 0 && time.Now().Add(-t.opts.Refresh).Unix() > claims.IssuedAt {
-		return t.Encrypt(claims.Data)
+	if claims.Type != typ {
+		return nil, ErrTokenType
+	}
+
+	if t.opts.Audience != "" && !claims.VerifyAudience(t.opts.Audience, true) {
+		return nil, ErrInvalidAudience
+	}
+
+	if t.opts.RequireSub && claims.Subject == "" {
+		return nil, ErrInvalidSubject
+	}
+	if t.opts.SubjectCheck != nil {
+		if err := t.opts.SubjectCheck(claims.Subject); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := t.checkRevoked(ctx, claims); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// 获取验证秘钥
+func (t *Token) keyFunc(token *jwt.Token) (interface{}, error) {
+	if t.opts.Keys == nil {
+		return t.opts.SecretKey, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	key, err := t.opts.Keys.Lookup(kid)
+	if err != nil {
+		return nil, err
+	}
+	if key.Method.Alg() != token.Method.Alg() {
+		return nil, jwt.ErrSignatureInvalid
 	}
 
-	return "", nil
+	return key.Public, nil
 }
 
 func NewToken(opts ...Option) *Token {
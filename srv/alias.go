@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/bsm/redislock"
 	"github.com/cbwfree/micro-core/conv"
+	"github.com/cbwfree/micro-core/idgen"
 	mgo "github.com/cbwfree/micro-core/store/mongo"
 	rds "github.com/cbwfree/micro-core/store/redis"
 	"github.com/cbwfree/micro-core/web"
@@ -30,7 +31,7 @@ func New(srvName string, flags ...[]cli.Flag) {
 }
 
 func Run() error {
-	return APP().Srv().Run()
+	return APP().Run()
 }
 
 func Close() {
@@ -65,6 +66,11 @@ func NameId() string {
 	return APP().NameId()
 }
 
+// Snowflake 获取雪花算法ID生成器
+func Snowflake() (*idgen.Snowflake, error) {
+	return APP().Snowflake()
+}
+
 // Server 获取服务器对象
 func Server() server.Server {
 	return APP().Srv().Server()
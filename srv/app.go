@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/cbwfree/micro-core/compile"
 	"github.com/cbwfree/micro-core/fn"
+	"github.com/cbwfree/micro-core/idgen"
 	mgo "github.com/cbwfree/micro-core/store/mongo"
 	rds "github.com/cbwfree/micro-core/store/redis"
 	"github.com/cbwfree/micro-core/web"
@@ -20,6 +21,8 @@ import (
 	"time"
 )
 
+const snowflakeLeaseTTL = 30 * time.Second // 雪花算法节点ID租约有效期
+
 var (
 	app = &App{
 		opts:      newOptions(),
@@ -39,6 +42,8 @@ type App struct {
 	Web   *web.Server
 
 	publisher map[string]micro.Publisher // 订阅
+	snowflake *idgen.Snowflake           // 雪花算法ID生成器
+	commanded bool                       // 已执行子命令, 不再启动服务
 }
 
 func (a *App) With(with ...WithAPP) {
@@ -74,31 +79,7 @@ func (a *App) New(srvName string, flags ...[]cli.Flag) {
 				return err
 			}
 
-			if a.Redis != nil {
-				a.Redis.Opts().Uri = a.opts.RedisUrl
-				a.Redis.Opts().Db = a.opts.RedisDb
-				a.Redis.Opts().MinIdleConns = a.opts.RedisIdeConns
-				a.Redis.Opts().PoolSize = a.opts.RedisMaxPool
-				if err := a.Redis.Connect(); err != nil {
-					return err
-				}
-			}
-
-			if a.Mongo != nil {
-				if a.opts.MongoDb == "" {
-					a.Mongo.Opts().Db = strings.Replace(srvName, ".", "-", -1)
-				} else {
-					a.Mongo.Opts().Db = strings.Replace(a.opts.MongoDb, ".", "-", -1)
-				}
-				a.Mongo.Opts().Uri = a.opts.MongoUrl
-				a.Mongo.Opts().MinPoolSize = a.opts.MongoMinPool
-				a.Mongo.Opts().MaxPoolSize = a.opts.MongoMaxPool
-				if err := a.Mongo.Connect(); err != nil {
-					return err
-				}
-			}
-
-			return nil
+			return a.connectStores()
 		}),
 		micro.AfterStart(func() error {
 			if a.Web != nil {
@@ -123,23 +104,68 @@ func (a *App) New(srvName string, flags ...[]cli.Flag) {
 			return nil
 		}),
 		micro.AfterStop(func() error {
-			if a.Redis != nil {
-				if err := a.Redis.Disconnect(); err != nil {
-					return err
-				}
-			}
-
-			if a.Mongo != nil {
-				if err := a.Mongo.Disconnect(); err != nil {
-					return err
-				}
-			}
-
-			return nil
+			return a.closeStores()
 		}),
 	)
 }
 
+// 连接已启用的存储
+func (a *App) connectStores() error {
+	if a.Redis != nil {
+		a.Redis.Opts().Uri = a.opts.RedisUrl
+		a.Redis.Opts().Db = a.opts.RedisDb
+		a.Redis.Opts().MinIdleConns = a.opts.RedisIdeConns
+		a.Redis.Opts().PoolSize = a.opts.RedisMaxPool
+		if err := a.Redis.Connect(); err != nil {
+			return err
+		}
+	}
+
+	if a.Mongo != nil {
+		if err := a.connectMongo(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// 释放雪花算法节点ID并断开已启用的存储
+func (a *App) closeStores() error {
+	if a.snowflake != nil {
+		if err := a.snowflake.Release(); err != nil {
+			log.Warnf("release snowflake worker error: %s", err)
+		}
+	}
+
+	if a.Redis != nil {
+		if err := a.Redis.Disconnect(); err != nil {
+			return err
+		}
+	}
+
+	if a.Mongo != nil {
+		if err := a.Mongo.Disconnect(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// 连接MongoDB
+func (a *App) connectMongo() error {
+	if a.opts.MongoDb == "" {
+		a.Mongo.Opts().Db = strings.Replace(compile.Name(), ".", "-", -1)
+	} else {
+		a.Mongo.Opts().Db = strings.Replace(a.opts.MongoDb, ".", "-", -1)
+	}
+	a.Mongo.Opts().Uri = a.opts.MongoUrl
+	a.Mongo.Opts().MinPoolSize = a.opts.MongoMinPool
+	a.Mongo.Opts().MaxPoolSize = a.opts.MongoMaxPool
+	return a.Mongo.Connect()
+}
+
 // Close 主动关闭APP
 func (a *App) Close() {
 	a.cancel()
@@ -149,6 +175,14 @@ func (a *App) Init(opts ...micro.Option) {
 	a.srv.Init(opts...)
 }
 
+// Run 启动服务, 已执行子命令时直接返回
+func (a *App) Run() error {
+	if a.commanded {
+		return nil
+	}
+	return a.srv.Run()
+}
+
 // Service 获取服务对象
 func (a *App) Srv() micro.Service {
 	return a.srv
@@ -174,6 +208,40 @@ func (a *App) NameId() string {
 	return fmt.Sprintf("%s-%s", a.Name(), a.Id())
 }
 
+// Snowflake 获取雪花算法ID生成器
+// 节点ID优先使用 snowflake_worker 配置, 未配置时通过Redis租用, 均不可用时返回错误
+func (a *App) Snowflake() (*idgen.Snowflake, error) {
+	a.Lock()
+	defer a.Unlock()
+
+	if a.snowflake != nil {
+		return a.snowflake, nil
+	}
+
+	switch {
+	case a.opts.SnowflakeWorker > idgen.SnowflakeMaxWorker:
+		return nil, fmt.Errorf("snowflake worker must be between 0 and %d", idgen.SnowflakeMaxWorker)
+	case a.opts.SnowflakeWorker >= 0:
+		a.snowflake = idgen.NewSnowflake(a.opts.SnowflakeWorker)
+	case a.Redis != nil:
+		sf, err := idgen.NewLeasedSnowflake(func() (idgen.WorkerLease, error) {
+			lease, err := a.Redis.LeaseWorker(a.NameId(), snowflakeLeaseTTL)
+			if err != nil {
+				return nil, err
+			}
+			return lease, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		a.snowflake = sf
+	default:
+		return nil, fmt.Errorf("snowflake worker is required: set snowflake_worker or enable redis")
+	}
+
+	return a.snowflake, nil
+}
+
 // Server 获取服务的服务端
 func (a *App) SrvServer() server.Server {
 	return a.srv.Server()
@@ -233,3 +301,13 @@ func (a *App) CallCtx(ctx context.Context, name string, method string, in interf
 	req := a.srv.Client().NewRequest(name, method, in)
 	return a.srv.Client().Call(ctx, req, out, opts...)
 }
+
+// CallJson 通过名称调用RPC (使用JSON编码, 适用于非protobuf的请求及返回数据)
+func (a *App) CallJson(ctx context.Context, name string, method string, in interface{}, out interface{}, filter ...selector.Filter) error {
+	var opts []client.CallOption
+	if len(filter) > 0 {
+		opts = append(opts, FilterSelector(filter[0]))
+	}
+	req := a.srv.Client().NewRequest(name, method, in, client.WithContentType("application/json"))
+	return a.srv.Client().Call(ctx, req, out, opts...)
+}
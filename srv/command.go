@@ -0,0 +1,125 @@
+package srv
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	mgo "github.com/cbwfree/micro-core/store/mongo"
+	"github.com/micro/cli/v2"
+	log "github.com/micro/go-micro/v2/logger"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// WithCommands 注册服务子命令
+func WithCommands(cmds ...*cli.Command) WithAPP {
+	return func(a *App) {
+		app := a.srv.Options().Cmd.App()
+		app.Commands = append(app.Commands, cmds...)
+	}
+}
+
+// WithTransferCommands 注册数据表导入导出子命令
+//
+//	export --table users --file users.jsonl [--filter '{"status":1}']
+//	import --table users --file users.csv [--batch 500] [--ordered] [--upsert]
+func WithTransferCommands(tables *mgo.Tables) WithAPP {
+	return func(a *App) {
+		WithCommands(a.transferCommands(tables)...)(a)
+	}
+}
+
+func (a *App) transferCommands(tables *mgo.Tables) []*cli.Command {
+	var table = func(c *cli.Context) (*mgo.Table, error) {
+		tab := tables.Get(c.String("table"))
+		if tab == nil {
+			return nil, fmt.Errorf("not found table [%s]", c.String("table"))
+		}
+		return tab, nil
+	}
+
+	return []*cli.Command{
+		{
+			Name:  "export",
+			Usage: "导出数据表到文件 (支持 jsonl, csv 格式)",
+			Flags: []cli.Flag{
+				&cli.StringFlag{Name: "table", Usage: "数据表名称", Required: true},
+				&cli.StringFlag{Name: "file", Usage: "导出文件路径, 由扩展名决定格式", Required: true},
+				&cli.StringFlag{Name: "filter", Usage: "查询条件 (JSON格式)"},
+			},
+			Action: func(c *cli.Context) error {
+				tab, err := table(c)
+				if err != nil {
+					return err
+				}
+
+				var filter = bson.M{}
+				if f := c.String("filter"); f != "" {
+					if err := json.Unmarshal([]byte(f), &filter); err != nil {
+						return fmt.Errorf("invalid filter: %s", err.Error())
+					}
+				}
+
+				return a.runCommand(func() error {
+					count, err := a.Mongo.ExportFile(context.Background(), tab, c.String("file"), filter)
+					if err != nil {
+						return err
+					}
+					log.Infof("export table [ %s ] success. total: %d", tab.Name(), count)
+					return nil
+				})
+			},
+		},
+		{
+			Name:  "import",
+			Usage: "从文件导入数据表 (支持 jsonl, csv 格式)",
+			Flags: []cli.Flag{
+				&cli.StringFlag{Name: "table", Usage: "数据表名称", Required: true},
+				&cli.StringFlag{Name: "file", Usage: "导入文件路径, 由扩展名决定格式", Required: true},
+				&cli.IntFlag{Name: "batch", Usage: "每批写入数量", Value: mgo.DefaultBulkSize},
+				&cli.BoolFlag{Name: "ordered", Usage: "有序写入, 遇到错误时停止"},
+				&cli.BoolFlag{Name: "upsert", Usage: "按 _id 替换已存在的数据"},
+			},
+			Action: func(c *cli.Context) error {
+				tab, err := table(c)
+				if err != nil {
+					return err
+				}
+
+				return a.runCommand(func() error {
+					res, err := a.Mongo.ImportFile(context.Background(), tab, c.String("file"), c.Bool("upsert"),
+						mgo.WithBulkSize(c.Int("batch")),
+						mgo.WithBulkOrdered(c.Bool("ordered")),
+					)
+					if res != nil {
+						for _, e := range res.Errors {
+							log.Warn(e.Error())
+						}
+						log.Infof("import table [ %s ] total: %d, inserted: %d, upserted: %d, modified: %d, errors: %d",
+							tab.Name(), res.Total, res.Inserted, res.Upserted, res.Modified, len(res.Errors))
+					}
+					return err
+				})
+			},
+		},
+	}
+}
+
+// 执行子命令, 连接已启用的存储, 执行完成后断开连接并返回, 之后 Run 不再启动服务
+func (a *App) runCommand(fn func() error) error {
+	if a.Mongo == nil {
+		return fmt.Errorf("mongodb store is not enabled")
+	}
+
+	a.commanded = true
+	if err := a.connectStores(); err != nil {
+		_ = a.closeStores()
+		return err
+	}
+
+	err := fn()
+	if cerr := a.closeStores(); cerr != nil {
+		log.Warnf("close stores error: %s", cerr)
+	}
+
+	return err
+}
@@ -19,6 +19,13 @@ var (
 			EnvVars:     []string{"CORE_ROOT"},
 			Destination: &OPTS().Root,
 		},
+		&cli.Int64Flag{
+			Name:        "snowflake_worker",
+			Value:       -1,
+			Usage:       "设置雪花算法节点ID (0-1023), 各节点需唯一. 未设置时通过redis租用",
+			EnvVars:     []string{"CORE_SNOWFLAKE_WORKER"},
+			Destination: &OPTS().SnowflakeWorker,
+		},
 	}
 
 	FlagRedis = []cli.Flag{
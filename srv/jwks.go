@@ -0,0 +1,40 @@
+package srv
+
+import (
+	"context"
+	"github.com/cbwfree/micro-core/jwt"
+	"github.com/micro/go-micro/v2"
+)
+
+const JWKSMethod = "JWKSHandler.Get"
+
+// JWKS RPC 请求
+type JWKSRequest struct{}
+
+// JWKS RPC 服务
+type JWKSHandler struct {
+	keys *jwt.KeySet
+}
+
+func (h *JWKSHandler) Get(_ context.Context, _ *JWKSRequest, rsp *jwt.JWKS) error {
+	rsp.Keys = h.keys.JWKS().Keys
+	return nil
+}
+
+// WithJWKSHandler 注册JWKS RPC服务, 供其他服务拉取公钥集合
+func WithJWKSHandler(keys *jwt.KeySet) WithAPP {
+	return func(a *App) {
+		_ = micro.RegisterHandler(a.srv.Server(), &JWKSHandler{keys: keys})
+	}
+}
+
+// JWKSFetcher 通过RPC拉取指定服务的公钥集合
+func JWKSFetcher(srvName string) jwt.JWKSFetcher {
+	return func(ctx context.Context) (*jwt.JWKS, error) {
+		var set = new(jwt.JWKS)
+		if err := APP().CallJson(ctx, srvName, JWKSMethod, &JWKSRequest{}, set); err != nil {
+			return nil, err
+		}
+		return set, nil
+	}
+}
@@ -6,6 +6,8 @@ type Options struct {
 	Dev  bool   // 开发模式
 	Root string // 数据保存位置
 
+	SnowflakeWorker int64 // 雪花算法节点ID, 小于0时通过Redis租用
+
 	RedisUrl      string // Redis URL地址
 	RedisDb       int    // Redis Db
 	RedisIdeConns int    // Redis 最小空闲连接数
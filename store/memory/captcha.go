@@ -0,0 +1,68 @@
+package mem
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	CaptchaKey = "CAPTCHA"
+
+	captchaPurgeInterval = 100 // 每保存N个验证码清理一次过期数据
+)
+
+type captchaEntry struct {
+	answer   string
+	attempts int
+}
+
+// 内存验证码存储
+type CaptchaStore struct {
+	sync.Mutex
+	ms    *Store
+	saves int
+}
+
+func (s *CaptchaStore) Save(_ context.Context, id, answer string, expire time.Duration) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.saves++; s.saves%captchaPurgeInterval == 0 {
+		s.ms.Purge()
+	}
+
+	return s.ms.Set(fmt.Sprintf("%s:%s", CaptchaKey, id), &captchaEntry{answer: answer}, expire)
+}
+
+func (s *CaptchaStore) Verify(_ context.Context, id, answer string, maxAttempts int) (bool, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	key := fmt.Sprintf("%s:%s", CaptchaKey, id)
+	records, err := s.ms.Read(key)
+	if err != nil {
+		return false, nil
+	}
+
+	entry, ok := records[0].Value().(*captchaEntry)
+	if !ok {
+		return false, nil
+	}
+
+	if entry.answer == answer {
+		return true, s.ms.Delete(key)
+	}
+
+	if entry.attempts++; entry.attempts >= maxAttempts {
+		return false, s.ms.Delete(key)
+	}
+
+	return false, nil
+}
+
+// CaptchaStore 获取验证码存储
+func (ms *Store) CaptchaStore() *CaptchaStore {
+	return &CaptchaStore{ms: ms}
+}
@@ -0,0 +1,136 @@
+package mem
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const SocketSessionKey = "SOCKET_SESSION"
+
+type socketFrame struct {
+	seq  uint64
+	data []byte
+}
+
+type socketSession struct {
+	owner  string
+	last   uint64
+	frames []socketFrame
+}
+
+// 内存Socket会话恢复存储
+type SocketSessionStore struct {
+	sync.Mutex
+	ms *Store
+}
+
+func (s *SocketSessionStore) Touch(_ context.Context, session, owner string, ttl time.Duration) error {
+	s.Lock()
+	defer s.Unlock()
+
+	key := fmt.Sprintf("%s:%s", SocketSessionKey, session)
+	ss, ok := s.get(key)
+	if !ok {
+		s.ms.Purge()
+		ss = &socketSession{owner: owner}
+	}
+	return s.ms.Set(key, ss, ttl)
+}
+
+func (s *SocketSessionStore) Owner(_ context.Context, session string) (string, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if ss, ok := s.get(fmt.Sprintf("%s:%s", SocketSessionKey, session)); ok {
+		return ss.owner, nil
+	}
+	return "", nil
+}
+
+func (s *SocketSessionStore) Append(_ context.Context, session string, seq uint64, frame []byte, limit int, ttl time.Duration) error {
+	s.Lock()
+	defer s.Unlock()
+
+	key := fmt.Sprintf("%s:%s", SocketSessionKey, session)
+	ss := s.load(key)
+	ss.last = seq
+	ss.frames = append(ss.frames, socketFrame{seq: seq, data: frame})
+	if limit > 0 && len(ss.frames) > limit {
+		ss.frames = append(ss.frames[:0:0], ss.frames[len(ss.frames)-limit:]...)
+	}
+
+	return s.ms.Set(key, ss, ttl)
+}
+
+func (s *SocketSessionStore) Replay(_ context.Context, session string, after uint64) ([][]byte, uint64, bool, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	records, err := s.ms.Read(fmt.Sprintf("%s:%s", SocketSessionKey, session))
+	if err != nil {
+		return nil, 0, false, nil
+	}
+
+	ss, ok := records[0].Value().(*socketSession)
+	if !ok {
+		return nil, 0, false, nil
+	}
+
+	var frames [][]byte
+	for _, f := range ss.frames {
+		if f.seq > after {
+			frames = append(frames, f.data)
+		}
+	}
+
+	return frames, ss.last, true, nil
+}
+
+func (s *SocketSessionStore) Ack(_ context.Context, session string, seq uint64) error {
+	s.Lock()
+	defer s.Unlock()
+
+	records, err := s.ms.Read(fmt.Sprintf("%s:%s", SocketSessionKey, session))
+	if err != nil {
+		return nil
+	}
+
+	if ss, ok := records[0].Value().(*socketSession); ok {
+		i := 0
+		for i < len(ss.frames) && ss.frames[i].seq <= seq {
+			i++
+		}
+		ss.frames = ss.frames[i:]
+	}
+
+	return nil
+}
+
+func (s *SocketSessionStore) Delete(_ context.Context, session string) error {
+	return s.ms.Delete(fmt.Sprintf("%s:%s", SocketSessionKey, session))
+}
+
+// 读取会话, 不存在或已过期时创建新会话
+func (s *SocketSessionStore) load(key string) *socketSession {
+	if ss, ok := s.get(key); ok {
+		return ss
+	}
+	s.ms.Purge()
+	return new(socketSession)
+}
+
+func (s *SocketSessionStore) get(key string) (*socketSession, bool) {
+	if records, err := s.ms.Read(key); err == nil {
+		if ss, ok := records[0].Value().(*socketSession); ok {
+			return ss, true
+		}
+	}
+	return nil, false
+}
+
+// SocketSessionStore 获取Socket会话恢复存储
+func (ms *Store) SocketSessionStore() *SocketSessionStore {
+	return &SocketSessionStore{ms: ms}
+}
@@ -77,6 +77,22 @@ func (ms *Store) Delete(keys ...string) error {
 	return nil
 }
 
+// Purge 清理过期数据, 返回清理数量
+func (ms *Store) Purge() int {
+	ms.Lock()
+	defer ms.Unlock()
+
+	var n int
+	for key, v := range ms.values {
+		if !v.CheckState() {
+			delete(ms.values, key)
+			n++
+		}
+	}
+
+	return n
+}
+
 func (ms *Store) Get(key string) (interface{}, error) {
 	ms.RLock()
 	defer ms.RUnlock()
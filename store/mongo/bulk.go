@@ -0,0 +1,364 @@
+package mgo
+
+import (
+	"context"
+	"fmt"
+	log "github.com/micro/go-micro/v2/logger"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"sync"
+)
+
+const (
+	DefaultBulkSize = 500 // 默认每批写入数量
+)
+
+// 批量写入操作
+const (
+	BulkInsert = "insert"
+	BulkUpdate = "update"
+	BulkUpsert = "upsert"
+	BulkDelete = "delete"
+)
+
+type BulkOption func(o *BulkOptions)
+
+type BulkOptions struct {
+	Size    int  // 每批写入数量
+	Ordered bool // 是否有序写入, 有序写入时遇到错误将停止后续写入
+}
+
+func WithBulkSize(size int) BulkOption {
+	return func(o *BulkOptions) {
+		o.Size = size
+	}
+}
+
+func WithBulkOrdered(ordered bool) BulkOption {
+	return func(o *BulkOptions) {
+		o.Ordered = ordered
+	}
+}
+
+// 单条写入错误
+type BulkError struct {
+	Index int    `json:"index"` // 写入序号 (从0开始)
+	Op    string `json:"op"`    // 写入操作
+	Code  int    `json:"code"`
+	Msg   string `json:"msg"`
+}
+
+func (e *BulkError) Error() string {
+	return fmt.Sprintf("bulk %s [%d] error: %s", e.Op, e.Index, e.Msg)
+}
+
+// 批量写入结果
+type BulkResult struct {
+	Total    int          `json:"total"`
+	Inserted int64        `json:"inserted"`
+	Matched  int64        `json:"matched"`
+	Modified int64        `json:"modified"`
+	Upserted int64        `json:"upserted"`
+	Deleted  int64        `json:"deleted"`
+	Errors   []*BulkError `json:"errors"`
+}
+
+// 批量写入
+// 通过 Store.Bulk 创建时写入成功后调用写入钩子, 存在写入钩子时更新/删除操作会额外查询写入前后的文档
+type Bulk struct {
+	sync.Mutex
+	col    *mongo.Collection
+	opts   *BulkOptions
+	hooks  *writeHooks
+	models []mongo.WriteModel
+	ops    []string
+	index  []int // 当前批次每条数据的序号
+	result *BulkResult
+}
+
+func (b *Bulk) Opts() *BulkOptions {
+	return b.opts
+}
+
+// Result 获取写入结果
+func (b *Bulk) Result() *BulkResult {
+	b.Lock()
+	defer b.Unlock()
+
+	return b.result
+}
+
+// Insert 插入文档
+func (b *Bulk) Insert(ctx context.Context, doc interface{}) error {
+	if b.hooks.has() {
+		doc = ensureId(doc)
+	}
+	return b.add(ctx, BulkInsert, mongo.NewInsertOneModel().SetDocument(doc))
+}
+
+// Update 更新单个文档
+func (b *Bulk) Update(ctx context.Context, filter interface{}, update interface{}) error {
+	return b.add(ctx, BulkUpdate, mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(update))
+}
+
+// UpdateMany 更新多个文档
+func (b *Bulk) UpdateMany(ctx context.Context, filter interface{}, update interface{}) error {
+	return b.add(ctx, BulkUpdate, mongo.NewUpdateManyModel().SetFilter(filter).SetUpdate(update))
+}
+
+// Upsert 替换文档, 不存在时插入
+func (b *Bulk) Upsert(ctx context.Context, filter interface{}, doc interface{}) error {
+	return b.add(ctx, BulkUpsert, mongo.NewReplaceOneModel().SetFilter(filter).SetReplacement(doc).SetUpsert(true))
+}
+
+// Delete 删除单个文档
+func (b *Bulk) Delete(ctx context.Context, filter interface{}) error {
+	return b.add(ctx, BulkDelete, mongo.NewDeleteOneModel().SetFilter(filter))
+}
+
+// DeleteMany 删除多个文档
+func (b *Bulk) DeleteMany(ctx context.Context, filter interface{}) error {
+	return b.add(ctx, BulkDelete, mongo.NewDeleteManyModel().SetFilter(filter))
+}
+
+func (b *Bulk) add(ctx context.Context, op string, model mongo.WriteModel) error {
+	b.Lock()
+	defer b.Unlock()
+
+	b.models = append(b.models, model)
+	b.ops = append(b.ops, op)
+	b.index = append(b.index, b.result.Total)
+	b.result.Total++
+
+	if len(b.models) >= b.opts.Size {
+		return b.flush(ctx)
+	}
+
+	return nil
+}
+
+// Skip 记录无法写入的数据 (如解析失败), 占用一个序号
+func (b *Bulk) Skip(op string, err error) {
+	b.Lock()
+	defer b.Unlock()
+
+	b.result.Errors = append(b.result.Errors, &BulkError{
+		Index: b.result.Total,
+		Op:    op,
+		Msg:   err.Error(),
+	})
+	b.result.Total++
+}
+
+// Flush 写入剩余数据
+func (b *Bulk) Flush(ctx context.Context) error {
+	b.Lock()
+	defer b.Unlock()
+
+	return b.flush(ctx)
+}
+
+func (b *Bulk) flush(ctx context.Context) error {
+	if len(b.models) == 0 {
+		return nil
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	models, ops, index := b.models, b.ops, b.index
+	b.models, b.ops, b.index = nil, nil, nil
+
+	var before [][]bson.M
+	if b.hooks.has() {
+		var err error
+		if before, err = b.findBefore(ctx, models); err != nil {
+			return err
+		}
+	}
+
+	res, err := b.col.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(b.opts.Ordered))
+	if res != nil {
+		b.result.Inserted += res.InsertedCount
+		b.result.Matched += res.MatchedCount
+		b.result.Modified += res.ModifiedCount
+		b.result.Upserted += res.UpsertedCount
+		b.result.Deleted += res.DeletedCount
+	}
+
+	var failed = make(map[int]bool) // 写入失败 (或未执行) 的序号
+	var werr error
+	if err != nil {
+		be, ok := err.(mongo.BulkWriteException)
+		if !ok {
+			return err
+		}
+		for _, we := range be.WriteErrors {
+			failed[we.Index] = true
+			b.result.Errors = append(b.result.Errors, &BulkError{
+				Index: index[we.Index],
+				Op:    ops[we.Index],
+				Code:  we.Code,
+				Msg:   we.Message,
+			})
+		}
+		if be.WriteConcernError != nil {
+			werr = fmt.Errorf("bulk write concern error: %s", be.WriteConcernError.Message)
+		} else if b.opts.Ordered && len(be.WriteErrors) > 0 {
+			// 有序写入遇到错误时停止后续写入
+			for i := be.WriteErrors[0].Index; i < len(models); i++ {
+				failed[i] = true
+			}
+			werr = b.result.Errors[len(b.result.Errors)-1]
+		}
+	}
+
+	if before != nil {
+		b.callHooks(ctx, models, before, res, failed)
+	}
+
+	return werr
+}
+
+// 查询更新/删除操作写入前的文档 (同一批次中多次写入同一文档时, 写入前的文档为批次开始前的状态)
+func (b *Bulk) findBefore(ctx context.Context, models []mongo.WriteModel) ([][]bson.M, error) {
+	before := make([][]bson.M, len(models))
+	for i, model := range models {
+		var filter interface{}
+		var many bool
+		switch m := model.(type) {
+		case *mongo.UpdateOneModel:
+			filter = m.Filter
+		case *mongo.UpdateManyModel:
+			filter, many = m.Filter, true
+		case *mongo.ReplaceOneModel:
+			filter = m.Filter
+		case *mongo.DeleteOneModel:
+			filter = m.Filter
+		case *mongo.DeleteManyModel:
+			filter, many = m.Filter, true
+		default:
+			continue
+		}
+
+		if many {
+			cur, err := b.col.Find(ctx, filter)
+			if err != nil {
+				return nil, err
+			}
+			if err := cur.All(ctx, &before[i]); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		var doc bson.M
+		if err := b.col.FindOne(ctx, filter).Decode(&doc); err != nil {
+			if err == mongo.ErrNoDocuments {
+				continue
+			}
+			return nil, err
+		}
+		before[i] = []bson.M{doc}
+	}
+	return before, nil
+}
+
+// 调用写入成功的操作的写入钩子
+func (b *Bulk) callHooks(ctx context.Context, models []mongo.WriteModel, before [][]bson.M, res *mongo.BulkWriteResult, failed map[int]bool) {
+	emit := func(op string, id interface{}, before, after bson.M) {
+		b.hooks.call(ctx, &WriteEvent{
+			Op:         op,
+			Db:         b.col.Database().Name(),
+			Collection: b.col.Name(),
+			DocumentId: id,
+			Before:     before,
+			After:      after,
+		})
+	}
+
+	for i, model := range models {
+		if failed[i] {
+			continue
+		}
+
+		switch m := model.(type) {
+		case *mongo.InsertOneModel:
+			after := toBsonM(m.Document)
+			emit(WriteInsert, after["_id"], nil, after)
+		case *mongo.DeleteOneModel, *mongo.DeleteManyModel:
+			for _, doc := range before[i] {
+				emit(WriteDelete, doc["_id"], doc, nil)
+			}
+		default:
+			op := WriteUpdate
+			if _, ok := m.(*mongo.ReplaceOneModel); ok {
+				op = WriteReplace
+			}
+
+			var ids []interface{}
+			for _, doc := range before[i] {
+				ids = append(ids, doc["_id"])
+			}
+			if len(ids) == 0 && res != nil {
+				if id, ok := res.UpsertedIDs[int64(i)]; ok {
+					ids = append(ids, id)
+				}
+			}
+
+			for j, id := range ids {
+				var after bson.M
+				if err := b.col.FindOne(ctx, bson.M{"_id": id}).Decode(&after); err != nil {
+					if err != mongo.ErrNoDocuments {
+						log.Errorf("bulk find [%s] document after %s error: %s", b.col.Name(), op, err.Error())
+					}
+					continue
+				}
+				var prev bson.M
+				if j < len(before[i]) {
+					prev = before[i][j]
+				}
+				emit(op, id, prev, after)
+			}
+		}
+	}
+}
+
+// 文档没有 _id 时生成 ObjectID, 用于写入钩子获取文档ID
+func ensureId(doc interface{}) interface{} {
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return doc
+	}
+	if _, err := bson.Raw(raw).LookupErr("_id"); err == nil {
+		return doc
+	}
+
+	var d bson.D
+	if err := bson.Unmarshal(raw, &d); err != nil {
+		return doc
+	}
+	return append(bson.D{{Key: "_id", Value: primitive.NewObjectID()}}, d...)
+}
+
+// NewBulk 实例化批量写入
+func NewBulk(col *mongo.Collection, opts ...BulkOption) *Bulk {
+	o := &BulkOptions{
+		Size: DefaultBulkSize,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.Size <= 0 {
+		o.Size = DefaultBulkSize
+	}
+
+	return &Bulk{
+		col:    col,
+		opts:   o,
+		result: new(BulkResult),
+	}
+}
@@ -0,0 +1,71 @@
+package mgo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"sync"
+)
+
+// 写入操作
+const (
+	WriteInsert  = "insert"
+	WriteUpdate  = "update"
+	WriteReplace = "replace"
+	WriteDelete  = "delete"
+)
+
+// 写入事件
+type WriteEvent struct {
+	Op         string      // 写入操作
+	Db         string      // 数据库名称
+	Collection string      // 集合名称
+	DocumentId interface{} // 文档ID
+	Before     bson.M      // 写入前的文档
+	After      bson.M      // 写入后的文档
+}
+
+// 写入钩子 (在写入成功后调用)
+type WriteHook func(ctx context.Context, ev *WriteEvent)
+
+// 写入钩子列表
+type writeHooks struct {
+	sync.RWMutex
+	hooks []WriteHook
+}
+
+func (wh *writeHooks) add(hook ...WriteHook) {
+	wh.Lock()
+	defer wh.Unlock()
+
+	wh.hooks = append(wh.hooks, hook...)
+}
+
+func (wh *writeHooks) has() bool {
+	if wh == nil {
+		return false
+	}
+
+	wh.RLock()
+	defer wh.RUnlock()
+
+	return len(wh.hooks) > 0
+}
+
+func (wh *writeHooks) call(ctx context.Context, ev *WriteEvent) {
+	if wh == nil {
+		return
+	}
+
+	wh.RLock()
+	hs := wh.hooks
+	wh.RUnlock()
+
+	for _, h := range hs {
+		h(ctx, ev)
+	}
+}
+
+// AddWriteHook 添加写入钩子, 通过 Store 的写入方法, Bulk 及 ImportFile 写入时调用
+func (ms *Store) AddWriteHook(hook ...WriteHook) {
+	ms.hooks.add(hook...)
+}
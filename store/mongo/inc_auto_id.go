@@ -3,6 +3,7 @@ package mgo
 import (
 	"context"
 	"errors"
+	"github.com/cbwfree/micro-core/idgen"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
@@ -17,6 +18,15 @@ type AutoIncId struct {
 
 // GetIncId 获取
 func GetIncId(ctx context.Context, db *mongo.Database, id string) (int64, error) {
+	return GetIncIdStep(ctx, db, id, 1)
+}
+
+// GetIncIdStep 按步长增加自增ID, 返回增加后的值
+func GetIncIdStep(ctx context.Context, db *mongo.Database, id string, step int64) (int64, error) {
+	if step <= 0 {
+		return 0, idgen.ErrInvalidStep
+	}
+
 	if ctx == nil {
 		ctx = context.Background()
 	}
@@ -25,7 +35,7 @@ func GetIncId(ctx context.Context, db *mongo.Database, id string) (int64, error)
 		FindOneAndUpdate(
 			ctx,
 			bson.M{"_id": id},
-			bson.M{"$inc": bson.M{"n": int64(1)}},
+			bson.M{"$inc": bson.M{"n": step}},
 			options.
 				FindOneAndUpdate().
 				SetUpsert(true).
@@ -44,3 +54,10 @@ func GetIncId(ctx context.Context, db *mongo.Database, id string) (int64, error)
 
 	return incId.Num, nil
 }
+
+// IncIdAllocator 基于自增ID集合的号段分配函数
+func IncIdAllocator(db *mongo.Database) idgen.Allocator {
+	return func(ctx context.Context, name string, step int64) (int64, error) {
+		return GetIncIdStep(ctx, db, name, step)
+	}
+}
@@ -9,6 +9,7 @@ package mgo
 import (
 	"context"
 	"errors"
+	"github.com/cbwfree/micro-core/idgen"
 	log "github.com/micro/go-micro/v2/logger"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -21,6 +22,7 @@ import (
 type Store struct {
 	opts   *Options
 	client *mongo.Client
+	hooks  *writeHooks // 写入钩子
 }
 
 func (ms *Store) With(opts ...Option) {
@@ -127,6 +129,13 @@ func (ms *Store) GetIncId(id string) (int64, error) {
 	return GetIncId(context.Background(), ms.D(), id)
 }
 
+// 获取号段ID生成器
+func (ms *Store) SegmentIdGen(step ...int64) *idgen.Segment {
+	return idgen.NewSegment(func(ctx context.Context, name string, n int64) (int64, error) {
+		return GetIncIdStep(ctx, ms.D(), name, n)
+	}, step...)
+}
+
 // 获取集合列表
 func (ms *Store) ListCollectionNames(dbname ...string) ([]string, error) {
 	return ms.D(dbname...).ListCollectionNames(context.Background(), bson.M{})
@@ -166,7 +175,8 @@ func (ms *Store) Scan(tabName string, cur, size int64, filter interface{}, resul
 // 实例化MongoDB存储
 func NewStore(opts ...Option) *Store {
 	ms := &Store{
-		opts: newOptions(opts...),
+		opts:  newOptions(opts...),
+		hooks: new(writeHooks),
 	}
 	return ms
 }
@@ -0,0 +1,368 @@
+package mgo
+
+import (
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 查询参数格式:
+//
+//	name=abc			等于
+//	name__ne=abc		不等于
+//	id__in=1,2,3		包含
+//	age__gte=10			范围 (gt, gte, lt, lte)
+//	name__prefix=ab		前缀匹配
+//	phone__exists=true	字段是否存在
+//	sort=-time,name		排序, - 表示倒序
+//	fields=name,age		返回字段
+//	cur=1&size=20		分页
+//
+// 同一字段的等于与其他操作不能同时使用 (如 age=5&age__gt=3), 否则返回错误
+// 参数值与字段类型不符时 (如 age=abc) 返回错误
+const (
+	QuerySplit    = "__"
+	QueryValSplit = ","
+	QuerySort     = "sort"
+	QueryFields   = "fields"
+	QueryCur      = "cur"
+	QuerySize     = "size"
+)
+
+// 查询操作
+type QueryOp string
+
+const (
+	OpEq     QueryOp = "eq"
+	OpNe     QueryOp = "ne"
+	OpIn     QueryOp = "in"
+	OpGt     QueryOp = "gt"
+	OpGte    QueryOp = "gte"
+	OpLt     QueryOp = "lt"
+	OpLte    QueryOp = "lte"
+	OpPrefix QueryOp = "prefix"
+	OpExists QueryOp = "exists"
+)
+
+var (
+	// 范围查询
+	OpRange = []QueryOp{OpGt, OpGte, OpLt, OpLte}
+)
+
+// 查询字段类型
+type QueryType int
+
+const (
+	QueryString QueryType = iota
+	QueryInt
+	QueryInt64
+	QueryFloat
+	QueryBool
+)
+
+// 查询字段
+type QueryField struct {
+	Name  string    // 查询参数名称
+	Field string    // 数据库字段名称
+	Type  QueryType // 字段类型
+	Ops   []QueryOp // 允许的查询操作
+}
+
+func (qf *QueryField) allow(op QueryOp) bool {
+	for _, o := range qf.Ops {
+		if o == op {
+			return true
+		}
+	}
+	return false
+}
+
+// 值类型转换, 值与字段类型不符时返回错误
+func (qf *QueryField) convert(val string) (interface{}, error) {
+	var v interface{}
+	var err error
+	switch qf.Type {
+	case QueryInt:
+		v, err = strconv.Atoi(val)
+	case QueryInt64:
+		v, err = strconv.ParseInt(val, 10, 64)
+	case QueryFloat:
+		v, err = strconv.ParseFloat(val, 64)
+	case QueryBool:
+		v, err = strconv.ParseBool(val)
+	default:
+		return val, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid query value [%s]", qf.Name)
+	}
+	return v, nil
+}
+
+// SetField 设置数据库字段名称
+func (qf *QueryField) SetField(field string) *QueryField {
+	qf.Field = field
+	return qf
+}
+
+// QF 创建查询字段, 数据库字段名称默认与参数名称相同
+func QF(name string, typ QueryType, ops ...QueryOp) *QueryField {
+	if len(ops) == 0 {
+		ops = []QueryOp{OpEq}
+	}
+	return &QueryField{
+		Name:  name,
+		Field: name,
+		Type:  typ,
+		Ops:   ops,
+	}
+}
+
+// 查询构造器 (仅白名单内的字段允许查询/排序/返回)
+type Query struct {
+	fields   map[string]*QueryField
+	sorts    map[string]string
+	projects map[string]string
+	sort     string
+	size     int64
+	maxSize  int64
+}
+
+// Sort 设置允许排序的字段
+func (q *Query) Sort(names ...string) *Query {
+	for _, n := range names {
+		q.sorts[n] = q.field(n)
+	}
+	return q
+}
+
+// Project 设置允许返回的字段
+func (q *Query) Project(names ...string) *Query {
+	for _, n := range names {
+		q.projects[n] = q.field(n)
+	}
+	return q
+}
+
+// DefaultSort 设置默认排序
+func (q *Query) DefaultSort(sort string) *Query {
+	q.sort = sort
+	return q
+}
+
+// DefaultSize 设置默认分页大小及最大分页大小
+func (q *Query) DefaultSize(size int64, max ...int64) *Query {
+	q.size = size
+	if len(max) > 0 {
+		q.maxSize = max[0]
+	}
+	return q
+}
+
+func (q *Query) field(name string) string {
+	if f, ok := q.fields[name]; ok {
+		return f.Field
+	}
+	return name
+}
+
+// Parse 解析查询参数
+func (q *Query) Parse(values url.Values) (*QueryResult, error) {
+	res := &QueryResult{
+		Filter: bson.M{},
+	}
+	var err error
+	if res.Cur, err = parsePage(values, QueryCur); err != nil {
+		return nil, err
+	}
+	if res.Size, err = parsePage(values, QuerySize); err != nil {
+		return nil, err
+	}
+
+	if res.Size <= 0 {
+		res.Size = q.size
+	}
+	if q.maxSize > 0 && res.Size > q.maxSize {
+		res.Size = q.maxSize
+	}
+
+	// 按参数名排序, 保证解析结果及错误信息一致
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		vals := values[key]
+		if len(vals) == 0 || key == QuerySort || key == QueryFields || key == QueryCur || key == QuerySize {
+			continue
+		}
+
+		name, op := key, OpEq
+		if i := strings.LastIndex(key, QuerySplit); i > 0 {
+			name, op = key[:i], QueryOp(key[i+len(QuerySplit):])
+		}
+
+		qf, ok := q.fields[name]
+		if !ok || !qf.allow(op) {
+			return nil, fmt.Errorf("invalid query param [%s]", key)
+		}
+
+		if err := res.add(qf, op, vals[0]); err != nil {
+			return nil, err
+		}
+	}
+
+	sorts := values.Get(QuerySort)
+	if sorts == "" {
+		sorts = q.sort
+	}
+	if sorts != "" {
+		for _, s := range strings.Split(sorts, QueryValSplit) {
+			var order = 1
+			if strings.HasPrefix(s, "-") {
+				s, order = s[1:], -1
+			}
+			field, ok := q.sorts[s]
+			if !ok {
+				return nil, fmt.Errorf("invalid sort field [%s]", s)
+			}
+			res.Sort = append(res.Sort, bson.E{Key: field, Value: order})
+		}
+	}
+
+	if fields := values.Get(QueryFields); fields != "" {
+		res.Projection = bson.M{}
+		for _, s := range strings.Split(fields, QueryValSplit) {
+			field, ok := q.projects[s]
+			if !ok {
+				return nil, fmt.Errorf("invalid projection field [%s]", s)
+			}
+			res.Projection[field] = 1
+		}
+	}
+
+	return res, nil
+}
+
+// 解析分页参数, 未设置时为0
+func parsePage(values url.Values, key string) (int64, error) {
+	val := values.Get(key)
+	if val == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(val, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid query value [%s]", key)
+	}
+	return n, nil
+}
+
+// NewQuery 实例化查询构造器
+func NewQuery(fields ...*QueryField) *Query {
+	q := &Query{
+		fields:   make(map[string]*QueryField),
+		sorts:    make(map[string]string),
+		projects: make(map[string]string),
+		size:     defaultScanSize,
+	}
+	for _, f := range fields {
+		q.fields[f.Name] = f
+	}
+	return q
+}
+
+// 查询结果
+type QueryResult struct {
+	Filter     bson.M
+	Sort       bson.D
+	Projection bson.M
+	Cur        int64
+	Size       int64
+}
+
+// 获取字段的操作条件, 字段已使用等于查询时返回错误
+func (qr *QueryResult) cond(qf *QueryField) (bson.M, error) {
+	v, ok := qr.Filter[qf.Field]
+	if !ok {
+		m := bson.M{}
+		qr.Filter[qf.Field] = m
+		return m, nil
+	}
+	if m, ok := v.(bson.M); ok {
+		return m, nil
+	}
+	return nil, fmt.Errorf("conflicting query param [%s]", qf.Name)
+}
+
+func (qr *QueryResult) add(qf *QueryField, op QueryOp, val string) error {
+	if op == OpEq {
+		if _, ok := qr.Filter[qf.Field]; ok {
+			return fmt.Errorf("conflicting query param [%s]", qf.Name)
+		}
+		v, err := qf.convert(val)
+		if err != nil {
+			return err
+		}
+		qr.Filter[qf.Field] = v
+		return nil
+	}
+
+	cond, err := qr.cond(qf)
+	if err != nil {
+		return err
+	}
+
+	switch op {
+	case OpNe, OpGt, OpGte, OpLt, OpLte:
+		v, err := qf.convert(val)
+		if err != nil {
+			return err
+		}
+		cond["$"+string(op)] = v
+	case OpIn:
+		var in []interface{}
+		for _, s := range strings.Split(val, QueryValSplit) {
+			v, err := qf.convert(s)
+			if err != nil {
+				return err
+			}
+			in = append(in, v)
+		}
+		cond["$in"] = in
+	case OpPrefix:
+		if qf.Type != QueryString {
+			return fmt.Errorf("invalid prefix query [%s]", qf.Name)
+		}
+		cond["$regex"] = "^" + regexp.QuoteMeta(val)
+	case OpExists:
+		exists, err := strconv.ParseBool(val)
+		if err != nil {
+			return fmt.Errorf("invalid query value [%s]", qf.Name)
+		}
+		cond["$exists"] = exists
+	default:
+		return fmt.Errorf("invalid query op [%s]", op)
+	}
+	return nil
+}
+
+// FindOptions 设置排序及返回字段, 可直接作为 FindScan 的参数, opts 为空时创建新的选项
+func (qr *QueryResult) FindOptions(opts *options.FindOptions) *options.FindOptions {
+	if opts == nil {
+		opts = options.Find()
+	}
+	if len(qr.Sort) > 0 {
+		opts = opts.SetSort(qr.Sort)
+	}
+	if len(qr.Projection) > 0 {
+		opts = opts.SetProjection(qr.Projection)
+	}
+	return opts
+}
@@ -0,0 +1,203 @@
+package mgo
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestQueryParse(t *testing.T) {
+	q := NewQuery(
+		QF("name", QueryString, OpEq, OpNe, OpPrefix),
+		QF("age", QueryInt, OpEq, OpGt, OpLt, OpIn),
+		QF("uid", QueryInt64, OpEq, OpGte).SetField("user_id"),
+		QF("phone", QueryString, OpExists),
+	).Sort("age", "uid").Project("name", "uid")
+
+	tests := []struct {
+		name    string
+		query   string
+		filter  bson.M
+		sort    bson.D
+		project bson.M
+		err     string
+	}{
+		{
+			name:   "eq",
+			query:  "name=abc&age=5",
+			filter: bson.M{"name": "abc", "age": 5},
+		},
+		{
+			name:   "range",
+			query:  "age__gt=3&age__lt=10",
+			filter: bson.M{"age": bson.M{"$gt": 3, "$lt": 10}},
+		},
+		{
+			name:   "in",
+			query:  "age__in=1,2,3",
+			filter: bson.M{"age": bson.M{"$in": []interface{}{1, 2, 3}}},
+		},
+		{
+			name:   "prefix",
+			query:  "name__prefix=a.b",
+			filter: bson.M{"name": bson.M{"$regex": `^a\.b`}},
+		},
+		{
+			name:   "exists",
+			query:  "phone__exists=true",
+			filter: bson.M{"phone": bson.M{"$exists": true}},
+		},
+		{
+			name:   "field name",
+			query:  "uid__gte=100",
+			filter: bson.M{"user_id": bson.M{"$gte": int64(100)}},
+		},
+		{
+			name:    "sort and fields",
+			query:   "sort=-age,uid&fields=name,uid",
+			filter:  bson.M{},
+			sort:    bson.D{{Key: "age", Value: -1}, {Key: "user_id", Value: 1}},
+			project: bson.M{"name": 1, "user_id": 1},
+		},
+		{
+			name:  "eq with op",
+			query: "age=5&age__gt=3",
+			err:   "conflicting query param [age]",
+		},
+		{
+			name:  "op with eq",
+			query: "name__ne=a&name=b",
+			err:   "conflicting query param [name]",
+		},
+		{
+			name:  "not allowed op",
+			query: "phone=123",
+			err:   "invalid query param [phone]",
+		},
+		{
+			name:  "unknown field",
+			query: "email=a",
+			err:   "invalid query param [email]",
+		},
+		{
+			name:  "first invalid param",
+			query: "zz=1&email=a",
+			err:   "invalid query param [email]",
+		},
+		{
+			name:  "invalid int",
+			query: "age=abc",
+			err:   "invalid query value [age]",
+		},
+		{
+			name:  "invalid int64",
+			query: "uid__gte=1.5",
+			err:   "invalid query value [uid]",
+		},
+		{
+			name:  "invalid in element",
+			query: "age__in=1,x,3",
+			err:   "invalid query value [age]",
+		},
+		{
+			name:  "invalid bool",
+			query: "phone__exists=maybe",
+			err:   "invalid query value [phone]",
+		},
+		{
+			name:  "invalid page",
+			query: "cur=abc",
+			err:   "invalid query value [cur]",
+		},
+		{
+			name:  "invalid sort",
+			query: "sort=name",
+			err:   "invalid sort field [name]",
+		},
+		{
+			name:  "invalid projection",
+			query: "fields=age",
+			err:   "invalid projection field [age]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// 多次解析结果应一致
+			for i := 0; i < 10; i++ {
+				res, err := q.Parse(values)
+				if tt.err != "" {
+					if err == nil || err.Error() != tt.err {
+						t.Fatalf("want error %q, got %v", tt.err, err)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(res.Filter, tt.filter) {
+					t.Fatalf("filter: want %v, got %v", tt.filter, res.Filter)
+				}
+				if !reflect.DeepEqual(res.Sort, tt.sort) {
+					t.Fatalf("sort: want %v, got %v", tt.sort, res.Sort)
+				}
+				if !reflect.DeepEqual(res.Projection, tt.project) {
+					t.Fatalf("projection: want %v, got %v", tt.project, res.Projection)
+				}
+			}
+		})
+	}
+}
+
+func TestQueryPage(t *testing.T) {
+	q := NewQuery().DefaultSize(20, 100)
+
+	tests := []struct {
+		query string
+		cur   int64
+		size  int64
+	}{
+		{"", 0, 20},
+		{"cur=2&size=50", 2, 50},
+		{"size=500", 0, 100},
+		{"size=-1", 0, 20},
+	}
+
+	for _, tt := range tests {
+		values, _ := url.ParseQuery(tt.query)
+		res, err := q.Parse(values)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Cur != tt.cur || res.Size != tt.size {
+			t.Errorf("%q: want cur=%d size=%d, got cur=%d size=%d", tt.query, tt.cur, tt.size, res.Cur, res.Size)
+		}
+	}
+}
+
+func TestQueryFindOptions(t *testing.T) {
+	q := NewQuery(QF("age", QueryInt)).Sort("age").Project("age")
+	values, _ := url.ParseQuery("sort=-age&fields=age")
+	res, err := q.Parse(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := res.FindOptions(nil)
+	if !reflect.DeepEqual(opts.Sort, bson.D{{Key: "age", Value: -1}}) || !reflect.DeepEqual(opts.Projection, bson.M{"age": 1}) {
+		t.Fatalf("unexpected options: %+v", opts)
+	}
+
+	limit := int64(5)
+	opts = res.FindOptions(&options.FindOptions{Limit: &limit})
+	if opts.Limit == nil || *opts.Limit != 5 || opts.Sort == nil {
+		t.Fatalf("unexpected options: %+v", opts)
+	}
+}
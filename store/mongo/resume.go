@@ -0,0 +1,53 @@
+package mgo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+const ResumeTokenName = "resume_token"
+
+type ResumeToken struct {
+	Id    string    `bson:"_id" json:"id"`
+	Token []byte    `bson:"token" json:"token"`
+	Time  time.Time `bson:"time" json:"time"`
+}
+
+// MongoDB 断点续传令牌存储
+type MongoResumeStore struct {
+	col *mongo.Collection
+}
+
+func (rs *MongoResumeStore) Load(ctx context.Context, name string) ([]byte, error) {
+	var token = new(ResumeToken)
+	if err := rs.col.FindOne(ctx, bson.M{"_id": name}).Decode(token); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return token.Token, nil
+}
+
+func (rs *MongoResumeStore) Save(ctx context.Context, name string, token []byte) error {
+	_, err := rs.col.UpdateOne(ctx,
+		bson.M{"_id": name},
+		bson.M{"$set": bson.M{"token": token, "time": time.Now()}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// NewMongoResumeStore 实例化MongoDB令牌存储, 默认保存在 resume_token 集合
+func NewMongoResumeStore(db *mongo.Database, name ...string) *MongoResumeStore {
+	colName := ResumeTokenName
+	if len(name) > 0 && name[0] != "" {
+		colName = name[0]
+	}
+	return &MongoResumeStore{
+		col: db.Collection(colName),
+	}
+}
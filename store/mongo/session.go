@@ -0,0 +1,90 @@
+package mgo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"sync"
+	"time"
+)
+
+const SessionName = "session"
+
+type SessionData struct {
+	Id       string    `bson:"_id" json:"id"`
+	Data     []byte    `bson:"data" json:"data"`
+	ExpireAt time.Time `bson:"expire_at" json:"expire_at"`
+}
+
+// MongoDB Session存储 (通过TTL索引自动清理过期数据)
+type MongoSessionStore struct {
+	sync.Mutex
+	col     *mongo.Collection
+	indexed bool // TTL索引是否已创建
+}
+
+// EnsureIndex 创建过期时间TTL索引
+func (ss *MongoSessionStore) EnsureIndex(ctx context.Context) error {
+	_, err := ss.col.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.M{"expire_at": 1},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	return err
+}
+
+// 首次保存时创建TTL索引, 创建失败时下次保存重试
+func (ss *MongoSessionStore) ensureIndex(ctx context.Context) error {
+	ss.Lock()
+	defer ss.Unlock()
+
+	if ss.indexed {
+		return nil
+	}
+	if err := ss.EnsureIndex(ctx); err != nil {
+		return err
+	}
+	ss.indexed = true
+
+	return nil
+}
+
+func (ss *MongoSessionStore) Load(ctx context.Context, id string) ([]byte, error) {
+	var sd = new(SessionData)
+	if err := ss.col.FindOne(ctx, bson.M{"_id": id, "expire_at": bson.M{"$gt": time.Now()}}).Decode(sd); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return sd.Data, nil
+}
+
+func (ss *MongoSessionStore) Save(ctx context.Context, id string, data []byte, ttl time.Duration) error {
+	if err := ss.ensureIndex(ctx); err != nil {
+		return err
+	}
+
+	_, err := ss.col.UpdateOne(ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": bson.M{"data": data, "expire_at": time.Now().Add(ttl)}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+func (ss *MongoSessionStore) Delete(ctx context.Context, id string) error {
+	_, err := ss.col.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+// NewMongoSessionStore 实例化MongoDB Session存储, 默认保存在 session 集合
+func NewMongoSessionStore(db *mongo.Database, name ...string) *MongoSessionStore {
+	colName := SessionName
+	if len(name) > 0 && name[0] != "" {
+		colName = name[0]
+	}
+	return &MongoSessionStore{
+		col: db.Collection(colName),
+	}
+}
@@ -1,16 +1,24 @@
 package mgo
 
 import (
+	"context"
+	"errors"
+	"github.com/cbwfree/micro-core/idgen"
 	"go.mongodb.org/mongo-driver/mongo"
 	"reflect"
 )
 
+var (
+	ErrNoIdGen = errors.New("table not set id generator")
+)
+
 // MongoDB集合
 type Table struct {
 	name  string             // 表名称
 	model reflect.Type       // 结构体反射类型
 	index []mongo.IndexModel // 索引
 	data  []interface{}      // 初始化数据
+	idGen idgen.Generator    // ID生成器
 }
 
 func (dt *Table) Name() string {
@@ -43,6 +51,27 @@ func (dt *Table) SetData(data []interface{}) {
 	dt.data = data
 }
 
+// SetIdGen 设置ID生成器
+func (dt *Table) SetIdGen(gen idgen.Generator) *Table {
+	dt.idGen = gen
+	return dt
+}
+
+func (dt *Table) IdGen() idgen.Generator {
+	return dt.idGen
+}
+
+// NextId 通过ID生成器获取下一个ID
+func (dt *Table) NextId(ctx context.Context) (int64, error) {
+	if dt.idGen == nil {
+		return 0, ErrNoIdGen
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return dt.idGen.Next(ctx, dt.name)
+}
+
 func NewTable(name string, model interface{}) *Table {
 	vo := reflect.ValueOf(model)
 	mt := &Table{
@@ -2,6 +2,7 @@ package mgo
 
 import (
 	"context"
+	"fmt"
 	"github.com/cbwfree/micro-core/fn"
 	log "github.com/micro/go-micro/v2/logger"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -58,6 +59,15 @@ func (mts *Tables) Add(name string, model interface{}, index []mongo.IndexModel,
 	return tab
 }
 
+// NextId 获取指定集合的下一个ID
+func (mts *Tables) NextId(ctx context.Context, name string) (int64, error) {
+	tab := mts.Get(name)
+	if tab == nil {
+		return 0, fmt.Errorf("not found table [%s]", name)
+	}
+	return tab.NextId(ctx)
+}
+
 // 设置自增初始化数据
 func (mts *Tables) SetAutoIdData(data []interface{}) {
 	mts.Add(AutoIncIdName, AutoIncId{}, nil, data)
@@ -0,0 +1,382 @@
+package mgo
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/cbwfree/micro-core/conv"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 导入导出文件格式
+const (
+	FormatJsonl = "jsonl"
+	FormatCsv   = "csv"
+)
+
+var (
+	refTime     = reflect.TypeOf(time.Time{})
+	refObjectId = reflect.TypeOf(primitive.ObjectID{})
+)
+
+// FileFormat 根据文件扩展名获取文件格式
+func FileFormat(file string) string {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".csv":
+		return FormatCsv
+	default:
+		return FormatJsonl
+	}
+}
+
+// Export 按模型类型导出集合数据, 返回导出数量
+func Export(ctx context.Context, col *mongo.Collection, model reflect.Type, w io.Writer, format string, filter interface{}) (int, error) {
+	if filter == nil {
+		filter = bson.M{}
+	}
+
+	cur, err := col.Find(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	defer cur.Close(ctx)
+
+	var fields = modelFields(model)
+	var bw = bufio.NewWriter(w)
+	var cw *csv.Writer
+
+	if format == FormatCsv {
+		cw = csv.NewWriter(bw)
+		var header []string
+		for _, f := range fields {
+			header = append(header, f.name)
+		}
+		if err := cw.Write(header); err != nil {
+			return 0, err
+		}
+	}
+
+	var count int
+	for cur.Next(ctx) {
+		row := conv.Elem(model)
+		if err := cur.Decode(row.Addr().Interface()); err != nil {
+			return count, err
+		}
+
+		if cw != nil {
+			var record []string
+			for _, f := range fields {
+				record = append(record, formatField(row.FieldByIndex(f.index)))
+			}
+			if err := cw.Write(record); err != nil {
+				return count, err
+			}
+		} else {
+			b, err := json.Marshal(row.Addr().Interface())
+			if err != nil {
+				return count, err
+			}
+			_, _ = bw.Write(b)
+			_ = bw.WriteByte('\n')
+		}
+
+		count++
+	}
+	if err := cur.Err(); err != nil {
+		return count, err
+	}
+
+	if cw != nil {
+		cw.Flush()
+		if err := cw.Error(); err != nil {
+			return count, err
+		}
+	}
+
+	return count, bw.Flush()
+}
+
+// Import 按模型类型导入数据, upsert 为 true 时按 _id 替换已存在的文档
+// _id 为零值的文档直接插入, ObjectID 类型的 _id 自动生成
+func Import(ctx context.Context, col *mongo.Collection, model reflect.Type, r io.Reader, format string, upsert bool, opts ...BulkOption) (*BulkResult, error) {
+	return importData(ctx, nil, col, model, nil, r, format, upsert, opts...)
+}
+
+// 导入数据, nextId 不为空时为整数类型且为零值的 _id 分配ID
+func importData(ctx context.Context, hooks *writeHooks, col *mongo.Collection, model reflect.Type, nextId func(ctx context.Context) (int64, error),
+	r io.Reader, format string, upsert bool, opts ...BulkOption) (*BulkResult, error) {
+	var bulk = NewBulk(col, opts...)
+	bulk.hooks = hooks
+	var idIndex []int
+	for _, f := range modelFields(model) {
+		if f.name == "_id" {
+			idIndex = f.index
+			break
+		}
+	}
+	var write = func(row reflect.Value) error {
+		if idIndex != nil {
+			id := row.FieldByIndex(idIndex)
+			if !id.IsZero() {
+				if upsert {
+					return bulk.Upsert(ctx, bson.M{"_id": id.Interface()}, row.Addr().Interface())
+				}
+			} else if err := assignId(ctx, id, nextId); err != nil {
+				return err
+			}
+		}
+		return bulk.Insert(ctx, row.Addr().Interface())
+	}
+
+	if format == FormatCsv {
+		var fields = modelFields(model)
+		var cr = csv.NewReader(r)
+		var index []*modelField
+
+		header, err := cr.Read()
+		if err != nil {
+			return bulk.Result(), err
+		}
+		for _, h := range header {
+			var field *modelField
+			for _, f := range fields {
+				if f.name == h {
+					field = f
+					break
+				}
+			}
+			index = append(index, field)
+		}
+
+		for {
+			record, err := cr.Read()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				bulk.Skip(BulkInsert, err)
+				continue
+			}
+
+			row := conv.Elem(model)
+			if err := parseRecord(row, index, record); err != nil {
+				bulk.Skip(BulkInsert, err)
+				continue
+			}
+			if err := write(row); err != nil {
+				return bulk.Result(), err
+			}
+		}
+	} else {
+		var scanner = bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" {
+				continue
+			}
+
+			row := conv.Elem(model)
+			if err := json.Unmarshal([]byte(line), row.Addr().Interface()); err != nil {
+				bulk.Skip(BulkInsert, err)
+				continue
+			}
+			if err := write(row); err != nil {
+				return bulk.Result(), err
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return bulk.Result(), err
+		}
+	}
+
+	if err := bulk.Flush(ctx); err != nil {
+		return bulk.Result(), err
+	}
+
+	return bulk.Result(), nil
+}
+
+// ExportFile 导出数据表到文件, 文件格式由扩展名决定
+func (ms *Store) ExportFile(ctx context.Context, tab *Table, file string, filter interface{}) (int, error) {
+	f, err := os.Create(file)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	return Export(ctx, ms.C(tab.Name()), tab.Model(), f, FileFormat(file), filter)
+}
+
+// ImportFile 从文件导入数据表, 文件格式由扩展名决定, 数据表设置了ID生成器时为零值的整数 _id 分配ID
+// 与 Import 不同, 写入成功后调用 Store 的写入钩子
+func (ms *Store) ImportFile(ctx context.Context, tab *Table, file string, upsert bool, opts ...BulkOption) (*BulkResult, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var nextId func(ctx context.Context) (int64, error)
+	if tab.IdGen() != nil {
+		nextId = tab.NextId
+	}
+
+	return importData(ctx, ms.hooks, ms.C(tab.Name()), tab.Model(), nextId, f, FileFormat(file), upsert, opts...)
+}
+
+// 为零值的 _id 分配ID, ObjectID 生成新ID, 整数类型使用ID生成器, 其他类型保持不变
+func assignId(ctx context.Context, id reflect.Value, nextId func(ctx context.Context) (int64, error)) error {
+	if id.Type() == refObjectId {
+		id.Set(reflect.ValueOf(primitive.NewObjectID()))
+		return nil
+	}
+	if nextId == nil {
+		return nil
+	}
+
+	switch id.Kind() {
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		n, err := nextId(ctx)
+		if err != nil {
+			return err
+		}
+		id.SetInt(n)
+	case reflect.Uint, reflect.Uint32, reflect.Uint64:
+		n, err := nextId(ctx)
+		if err != nil {
+			return err
+		}
+		id.SetUint(uint64(n))
+	}
+	return nil
+}
+
+// Bulk 获取集合的批量写入对象, 写入成功后调用 Store 的写入钩子
+func (ms *Store) Bulk(tabName string, opts ...BulkOption) *Bulk {
+	b := NewBulk(ms.C(tabName), opts...)
+	b.hooks = ms.hooks
+	return b
+}
+
+// 模型字段
+type modelField struct {
+	name  string
+	index []int
+}
+
+// 获取模型字段列表 (以bson标签为字段名)
+func modelFields(model reflect.Type) []*modelField {
+	var fields []*modelField
+	for i := 0; i < model.NumField(); i++ {
+		sf := model.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+
+		name := strings.Split(sf.Tag.Get("bson"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(sf.Name)
+		}
+
+		fields = append(fields, &modelField{name: name, index: sf.Index})
+	}
+	return fields
+}
+
+func formatField(v reflect.Value) string {
+	switch v.Type() {
+	case refTime:
+		return v.Interface().(time.Time).Format(time.RFC3339)
+	case refObjectId:
+		return v.Interface().(primitive.ObjectID).Hex()
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return fmt.Sprint(v.Interface())
+	default:
+		return string(conv.ToJson(v.Interface()))
+	}
+}
+
+func parseRecord(row reflect.Value, index []*modelField, record []string) error {
+	for i, val := range record {
+		if i >= len(index) || index[i] == nil || val == "" {
+			continue
+		}
+		if err := parseField(row.FieldByIndex(index[i].index), val); err != nil {
+			return fmt.Errorf("invalid field [%s]: %s", index[i].name, err.Error())
+		}
+	}
+	return nil
+}
+
+func parseField(v reflect.Value, val string) error {
+	switch v.Type() {
+	case refTime:
+		t, err := time.Parse(time.RFC3339, val)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	case refObjectId:
+		id, err := primitive.ObjectIDFromHex(val)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(id))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(val)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(val, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(val, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(val, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	default:
+		return json.Unmarshal([]byte(val), v.Addr().Interface())
+	}
+	return nil
+}
@@ -0,0 +1,493 @@
+package mgo
+
+import (
+	"context"
+	"errors"
+	"github.com/cbwfree/micro-core/conv"
+	log "github.com/micro/go-micro/v2/logger"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"hash/fnv"
+	"reflect"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultWatchWorkers = 1               // 默认处理协程数
+	DefaultWatchQueue   = 100             // 默认每个处理协程的队列长度
+	DefaultWatchRetry   = 3 * time.Second // 变更流断开后的重连间隔
+
+	watchHandleBackoff = 100 * time.Millisecond // 事件处理失败后的初始重试间隔
+
+	errCodeInvalidResumeToken = 260 // 令牌无法用于 resumeAfter (如 invalidate 事件的令牌)
+)
+
+// 变更事件类型
+const (
+	WatchInsert     = "insert"
+	WatchUpdate     = "update"
+	WatchReplace    = "replace"
+	WatchDelete     = "delete"
+	WatchDrop       = "drop"
+	WatchRename     = "rename"
+	WatchInvalidate = "invalidate"
+)
+
+var (
+	ErrWatchRunning = errors.New("watcher is running")
+)
+
+// 变更事件
+type ChangeEvent struct {
+	Token             bson.Raw            `bson:"_id"`
+	OperationType     string              `bson:"operationType"`
+	Ns                ChangeNamespace     `bson:"ns"`
+	DocumentKey       bson.Raw            `bson:"documentKey"`
+	UpdateDescription *UpdateDescription  `bson:"updateDescription,omitempty"`
+	ClusterTime       primitive.Timestamp `bson:"clusterTime"`
+	Raw               bson.Raw            `bson:"fullDocument,omitempty"`
+	FullDocument      interface{}         `bson:"-"` // 按模型类型解码后的文档
+}
+
+// 事件所属的数据库及集合
+type ChangeNamespace struct {
+	Db   string `bson:"db"`
+	Coll string `bson:"coll"`
+}
+
+// 更新事件的字段变化
+type UpdateDescription struct {
+	UpdatedFields bson.M   `bson:"updatedFields"`
+	RemovedFields []string `bson:"removedFields"`
+}
+
+// 变更事件处理函数
+// 事件至少投递一次: 进程在事件确认前退出时, 重启后从最后确认的令牌继续, 已处理的事件可能重复投递, 处理函数需保证幂等
+// 处理函数返回错误时按指数退避重试 (最大间隔为 Retry) 直到成功, 期间同一处理协程的后续事件等待, 令牌不会越过未处理成功的事件
+// 集合被删除或重命名时收到 invalidate 事件, 之后变更流关闭, 重连时使用 startAfter 从该事件之后继续监听
+type WatchHandler func(ctx context.Context, ev *ChangeEvent) error
+
+// 断点续传令牌存储
+type ResumeStore interface {
+	Load(ctx context.Context, name string) ([]byte, error)
+	Save(ctx context.Context, name string, token []byte) error
+}
+
+type WatchOption func(o *WatchOptions)
+
+type WatchOptions struct {
+	Db           string         // 数据库名称, 默认为当前数据库
+	Collection   string         // 集合名称, 为空时监听整个数据库
+	Model        reflect.Type   // 文档模型类型
+	Pipeline     mongo.Pipeline // 过滤管道
+	FullDocument bool           // 更新事件是否返回完整文档
+	Store        ResumeStore    // 断点续传令牌存储
+	Workers      int            // 处理协程数
+	Queue        int            // 每个处理协程的队列长度
+	Retry        time.Duration  // 重连间隔, 同时为事件处理失败后重试的最大间隔
+}
+
+// 监听指定集合
+func WithWatchCollection(name string, dbname ...string) WatchOption {
+	return func(o *WatchOptions) {
+		o.Collection = name
+		if len(dbname) > 0 {
+			o.Db = dbname[0]
+		}
+	}
+}
+
+// 监听整个数据库
+func WithWatchDatabase(dbname string) WatchOption {
+	return func(o *WatchOptions) {
+		o.Db = dbname
+		o.Collection = ""
+	}
+}
+
+// 监听数据表, 并按数据表模型解码文档
+func WithWatchTable(tab *Table) WatchOption {
+	return func(o *WatchOptions) {
+		o.Collection = tab.Name()
+		o.Model = tab.Model()
+	}
+}
+
+func WithWatchModel(model interface{}) WatchOption {
+	return func(o *WatchOptions) {
+		o.Model = reflect.TypeOf(model)
+	}
+}
+
+func WithWatchPipeline(pipeline mongo.Pipeline) WatchOption {
+	return func(o *WatchOptions) {
+		o.Pipeline = pipeline
+	}
+}
+
+// 只监听指定类型的事件
+func WithWatchOperation(op ...string) WatchOption {
+	return func(o *WatchOptions) {
+		o.Pipeline = append(o.Pipeline, bson.D{{Key: "$match", Value: bson.M{"operationType": bson.M{"$in": op}}}})
+	}
+}
+
+func WithWatchFullDocument(full bool) WatchOption {
+	return func(o *WatchOptions) {
+		o.FullDocument = full
+	}
+}
+
+func WithWatchResumeStore(store ResumeStore) WatchOption {
+	return func(o *WatchOptions) {
+		o.Store = store
+	}
+}
+
+// 设置处理协程数及队列长度, 队列满时暂停读取变更流
+func WithWatchWorkers(workers int, queue int) WatchOption {
+	return func(o *WatchOptions) {
+		o.Workers = workers
+		o.Queue = queue
+	}
+}
+
+func WithWatchRetry(t time.Duration) WatchOption {
+	return func(o *WatchOptions) {
+		o.Retry = t
+	}
+}
+
+// 待确认的事件
+type watchPending struct {
+	seq        int64
+	token      bson.Raw
+	invalidate bool // 是否为 invalidate 事件, 其令牌只能用于 startAfter
+	done       bool
+}
+
+// 变更监听
+type Watcher struct {
+	sync.Mutex
+	name    string
+	store   *Store
+	opts    *WatchOptions
+	handler WatchHandler
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	queues  []chan *watchTask
+	pending []*watchPending
+	token   bson.Raw      // 最后确认的令牌
+	after   bool          // 最后确认的令牌是否使用 startAfter
+	last    *watchPending // 最后接收的事件, 重连时从此处继续, 避免重复投递队列中未处理的事件
+	seq     int64         // 已接收事件序号
+	saveMu  sync.Mutex    // 保存令牌锁
+	saved   int64         // 已保存令牌的事件序号
+}
+
+type watchTask struct {
+	ev      *ChangeEvent
+	pending *watchPending
+}
+
+func (w *Watcher) Name() string {
+	return w.name
+}
+
+func (w *Watcher) Opts() *WatchOptions {
+	return w.opts
+}
+
+// Token 获取最后确认的令牌
+func (w *Watcher) Token() bson.Raw {
+	w.Lock()
+	defer w.Unlock()
+
+	return w.token
+}
+
+// Start 开始监听
+func (w *Watcher) Start() error {
+	w.Lock()
+	defer w.Unlock()
+
+	if w.cancel != nil {
+		return ErrWatchRunning
+	}
+
+	w.ctx, w.cancel = context.WithCancel(context.Background())
+	w.last = nil
+
+	if w.opts.Store != nil {
+		token, err := w.opts.Store.Load(w.ctx, w.name)
+		if err != nil {
+			w.cancel()
+			w.cancel = nil
+			return err
+		}
+		if len(token) > 0 {
+			w.token, w.after = token, false
+		}
+	}
+
+	w.queues = make([]chan *watchTask, w.opts.Workers)
+	for i := range w.queues {
+		w.queues[i] = make(chan *watchTask, w.opts.Queue)
+		w.wg.Add(1)
+		go w.work(w.queues[i])
+	}
+
+	w.wg.Add(1)
+	go w.loop()
+
+	log.Debugf("[%s] watcher started ...", w.name)
+
+	return nil
+}
+
+// Stop 停止监听, 等待已接收的事件处理完成
+func (w *Watcher) Stop() {
+	w.Lock()
+	if w.cancel == nil {
+		w.Unlock()
+		return
+	}
+	w.cancel()
+	w.Unlock()
+
+	w.wg.Wait()
+
+	w.Lock()
+	w.cancel = nil
+	w.pending = nil
+	w.Unlock()
+
+	log.Debugf("[%s] watcher stopped ...", w.name)
+}
+
+// 打开变更流, 优先从最后接收的事件继续, 否则从最后确认的令牌继续
+func (w *Watcher) open(forceAfter bool) (*mongo.ChangeStream, error) {
+	w.Lock()
+	token, after := w.token, w.after
+	if w.last != nil {
+		token, after = w.last.token, w.last.invalidate
+	}
+	w.Unlock()
+
+	opts := options.ChangeStream()
+	if w.opts.FullDocument {
+		opts = opts.SetFullDocument(options.UpdateLookup)
+	}
+	if len(token) > 0 {
+		if after || forceAfter {
+			opts = opts.SetStartAfter(token)
+		} else {
+			opts = opts.SetResumeAfter(token)
+		}
+	}
+
+	pipeline := w.opts.Pipeline
+	if pipeline == nil {
+		pipeline = mongo.Pipeline{}
+	}
+
+	db := w.store.D(w.opts.Db)
+	if w.opts.Collection == "" {
+		return db.Watch(w.ctx, pipeline, opts)
+	}
+	return db.Collection(w.opts.Collection).Watch(w.ctx, pipeline, opts)
+}
+
+// 读取变更流
+func (w *Watcher) loop() {
+	defer func() {
+		for _, q := range w.queues {
+			close(q)
+		}
+		w.wg.Done()
+	}()
+
+	for {
+		if err := w.read(); err != nil && w.ctx.Err() == nil {
+			log.Errorf("[%s] watch change stream error: %s", w.name, err.Error())
+		}
+
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-time.After(w.opts.Retry):
+		}
+	}
+}
+
+func (w *Watcher) read() error {
+	cs, err := w.open(false)
+	if ce, ok := err.(mongo.CommandError); ok && ce.Code == errCodeInvalidResumeToken {
+		// 保存的令牌来自 invalidate 事件时无法 resumeAfter
+		cs, err = w.open(true)
+	}
+	if err != nil {
+		return err
+	}
+	defer cs.Close(context.Background())
+
+	for cs.Next(w.ctx) {
+		ev, err := w.decode(cs.Current)
+		if err != nil {
+			log.Errorf("[%s] decode change event error: %s", w.name, err.Error())
+			continue
+		}
+
+		w.Lock()
+		w.seq++
+		task := &watchTask{
+			ev: ev,
+			pending: &watchPending{
+				seq:        w.seq,
+				token:      ev.Token,
+				invalidate: ev.OperationType == WatchInvalidate,
+			},
+		}
+		w.pending = append(w.pending, task.pending)
+		w.last = task.pending
+		w.Unlock()
+
+		// 队列已满时阻塞, 暂停读取变更流
+		select {
+		case w.queues[w.partition(ev)] <- task:
+		case <-w.ctx.Done():
+			return nil
+		}
+	}
+
+	return cs.Err()
+}
+
+// 解码变更事件
+func (w *Watcher) decode(raw bson.Raw) (*ChangeEvent, error) {
+	ev := new(ChangeEvent)
+	if err := bson.Unmarshal(raw, ev); err != nil {
+		return nil, err
+	}
+
+	if len(ev.Raw) > 0 {
+		if w.opts.Model != nil {
+			doc := conv.Elem(w.opts.Model).Addr().Interface()
+			if err := bson.Unmarshal(ev.Raw, doc); err != nil {
+				return nil, err
+			}
+			ev.FullDocument = doc
+		} else {
+			ev.FullDocument = ev.Raw
+		}
+	}
+
+	return ev, nil
+}
+
+// 同一文档的事件分配到同一个处理协程, 保证处理顺序
+func (w *Watcher) partition(ev *ChangeEvent) int {
+	if len(w.queues) == 1 {
+		return 0
+	}
+	h := fnv.New32a()
+	_, _ = h.Write(ev.DocumentKey)
+	return int(h.Sum32() % uint32(len(w.queues)))
+}
+
+func (w *Watcher) work(queue chan *watchTask) {
+	defer w.wg.Done()
+
+	for task := range queue {
+		if w.handle(task.ev) {
+			w.commit(task.pending)
+		}
+	}
+}
+
+// 处理事件, 失败时重试直到成功; 停止监听时放弃重试且不确认该事件, 重启后重新投递
+func (w *Watcher) handle(ev *ChangeEvent) bool {
+	backoff, max := watchHandleBackoff, w.opts.Retry
+	if max < backoff {
+		max = backoff
+	}
+
+	for {
+		err := w.handler(context.Background(), ev)
+		if err == nil {
+			return true
+		}
+		log.Errorf("[%s] handle change event [%s] error: %s, retry after %s", w.name, ev.OperationType, err.Error(), backoff)
+
+		select {
+		case <-w.ctx.Done():
+			return false
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > max {
+			backoff = max
+		}
+	}
+}
+
+// 按接收顺序确认事件, 保存已连续处理完成的最后一个令牌
+func (w *Watcher) commit(p *watchPending) {
+	w.Lock()
+	p.done = true
+
+	var last *watchPending
+	for len(w.pending) > 0 && w.pending[0].done {
+		last = w.pending[0]
+		w.pending = w.pending[1:]
+	}
+	if last != nil {
+		w.token, w.after = last.token, last.invalidate
+	}
+	w.Unlock()
+
+	if last == nil || w.opts.Store == nil {
+		return
+	}
+
+	w.saveMu.Lock()
+	defer w.saveMu.Unlock()
+
+	// 避免并发保存时旧令牌覆盖新令牌
+	if last.seq <= w.saved {
+		return
+	}
+	if err := w.opts.Store.Save(context.Background(), w.name, last.token); err != nil {
+		log.Errorf("[%s] save resume token error: %s", w.name, err.Error())
+		return
+	}
+	w.saved = last.seq
+}
+
+// Watch 创建变更监听, name 作为断点续传令牌的保存名称
+func (ms *Store) Watch(name string, handler WatchHandler, opts ...WatchOption) *Watcher {
+	o := &WatchOptions{
+		Workers: DefaultWatchWorkers,
+		Queue:   DefaultWatchQueue,
+		Retry:   DefaultWatchRetry,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.Workers <= 0 {
+		o.Workers = DefaultWatchWorkers
+	}
+	if o.Queue < 0 {
+		o.Queue = 0
+	}
+
+	return &Watcher{
+		name:    name,
+		store:   ms,
+		opts:    o,
+		handler: handler,
+	}
+}
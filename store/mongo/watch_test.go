@@ -0,0 +1,115 @@
+package mgo
+
+import (
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testResumeStore struct {
+	sync.Mutex
+	tokens []string
+}
+
+func (s *testResumeStore) Load(context.Context, string) ([]byte, error) {
+	return nil, nil
+}
+
+func (s *testResumeStore) Save(_ context.Context, _ string, token []byte) error {
+	s.Lock()
+	defer s.Unlock()
+	s.tokens = append(s.tokens, bson.Raw(token).Lookup("t").StringValue())
+	return nil
+}
+
+func (s *testResumeStore) saved() []string {
+	s.Lock()
+	defer s.Unlock()
+	return append([]string(nil), s.tokens...)
+}
+
+// 不连接数据库, 直接向处理协程投递事件
+func newTestWatcher(handler WatchHandler, store ResumeStore) (*Watcher, chan *watchTask) {
+	w := new(Store).Watch("test", handler, WithWatchResumeStore(store), WithWatchRetry(20*time.Millisecond))
+	w.ctx, w.cancel = context.WithCancel(context.Background())
+	queue := make(chan *watchTask, 10)
+	w.wg.Add(1)
+	go w.work(queue)
+	return w, queue
+}
+
+func pushTestEvent(w *Watcher, queue chan *watchTask, token string) {
+	raw, _ := bson.Marshal(bson.M{"t": token})
+	w.Lock()
+	w.seq++
+	p := &watchPending{seq: w.seq, token: raw}
+	w.pending = append(w.pending, p)
+	w.Unlock()
+	queue <- &watchTask{ev: &ChangeEvent{Token: raw, OperationType: WatchInsert}, pending: p}
+}
+
+func TestWatcherRetryHandler(t *testing.T) {
+	var mu sync.Mutex
+	calls := make(map[string]int)
+	handler := func(_ context.Context, ev *ChangeEvent) error {
+		mu.Lock()
+		defer mu.Unlock()
+		token := ev.Token.Lookup("t").StringValue()
+		calls[token]++
+		if token == "b" && calls[token] < 3 {
+			return errors.New("temporary failure")
+		}
+		return nil
+	}
+
+	store := &testResumeStore{}
+	w, queue := newTestWatcher(handler, store)
+	for _, token := range []string{"a", "b", "c"} {
+		pushTestEvent(w, queue, token)
+	}
+	close(queue)
+	w.wg.Wait()
+
+	if calls["a"] != 1 || calls["b"] != 3 || calls["c"] != 1 {
+		t.Fatalf("unexpected calls: %v", calls)
+	}
+	if saved := store.saved(); len(saved) == 0 || saved[len(saved)-1] != "c" {
+		t.Fatalf("want last token c, got %v", saved)
+	}
+}
+
+func TestWatcherStopWithoutCommit(t *testing.T) {
+	failed := make(chan struct{}, 1)
+	handler := func(_ context.Context, ev *ChangeEvent) error {
+		if ev.Token.Lookup("t").StringValue() == "b" {
+			select {
+			case failed <- struct{}{}:
+			default:
+			}
+			return errors.New("permanent failure")
+		}
+		return nil
+	}
+
+	store := &testResumeStore{}
+	w, queue := newTestWatcher(handler, store)
+	for _, token := range []string{"a", "b", "c"} {
+		pushTestEvent(w, queue, token)
+	}
+
+	<-failed
+	w.cancel()
+	close(queue)
+	w.wg.Wait()
+
+	// 令牌停留在失败事件之前, 重启后从 b 重新投递
+	if saved := store.saved(); len(saved) != 1 || saved[0] != "a" {
+		t.Fatalf("want token a, got %v", saved)
+	}
+	if token := w.Token().Lookup("t").StringValue(); token != "a" {
+		t.Fatalf("want token a, got %s", token)
+	}
+}
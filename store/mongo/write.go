@@ -0,0 +1,185 @@
+package mgo
+
+import (
+	"context"
+	log "github.com/micro/go-micro/v2/logger"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// 写入数据 (写入后调用写入钩子, 存在写入钩子时更新/删除操作会额外查询写入前后的文档)
+
+// InsertOne 插入单条数据
+func (ms *Store) InsertOne(ctx context.Context, tabName string, doc interface{}) (interface{}, error) {
+	return insertOne(ctx, ms.hooks, ms.C(tabName), doc)
+}
+
+// UpdateOne 更新单条数据
+func (ms *Store) UpdateOne(ctx context.Context, tabName string, filter interface{}, update interface{}, upsert ...bool) error {
+	return updateOne(ctx, ms.hooks, ms.C(tabName), filter, update, upsert...)
+}
+
+// ReplaceOne 替换单条数据
+func (ms *Store) ReplaceOne(ctx context.Context, tabName string, filter interface{}, doc interface{}, upsert ...bool) error {
+	return replaceOne(ctx, ms.hooks, ms.C(tabName), filter, doc, upsert...)
+}
+
+// DeleteOne 删除单条数据
+func (ms *Store) DeleteOne(ctx context.Context, tabName string, filter interface{}) error {
+	return deleteOne(ctx, ms.hooks, ms.C(tabName), filter)
+}
+
+func insertOne(ctx context.Context, hooks *writeHooks, col *mongo.Collection, doc interface{}) (interface{}, error) {
+	res, err := col.InsertOne(ctx, doc)
+	if err != nil {
+		return nil, err
+	}
+
+	if hooks.has() {
+		after := toBsonM(doc)
+		if after != nil {
+			after["_id"] = res.InsertedID
+		}
+		hooks.call(ctx, &WriteEvent{
+			Op:         WriteInsert,
+			Db:         col.Database().Name(),
+			Collection: col.Name(),
+			DocumentId: res.InsertedID,
+			After:      after,
+		})
+	}
+
+	return res.InsertedID, nil
+}
+
+func updateOne(ctx context.Context, hooks *writeHooks, col *mongo.Collection, filter interface{}, update interface{}, upsert ...bool) error {
+	if filter == nil {
+		filter = bson.M{}
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.Before)
+	if len(upsert) > 0 && upsert[0] {
+		opts = opts.SetUpsert(true)
+	}
+
+	if !hooks.has() {
+		uo := options.Update()
+		if opts.Upsert != nil {
+			uo = uo.SetUpsert(*opts.Upsert)
+		}
+		_, err := col.UpdateOne(ctx, filter, update, uo)
+		return err
+	}
+
+	var before bson.M
+	if err := col.FindOneAndUpdate(ctx, filter, update, opts).Decode(&before); err != nil && err != mongo.ErrNoDocuments {
+		return err
+	}
+
+	afterWrite(ctx, hooks, col, WriteUpdate, filter, before)
+	return nil
+}
+
+func replaceOne(ctx context.Context, hooks *writeHooks, col *mongo.Collection, filter interface{}, doc interface{}, upsert ...bool) error {
+	if filter == nil {
+		filter = bson.M{}
+	}
+
+	opts := options.FindOneAndReplace().SetReturnDocument(options.Before)
+	if len(upsert) > 0 && upsert[0] {
+		opts = opts.SetUpsert(true)
+	}
+
+	if !hooks.has() {
+		ro := options.Replace()
+		if opts.Upsert != nil {
+			ro = ro.SetUpsert(*opts.Upsert)
+		}
+		_, err := col.ReplaceOne(ctx, filter, doc, ro)
+		return err
+	}
+
+	var before bson.M
+	if err := col.FindOneAndReplace(ctx, filter, doc, opts).Decode(&before); err != nil && err != mongo.ErrNoDocuments {
+		return err
+	}
+
+	afterWrite(ctx, hooks, col, WriteReplace, filter, before)
+	return nil
+}
+
+func deleteOne(ctx context.Context, hooks *writeHooks, col *mongo.Collection, filter interface{}) error {
+	if filter == nil {
+		filter = bson.M{}
+	}
+
+	if !hooks.has() {
+		_, err := col.DeleteOne(ctx, filter)
+		return err
+	}
+
+	var before bson.M
+	if err := col.FindOneAndDelete(ctx, filter).Decode(&before); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		return err
+	}
+
+	hooks.call(ctx, &WriteEvent{
+		Op:         WriteDelete,
+		Db:         col.Database().Name(),
+		Collection: col.Name(),
+		DocumentId: before["_id"],
+		Before:     before,
+	})
+
+	return nil
+}
+
+// 更新/替换后查询最新文档并调用写入钩子
+// 写入已完成, 查询失败时只记录日志, 已匹配到文档时仍调用钩子 (After 为空)
+func afterWrite(ctx context.Context, hooks *writeHooks, col *mongo.Collection, op string, filter interface{}, before bson.M) {
+	var after bson.M
+	if before != nil {
+		filter = bson.M{"_id": before["_id"]}
+	}
+	if err := col.FindOne(ctx, filter).Decode(&after); err != nil {
+		if err != mongo.ErrNoDocuments {
+			log.Warnf("[%s.%s] find document after %s error: %s", col.Database().Name(), col.Name(), op, err.Error())
+		}
+		if before == nil { // 未匹配到文档, 或插入 (upsert) 的文档无法查询
+			return
+		}
+		after = nil
+	}
+
+	id := after["_id"]
+	if before != nil {
+		id = before["_id"]
+	}
+
+	hooks.call(ctx, &WriteEvent{
+		Op:         op,
+		Db:         col.Database().Name(),
+		Collection: col.Name(),
+		DocumentId: id,
+		Before:     before,
+		After:      after,
+	})
+}
+
+func toBsonM(doc interface{}) bson.M {
+	b, err := bson.Marshal(doc)
+	if err != nil {
+		return nil
+	}
+
+	var m bson.M
+	if err := bson.Unmarshal(b, &m); err != nil {
+		return nil
+	}
+
+	return m
+}
@@ -0,0 +1,56 @@
+package rds
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-redis/redis/v7"
+	"time"
+)
+
+const CaptchaKey = "CAPTCHA"
+
+// 验证码校验脚本, 返回 1: 校验成功, 0: 校验失败或验证码不存在
+var verifyCaptchaScript = redis.NewScript(`
+local ans = redis.call('HGET', KEYS[1], 'answer')
+if not ans then
+	return 0
+end
+if ans == ARGV[1] then
+	redis.call('DEL', KEYS[1])
+	return 1
+end
+local n = redis.call('HINCRBY', KEYS[1], 'attempts', 1)
+if n >= tonumber(ARGV[2]) then
+	redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// Redis 验证码存储
+type CaptchaStore struct {
+	rs *Store
+}
+
+func (s *CaptchaStore) Save(_ context.Context, id, answer string, expire time.Duration) error {
+	key := fmt.Sprintf("%s:%s", CaptchaKey, id)
+	_, err := s.rs.client.TxPipelined(func(tx redis.Pipeliner) error {
+		tx.HSet(key, "answer", answer, "attempts", 0)
+		tx.Expire(key, expire)
+		return nil
+	})
+	return err
+}
+
+func (s *CaptchaStore) Verify(_ context.Context, id, answer string, maxAttempts int) (bool, error) {
+	key := fmt.Sprintf("%s:%s", CaptchaKey, id)
+	res, err := verifyCaptchaScript.Run(s.rs.client, []string{key}, answer, maxAttempts).Int()
+	if err != nil {
+		return false, err
+	}
+	return res == 1, nil
+}
+
+// CaptchaStore 获取验证码存储
+func (rs *Store) CaptchaStore() *CaptchaStore {
+	return &CaptchaStore{rs: rs}
+}
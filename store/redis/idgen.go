@@ -0,0 +1,31 @@
+package rds
+
+import (
+	"context"
+	"fmt"
+	"github.com/cbwfree/micro-core/idgen"
+)
+
+const AutoIncIdKey = "AUTO_INC_ID"
+
+// IncrId 按步长增加自增ID, 返回增加后的值
+func (rs *Store) IncrId(name string, step int64) (int64, error) {
+	if step <= 0 {
+		return 0, idgen.ErrInvalidStep
+	}
+	return rs.client.IncrBy(fmt.Sprintf("%s:%s", AutoIncIdKey, name), step).Result()
+}
+
+// IdGen 获取INCR自增ID生成器 (每次获取ID都会访问Redis)
+func (rs *Store) IdGen() idgen.Generator {
+	return idgen.GeneratorFunc(func(_ context.Context, name string) (int64, error) {
+		return rs.IncrId(name, 1)
+	})
+}
+
+// SegmentIdGen 获取号段ID生成器
+func (rs *Store) SegmentIdGen(step ...int64) *idgen.Segment {
+	return idgen.NewSegment(func(_ context.Context, name string, n int64) (int64, error) {
+		return rs.IncrId(name, n)
+	}, step...)
+}
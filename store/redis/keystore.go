@@ -0,0 +1,50 @@
+package rds
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-redis/redis/v7"
+)
+
+const JWTKeysKey = "JWT_KEYS" // JWT秘钥集合
+
+// 秘钥集合保存脚本, 版本一致时保存, 返回 1: 成功, 0: 版本已变更
+var saveKeysScript = redis.NewScript(`
+local cur = redis.call('HGET', KEYS[1], 'version') or ''
+if cur ~= ARGV[1] then
+	return 0
+end
+redis.call('HSET', KEYS[1], 'version', ARGV[2], 'data', ARGV[3])
+return 1
+`)
+
+// Redis JWT秘钥存储, 供多个实例共享同一个秘钥集合
+type KeyStore struct {
+	rs  *Store
+	key string
+}
+
+func (s *KeyStore) Load(_ context.Context) ([]byte, string, error) {
+	res, err := s.rs.client.HMGet(s.key, "data", "version").Result()
+	if err != nil {
+		return nil, "", err
+	}
+
+	data, _ := res[0].(string)
+	version, _ := res[1].(string)
+
+	return []byte(data), version, nil
+}
+
+func (s *KeyStore) Save(_ context.Context, prev, version string, data []byte) (bool, error) {
+	n, err := saveKeysScript.Run(s.rs.client, []string{s.key}, prev, version, data).Int()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+// KeyStore 获取JWT秘钥存储, name 区分不同的秘钥集合
+func (rs *Store) KeyStore(name string) *KeyStore {
+	return &KeyStore{rs: rs, key: fmt.Sprintf("%s:%s", JWTKeysKey, name)}
+}
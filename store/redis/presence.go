@@ -0,0 +1,54 @@
+package rds
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-redis/redis/v7"
+)
+
+const SocketPresenceKey = "SOCKET_PRESENCE" // 房间 -> 连接ID -> 节点ID
+
+// Redis 房间在线状态存储
+// 记录登记到节点目录中, 节点失效后随 SocketDirectory.Purge 一并清理
+type PresenceStore struct {
+	rs *Store
+}
+
+func (s *PresenceStore) Join(_ context.Context, node, room, connId string) error {
+	key := presenceKey(room)
+	_, err := s.rs.client.TxPipelined(func(tx redis.Pipeliner) error {
+		tx.HSet(key, connId, node)
+		tx.SAdd(nodeKey(node), entry(key, connId))
+		return nil
+	})
+	return err
+}
+
+func (s *PresenceStore) Leave(_ context.Context, node, room, connId string) error {
+	key := presenceKey(room)
+	_, err := s.rs.client.TxPipelined(func(tx redis.Pipeliner) error {
+		tx.HDel(key, connId)
+		tx.SRem(nodeKey(node), entry(key, connId))
+		return nil
+	})
+	return err
+}
+
+func (s *PresenceStore) Count(_ context.Context, room string) (int, error) {
+	n, err := s.rs.client.HLen(presenceKey(room)).Result()
+	return int(n), err
+}
+
+// Members 获取房间所有连接ID及其所在节点
+func (s *PresenceStore) Members(_ context.Context, room string) (map[string]string, error) {
+	return s.rs.client.HGetAll(presenceKey(room)).Result()
+}
+
+// PresenceStore 获取房间在线状态存储
+func (rs *Store) PresenceStore() *PresenceStore {
+	return &PresenceStore{rs: rs}
+}
+
+func presenceKey(room string) string {
+	return fmt.Sprintf("%s:%s", SocketPresenceKey, room)
+}
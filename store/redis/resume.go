@@ -0,0 +1,31 @@
+package rds
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-redis/redis/v7"
+)
+
+const ResumeTokenKey = "RESUME_TOKEN"
+
+// Redis 断点续传令牌存储
+type ResumeStore struct {
+	rs *Store
+}
+
+func (s *ResumeStore) Load(_ context.Context, name string) ([]byte, error) {
+	b, err := s.rs.client.Get(fmt.Sprintf("%s:%s", ResumeTokenKey, name)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	return b, err
+}
+
+func (s *ResumeStore) Save(_ context.Context, name string, token []byte) error {
+	return s.rs.client.Set(fmt.Sprintf("%s:%s", ResumeTokenKey, name), token, 0).Err()
+}
+
+// ResumeStore 获取断点续传令牌存储
+func (rs *Store) ResumeStore() *ResumeStore {
+	return &ResumeStore{rs: rs}
+}
@@ -0,0 +1,36 @@
+package rds
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-redis/redis/v7"
+	"time"
+)
+
+const SessionKey = "SESSION"
+
+// Redis Session存储
+type SessionStore struct {
+	rs *Store
+}
+
+func (s *SessionStore) Load(_ context.Context, id string) ([]byte, error) {
+	b, err := s.rs.client.Get(fmt.Sprintf("%s:%s", SessionKey, id)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	return b, err
+}
+
+func (s *SessionStore) Save(_ context.Context, id string, data []byte, ttl time.Duration) error {
+	return s.rs.client.Set(fmt.Sprintf("%s:%s", SessionKey, id), data, ttl).Err()
+}
+
+func (s *SessionStore) Delete(_ context.Context, id string) error {
+	return s.rs.client.Del(fmt.Sprintf("%s:%s", SessionKey, id)).Err()
+}
+
+// SessionStore 获取Session存储
+func (rs *Store) SessionStore() *SessionStore {
+	return &SessionStore{rs: rs}
+}
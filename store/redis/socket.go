@@ -0,0 +1,173 @@
+package rds
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-redis/redis/v7"
+	"strconv"
+	"time"
+)
+
+const (
+	SocketConnKey  = "SOCKET_CONN"  // 连接ID -> 节点ID
+	SocketIndexKey = "SOCKET_INDEX" // 索引 (meta, 分组) -> 连接ID -> 节点ID
+	SocketNodeKey  = "SOCKET_NODE"  // 节点 -> 登记的目录项
+	SocketNodesKey = "SOCKET_NODES" // 节点心跳时间
+)
+
+// 清理节点登记的目录项, 仅删除仍指向该节点的项 (连接恢复到其他节点后目录项已指向新节点)
+// KEYS[1]: 节点登记集合, KEYS[2]: 节点心跳集合, ARGV[1]: 节点ID
+var purgeNodeScript = redis.NewScript(`
+for _, e in ipairs(redis.call('SMEMBERS', KEYS[1])) do
+	local i = string.find(e, '|[^|]*$')
+	if i then
+		local key, field = string.sub(e, 1, i - 1), string.sub(e, i + 1)
+		if redis.call('HGET', key, field) == ARGV[1] then
+			redis.call('HDEL', key, field)
+		end
+	end
+end
+redis.call('DEL', KEYS[1])
+redis.call('ZREM', KEYS[2], ARGV[1])
+return 1
+`)
+
+// 移除节点登记的目录项 (连接或索引), 目录项仍指向该节点时才删除
+// KEYS[1]: 连接目录或索引, KEYS[2]: 节点登记集合, ARGV[1]: 节点ID, ARGV[2]: 连接ID, ARGV[3]: 登记项
+var delEntryScript = redis.NewScript(`
+if redis.call('HGET', KEYS[1], ARGV[2]) == ARGV[1] then
+	redis.call('HDEL', KEYS[1], ARGV[2])
+end
+redis.call('SREM', KEYS[2], ARGV[3])
+return 1
+`)
+
+// Redis Socket 消息总线 (Pub/Sub)
+type SocketBus struct {
+	rs *Store
+}
+
+func (b *SocketBus) Publish(topic string, data []byte) error {
+	return b.rs.client.Publish(topic, data).Err()
+}
+
+func (b *SocketBus) Subscribe(topic string, handler func(data []byte)) (func() error, error) {
+	ps := b.rs.client.Subscribe(topic)
+	if _, err := ps.Receive(); err != nil {
+		_ = ps.Close()
+		return nil, err
+	}
+
+	go func() {
+		for msg := range ps.Channel() {
+			handler([]byte(msg.Payload))
+		}
+	}()
+
+	return ps.Close, nil
+}
+
+// SocketBus 获取Socket消息总线
+func (rs *Store) SocketBus() *SocketBus {
+	return &SocketBus{rs: rs}
+}
+
+// Redis Socket 连接目录
+type SocketDirectory struct {
+	rs *Store
+}
+
+func (d *SocketDirectory) AddConn(_ context.Context, node, connId string) error {
+	_, err := d.rs.client.TxPipelined(func(tx redis.Pipeliner) error {
+		tx.HSet(SocketConnKey, connId, node)
+		tx.SAdd(nodeKey(node), entry(SocketConnKey, connId))
+		return nil
+	})
+	return err
+}
+
+func (d *SocketDirectory) DelConn(_ context.Context, node, connId string) error {
+	keys := []string{SocketConnKey, nodeKey(node)}
+	return delEntryScript.Run(d.rs.client, keys, node, connId, entry(SocketConnKey, connId)).Err()
+}
+
+func (d *SocketDirectory) AddIndex(_ context.Context, node, index, connId string) error {
+	key := indexKey(index)
+	_, err := d.rs.client.TxPipelined(func(tx redis.Pipeliner) error {
+		tx.HSet(key, connId, node)
+		tx.SAdd(nodeKey(node), entry(key, connId))
+		return nil
+	})
+	return err
+}
+
+func (d *SocketDirectory) DelIndex(_ context.Context, node, index, connId string) error {
+	key := indexKey(index)
+	return delEntryScript.Run(d.rs.client, []string{key, nodeKey(node)}, node, connId, entry(key, connId)).Err()
+}
+
+func (d *SocketDirectory) ConnNode(_ context.Context, connId string) (string, error) {
+	node, err := d.rs.client.HGet(SocketConnKey, connId).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	return node, err
+}
+
+func (d *SocketDirectory) IndexNodes(_ context.Context, index string) ([]string, error) {
+	values, err := d.rs.client.HVals(indexKey(index)).Result()
+	if err != nil {
+		return nil, err
+	}
+	return unique(values), nil
+}
+
+func (d *SocketDirectory) IndexConns(_ context.Context, index string) (map[string]string, error) {
+	return d.rs.client.HGetAll(indexKey(index)).Result()
+}
+
+func (d *SocketDirectory) Heartbeat(_ context.Context, node string) error {
+	return d.rs.client.ZAdd(SocketNodesKey, &redis.Z{
+		Score:  float64(time.Now().Unix()),
+		Member: node,
+	}).Err()
+}
+
+func (d *SocketDirectory) DeadNodes(_ context.Context, ttl time.Duration) ([]string, error) {
+	max := strconv.FormatInt(time.Now().Add(-ttl).Unix(), 10)
+	return d.rs.client.ZRangeByScore(SocketNodesKey, &redis.ZRangeBy{Min: "-inf", Max: "(" + max}).Result()
+}
+
+func (d *SocketDirectory) Purge(_ context.Context, node string) error {
+	return purgeNodeScript.Run(d.rs.client, []string{nodeKey(node), SocketNodesKey}, node).Err()
+}
+
+// SocketDirectory 获取Socket连接目录
+func (rs *Store) SocketDirectory() *SocketDirectory {
+	return &SocketDirectory{rs: rs}
+}
+
+func nodeKey(node string) string {
+	return fmt.Sprintf("%s:%s", SocketNodeKey, node)
+}
+
+func indexKey(index string) string {
+	return fmt.Sprintf("%s:%s", SocketIndexKey, index)
+}
+
+func entry(key, field string) string {
+	return key + "|" + field
+}
+
+func unique(values []string) []string {
+	seen := make(map[string]struct{}, len(values))
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		if _, ok := seen[v]; ok {
+			continue
+		}
+		seen[v] = struct{}{}
+		result = append(result, v)
+	}
+	return result
+}
@@ -0,0 +1,113 @@
+package rds
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"github.com/go-redis/redis/v7"
+	"strconv"
+	"time"
+)
+
+const (
+	SocketSessionKey      = "SOCKET_SESSION"       // 会话待确认消息 (有序集合, score 为序号)
+	SocketSessionSeqKey   = "SOCKET_SESSION_SEQ"   // 会话最后的消息序号
+	SocketSessionOwnerKey = "SOCKET_SESSION_OWNER" // 会话所属用户
+)
+
+// Redis Socket 会话恢复存储
+type SocketSessionStore struct {
+	rs *Store
+}
+
+func (s *SocketSessionStore) Touch(_ context.Context, session, owner string, ttl time.Duration) error {
+	key := socketSessionSeqKey(session)
+	ownerKey := socketSessionOwnerKey(session)
+	_, err := s.rs.client.TxPipelined(func(tx redis.Pipeliner) error {
+		tx.SetNX(key, 0, ttl)
+		tx.Expire(key, ttl)
+		tx.SetNX(ownerKey, owner, ttl)
+		tx.Expire(ownerKey, ttl)
+		tx.Expire(socketSessionKey(session), ttl)
+		return nil
+	})
+	return err
+}
+
+func (s *SocketSessionStore) Owner(_ context.Context, session string) (string, error) {
+	owner, err := s.rs.client.Get(socketSessionOwnerKey(session)).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	return owner, err
+}
+
+func (s *SocketSessionStore) Append(_ context.Context, session string, seq uint64, frame []byte, limit int, ttl time.Duration) error {
+	key := socketSessionKey(session)
+	member := make([]byte, 8+len(frame))
+	binary.BigEndian.PutUint64(member, seq)
+	copy(member[8:], frame)
+
+	_, err := s.rs.client.TxPipelined(func(tx redis.Pipeliner) error {
+		tx.ZAdd(key, &redis.Z{Score: float64(seq), Member: member})
+		if limit > 0 {
+			tx.ZRemRangeByRank(key, 0, int64(-limit-1))
+		}
+		tx.Expire(key, ttl)
+		tx.Expire(socketSessionOwnerKey(session), ttl)
+		tx.Set(socketSessionSeqKey(session), seq, ttl)
+		return nil
+	})
+	return err
+}
+
+func (s *SocketSessionStore) Replay(_ context.Context, session string, after uint64) ([][]byte, uint64, bool, error) {
+	last, err := s.rs.client.Get(socketSessionSeqKey(session)).Uint64()
+	if err == redis.Nil {
+		return nil, 0, false, nil
+	} else if err != nil {
+		return nil, 0, false, err
+	}
+
+	members, err := s.rs.client.ZRangeByScore(socketSessionKey(session), &redis.ZRangeBy{
+		Min: "(" + strconv.FormatUint(after, 10),
+		Max: "+inf",
+	}).Result()
+	if err != nil {
+		return nil, 0, false, err
+	}
+
+	frames := make([][]byte, 0, len(members))
+	for _, m := range members {
+		if len(m) > 8 {
+			frames = append(frames, []byte(m[8:]))
+		}
+	}
+
+	return frames, last, true, nil
+}
+
+func (s *SocketSessionStore) Ack(_ context.Context, session string, seq uint64) error {
+	return s.rs.client.ZRemRangeByScore(socketSessionKey(session), "-inf", strconv.FormatUint(seq, 10)).Err()
+}
+
+func (s *SocketSessionStore) Delete(_ context.Context, session string) error {
+	return s.rs.client.Del(socketSessionKey(session), socketSessionSeqKey(session), socketSessionOwnerKey(session)).Err()
+}
+
+// SocketSessionStore 获取Socket会话恢复存储
+func (rs *Store) SocketSessionStore() *SocketSessionStore {
+	return &SocketSessionStore{rs: rs}
+}
+
+func socketSessionKey(session string) string {
+	return fmt.Sprintf("%s:%s", SocketSessionKey, session)
+}
+
+func socketSessionSeqKey(session string) string {
+	return fmt.Sprintf("%s:%s", SocketSessionSeqKey, session)
+}
+
+func socketSessionOwnerKey(session string) string {
+	return fmt.Sprintf("%s:%s", SocketSessionOwnerKey, session)
+}
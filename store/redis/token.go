@@ -0,0 +1,113 @@
+package rds
+
+import (
+	"context"
+	"fmt"
+	"github.com/cbwfree/micro-core/jwt"
+	"github.com/go-redis/redis/v7"
+	"time"
+)
+
+const (
+	TokenRevokedKey        = "JWT_REVOKED"         // 令牌吊销列表
+	TokenSubjectRevokedKey = "JWT_SUBJECT_REVOKED" // 主体吊销时间 (毫秒)
+	TokenRefreshKey        = "JWT_REFRESH"         // 刷新令牌家族
+	TokenSubjectRefreshKey = "JWT_SUBJECT_REFRESH" // 主体的刷新令牌家族集合
+)
+
+// 刷新令牌轮换脚本, 返回 1: 轮换成功, 0: 家族不存在, -1: 旧令牌已被使用 (删除家族)
+var rotateRefreshScript = redis.NewScript(`
+local cur = redis.call('GET', KEYS[1])
+if not cur then
+	return 0
+end
+if cur ~= ARGV[1] then
+	redis.call('DEL', KEYS[1])
+	return -1
+end
+redis.call('SET', KEYS[1], ARGV[2], 'PX', ARGV[3])
+return 1
+`)
+
+// Redis JWT令牌存储
+type TokenStore struct {
+	rs *Store
+}
+
+func (s *TokenStore) Revoke(_ context.Context, jti string, ttl time.Duration) error {
+	return s.rs.client.Set(fmt.Sprintf("%s:%s", TokenRevokedKey, jti), 1, ttl).Err()
+}
+
+func (s *TokenStore) IsRevoked(_ context.Context, jti string) (bool, error) {
+	n, err := s.rs.client.Exists(fmt.Sprintf("%s:%s", TokenRevokedKey, jti)).Result()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (s *TokenStore) RevokeSubject(_ context.Context, sub string, at int64, ttl time.Duration) error {
+	setKey := fmt.Sprintf("%s:%s", TokenSubjectRefreshKey, sub)
+	families, err := s.rs.client.SMembers(setKey).Result()
+	if err != nil {
+		return err
+	}
+
+	_, err = s.rs.client.TxPipelined(func(tx redis.Pipeliner) error {
+		for _, family := range families {
+			tx.Del(fmt.Sprintf("%s:%s", TokenRefreshKey, family))
+		}
+		tx.Del(setKey)
+		tx.Set(fmt.Sprintf("%s:%s", TokenSubjectRevokedKey, sub), at, ttl)
+		return nil
+	})
+
+	return err
+}
+
+func (s *TokenStore) SubjectRevokedAt(_ context.Context, sub string) (int64, error) {
+	at, err := s.rs.client.Get(fmt.Sprintf("%s:%s", TokenSubjectRevokedKey, sub)).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return at, err
+}
+
+func (s *TokenStore) SaveRefresh(_ context.Context, sub, family, jti string, ttl time.Duration) error {
+	_, err := s.rs.client.TxPipelined(func(tx redis.Pipeliner) error {
+		tx.Set(fmt.Sprintf("%s:%s", TokenRefreshKey, family), jti, ttl)
+		if sub != "" {
+			setKey := fmt.Sprintf("%s:%s", TokenSubjectRefreshKey, sub)
+			tx.SAdd(setKey, family)
+			tx.Expire(setKey, ttl)
+		}
+		return nil
+	})
+	return err
+}
+
+func (s *TokenStore) RotateRefresh(_ context.Context, family, oldJti, newJti string, ttl time.Duration) error {
+	key := fmt.Sprintf("%s:%s", TokenRefreshKey, family)
+	res, err := rotateRefreshScript.Run(s.rs.client, []string{key}, oldJti, newJti, ttl.Milliseconds()).Int()
+	if err != nil {
+		return err
+	}
+
+	switch res {
+	case 1:
+		return nil
+	case -1:
+		return jwt.ErrRefreshReused
+	default:
+		return jwt.ErrRefreshInvalid
+	}
+}
+
+func (s *TokenStore) RevokeRefresh(_ context.Context, family string) error {
+	return s.rs.client.Del(fmt.Sprintf("%s:%s", TokenRefreshKey, family)).Err()
+}
+
+// TokenStore 获取JWT令牌存储
+func (rs *Store) TokenStore() *TokenStore {
+	return &TokenStore{rs: rs}
+}
@@ -0,0 +1,140 @@
+package rds
+
+import (
+	"context"
+	"fmt"
+	"github.com/cbwfree/micro-core/idgen"
+	"github.com/go-redis/redis/v7"
+	log "github.com/micro/go-micro/v2/logger"
+	"sync"
+	"time"
+)
+
+const SnowflakeWorkerKey = "SNOWFLAKE_WORKER" // 雪花算法节点ID租约
+
+// 续约脚本, 仍为租约持有者时延长过期时间, 返回 1: 成功, 0: 租约已丢失
+var renewWorkerScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// 释放脚本, 仅删除自己持有的租约
+var releaseWorkerScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// 雪花算法节点ID租约
+// 通过 SET NX PX 占用空闲的节点ID, 后台每 ttl/3 续约一次, 续约失败超过 ttl 后租约失效
+// 租约失效后不再续约, 配合 idgen.NewLeasedSnowflake 使用时会重新租用新的节点ID
+type WorkerLease struct {
+	sync.RWMutex
+	rs       *Store
+	owner    string
+	worker   int64
+	ttl      time.Duration
+	deadline time.Time // 租约有效期
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+func (l *WorkerLease) Worker() int64 {
+	return l.worker
+}
+
+func (l *WorkerLease) Alive() bool {
+	l.RLock()
+	defer l.RUnlock()
+	return time.Now().Before(l.deadline)
+}
+
+// Release 停止续约并释放节点ID
+func (l *WorkerLease) Release() error {
+	l.cancel()
+	<-l.done
+
+	l.Lock()
+	l.deadline = time.Time{}
+	l.Unlock()
+
+	return releaseWorkerScript.Run(l.rs.client, []string{workerKey(l.worker)}, l.owner).Err()
+}
+
+func (l *WorkerLease) keepalive(ctx context.Context) {
+	defer close(l.done)
+
+	ticker := time.NewTicker(l.ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		start := time.Now()
+		n, err := renewWorkerScript.Run(l.rs.client, []string{workerKey(l.worker)}, l.owner, l.ttl.Milliseconds()).Int()
+		if err != nil {
+			log.Warnf("renew snowflake worker %d error: %s", l.worker, err)
+			continue
+		}
+		if n == 0 {
+			log.Errorf("snowflake worker %d lease lost", l.worker)
+			l.Lock()
+			l.deadline = time.Time{}
+			l.Unlock()
+			return
+		}
+
+		l.Lock()
+		l.deadline = start.Add(l.ttl)
+		l.Unlock()
+	}
+}
+
+// LeaseWorker 租用雪花算法节点ID, owner 为节点唯一标识
+// 从递增的起始位置依次尝试占用, 所有节点ID都被占用时返回 idgen.ErrNoWorker
+func (rs *Store) LeaseWorker(owner string, ttl time.Duration) (*WorkerLease, error) {
+	next, err := rs.client.Incr(workerKey("next")).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := int64(0); i <= idgen.SnowflakeMaxWorker; i++ {
+		worker := (next + i) & idgen.SnowflakeMaxWorker
+		start := time.Now()
+		ok, err := rs.client.SetNX(workerKey(worker), owner, ttl).Result()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		l := &WorkerLease{
+			rs:       rs,
+			owner:    owner,
+			worker:   worker,
+			ttl:      ttl,
+			deadline: start.Add(ttl),
+			cancel:   cancel,
+			done:     make(chan struct{}),
+		}
+		go l.keepalive(ctx)
+
+		log.Debugf("lease snowflake worker %d for %s", worker, owner)
+		return l, nil
+	}
+
+	return nil, idgen.ErrNoWorker
+}
+
+func workerKey(worker interface{}) string {
+	return fmt.Sprintf("%s:%v", SnowflakeWorkerKey, worker)
+}
@@ -0,0 +1,119 @@
+package web
+
+import (
+	"encoding/json"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	log "github.com/micro/go-micro/v2/logger"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 访问日志中需要脱敏的查询参数
+var accessLogRedactKeys = map[string]bool{
+	"token":         true,
+	"resume":        true,
+	"access_token":  true,
+	"refresh_token": true,
+	"password":      true,
+	"secret":        true,
+}
+
+// 访问日志
+type accessLog struct {
+	Time         string `json:"time"`
+	Id           string `json:"id"`
+	RemoteIp     string `json:"remote_ip"`
+	Host         string `json:"host"`
+	Method       string `json:"method"`
+	Uri          string `json:"uri"`
+	Route        string `json:"route"`
+	Status       int    `json:"status"`
+	Latency      int64  `json:"latency"`
+	LatencyHuman string `json:"latency_human"`
+	BytesIn      int64  `json:"bytes_in"`
+	BytesOut     int64  `json:"bytes_out"`
+	UserAgent    string `json:"user_agent"`
+	Error        string `json:"error,omitempty"`
+}
+
+// JSON格式的访问日志中间件, 通过 go-micro logger 输出
+// 5xx 使用 error 级别, 4xx 使用 warn 级别, 其他使用 info 级别
+func AccessLog(skipper middleware.Skipper) echo.MiddlewareFunc {
+	if skipper == nil {
+		skipper = middleware.DefaultSkipper
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if skipper(c) {
+				return next(c)
+			}
+
+			start := time.Now()
+			err := next(c)
+			if err != nil {
+				c.Error(err)
+			}
+			latency := time.Since(start)
+
+			req := c.Request()
+			res := c.Response()
+			entry := &accessLog{
+				Time:         start.Format(time.RFC3339Nano),
+				Id:           RequestIdOf(c),
+				RemoteIp:     c.RealIP(),
+				Host:         req.Host,
+				Method:       req.Method,
+				Uri:          redactUri(req.URL),
+				Route:        c.Path(),
+				Status:       res.Status,
+				Latency:      int64(latency),
+				LatencyHuman: latency.String(),
+				BytesOut:     res.Size,
+				UserAgent:    req.UserAgent(),
+			}
+			entry.BytesIn, _ = strconv.ParseInt(req.Header.Get(echo.HeaderContentLength), 10, 64)
+			if err != nil {
+				entry.Error = err.Error()
+			}
+
+			level := log.InfoLevel
+			switch {
+			case res.Status >= 500:
+				level = log.ErrorLevel
+			case res.Status >= 400:
+				level = log.WarnLevel
+			}
+			if b, err := json.Marshal(entry); err == nil {
+				log.Log(level, string(b))
+			}
+
+			return nil
+		}
+	}
+}
+
+// 请求路径及脱敏后的查询参数, 保持原有参数顺序
+func redactUri(u *url.URL) string {
+	if u.RawQuery == "" {
+		return u.Path
+	}
+
+	parts := strings.Split(u.RawQuery, "&")
+	for i, part := range parts {
+		key := part
+		if n := strings.IndexByte(part, '='); n >= 0 {
+			key = part[:n]
+		}
+		if k, err := url.QueryUnescape(key); err == nil {
+			key = k
+		}
+		if accessLogRedactKeys[strings.ToLower(key)] {
+			parts[i] = url.QueryEscape(key) + "=***"
+		}
+	}
+	return u.Path + "?" + strings.Join(parts, "&")
+}
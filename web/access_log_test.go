@@ -0,0 +1,113 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	log "github.com/micro/go-micro/v2/logger"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// 记录日志内容的 logger
+type captureLogger struct {
+	sync.Mutex
+	buf bytes.Buffer
+}
+
+func (l *captureLogger) Init(...log.Option) error                 { return nil }
+func (l *captureLogger) Options() log.Options                     { return log.Options{} }
+func (l *captureLogger) Fields(map[string]interface{}) log.Logger { return l }
+func (l *captureLogger) String() string                           { return "capture" }
+
+func (l *captureLogger) Log(_ log.Level, v ...interface{}) {
+	l.Lock()
+	defer l.Unlock()
+	fmt.Fprintln(&l.buf, v...)
+}
+
+func (l *captureLogger) Logf(_ log.Level, format string, v ...interface{}) {
+	l.Lock()
+	defer l.Unlock()
+	fmt.Fprintf(&l.buf, format+"\n", v...)
+}
+
+func (l *captureLogger) Output() string {
+	l.Lock()
+	defer l.Unlock()
+	return l.buf.String()
+}
+
+func TestAccessLogWithMetrics(t *testing.T) {
+	out := new(captureLogger)
+	logger := log.DefaultLogger
+	log.DefaultLogger = out
+	defer func() {
+		log.DefaultLogger = logger
+	}()
+
+	s, _ := startSocketServer(t, WithMetrics("/metrics"), WithAPIPrefix("/api"), WithAPIRoutes(func(g *echo.Group) {
+		g.GET("/fail", func(c echo.Context) error {
+			return echo.NewHTTPError(http.StatusTeapot, "teapot")
+		})
+	}))
+	defer s.Close()
+	addr := "http://" + s.echo.Listener.Addr().String()
+
+	res, err := http.Get(addr + "/api/fail?token=secret&page=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body Result
+	_ = json.NewDecoder(res.Body).Decode(&body)
+	_ = res.Body.Close()
+	if body.Code != http.StatusTeapot {
+		t.Fatalf("want code 418, got %+v", body)
+	}
+
+	// 访问日志记录错误及脱敏后的地址
+	var entry *accessLog
+	for _, line := range strings.Split(out.Output(), "\n") {
+		if i := strings.Index(line, `{"time"`); i >= 0 {
+			entry = new(accessLog)
+			if err := json.Unmarshal([]byte(line[i:]), entry); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if entry == nil {
+		t.Fatalf("access log not found: %s", out.Output())
+	}
+	if !strings.Contains(entry.Error, "teapot") {
+		t.Fatalf("unexpected access log: %+v", entry)
+	}
+	if entry.Uri != "/api/fail?token=***&page=1" {
+		t.Fatalf("query not redacted: %s", entry.Uri)
+	}
+
+	// 统计记录错误处理后的状态码
+	if metrics := string(s.metrics.Export()); !strings.Contains(metrics, `route="/api/fail",code="200"`) {
+		t.Fatalf("status not collected: %s", metrics)
+	}
+}
+
+func TestRedactUri(t *testing.T) {
+	tests := map[string]string{
+		"/p":                            "/p",
+		"/ws?token=abc&x=1":             "/ws?token=***&x=1",
+		"/ws?Resume=a&last_seq=3":       "/ws?Resume=***&last_seq=3",
+		"/p?password":                   "/p?password=***",
+		"/p?refresh%5Ftoken=q&a%5Fb=2":  "/p?refresh_token=***&a%5Fb=2",
+		"/p?access_token=a&secret=b&c=": "/p?access_token=***&secret=***&c=",
+	}
+	for uri, want := range tests {
+		u, _ := url.Parse(uri)
+		if got := redactUri(u); got != want {
+			t.Errorf("%s: want %s, got %s", uri, want, got)
+		}
+	}
+}
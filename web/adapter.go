@@ -0,0 +1,222 @@
+package web
+
+import (
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"io"
+	"net/http"
+	"net/textproto"
+	"reflect"
+	"strconv"
+)
+
+var (
+	ctxType   = reflect.TypeOf((*Context)(nil))
+	errorType = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// 自定义HTTP状态码, 返回数据实现此接口时使用指定的状态码输出
+type HTTPStatus interface {
+	HTTPStatus() int
+}
+
+// 自定义响应, 返回数据实现此接口时由其自行输出 (如文件下载, 流式响应)
+type Responder interface {
+	Respond(c *Context) error
+}
+
+// 流式响应
+type StreamResponse struct {
+	Code        int
+	ContentType string
+	Reader      io.Reader
+}
+
+func (sr *StreamResponse) Respond(c *Context) error {
+	code := sr.Code
+	if code == 0 {
+		code = http.StatusOK
+	}
+	return c.Ctx().Stream(code, sr.ContentType, sr.Reader)
+}
+
+// Handle 将类型化的处理函数转换为 echo.HandlerFunc
+// 支持的函数签名:
+//
+//	func(*web.Context, *Req) (*Resp, error)
+//	func(*web.Context, *Req) error
+//	func(*web.Context) (*Resp, error)
+//	func(*web.Context) error
+//
+// 请求参数依次从路径 (param), 查询参数 (query), 请求体 (json/form) 及 Header (header) 绑定, 并通过已注册的验证器验证
+// 返回数据统一使用 web.Result 输出, 错误通过 ParseError 转换, 处理函数已自行输出响应时不再重复输出
+func Handle(fn interface{}) echo.HandlerFunc {
+	fv := reflect.ValueOf(fn)
+	ft := fv.Type()
+	if err := checkHandler(ft); err != nil {
+		panic(err)
+	}
+
+	var reqType reflect.Type
+	if ft.NumIn() == 2 {
+		reqType = ft.In(1).Elem()
+	}
+
+	return func(c echo.Context) error {
+		ctx := ExtendCtx(c)
+
+		args := []reflect.Value{reflect.ValueOf(ctx)}
+		if reqType != nil {
+			req := reflect.New(reqType)
+			if err := bindRequest(c, req.Interface()); err != nil {
+				return ctx.Error(err)
+			}
+			args = append(args, req)
+		}
+
+		out := fv.Call(args)
+
+		if err, _ := out[len(out)-1].Interface().(error); err != nil {
+			if c.Response().Committed {
+				return err
+			}
+			return ctx.Error(err)
+		}
+
+		if c.Response().Committed {
+			return nil
+		}
+
+		var data interface{}
+		if len(out) == 2 && !isNilValue(out[0]) {
+			data = out[0].Interface()
+		}
+
+		return respond(ctx, data)
+	}
+}
+
+// 输出返回数据
+func respond(ctx *Context, data interface{}) error {
+	if r, ok := data.(Responder); ok {
+		return r.Respond(ctx)
+	}
+	if hs, ok := data.(HTTPStatus); ok {
+		return ctx.Ctx().JSON(hs.HTTPStatus(), NewResult(0, "success", data))
+	}
+	return ctx.JsonSuccess(data)
+}
+
+// 检查处理函数签名
+func checkHandler(ft reflect.Type) error {
+	if ft.Kind() != reflect.Func {
+		return fmt.Errorf("web.Handle: %s is not a function", ft)
+	}
+	if ft.NumIn() < 1 || ft.NumIn() > 2 || ft.In(0) != ctxType {
+		return fmt.Errorf("web.Handle: %s must accept *web.Context and an optional request pointer", ft)
+	}
+	if ft.NumIn() == 2 && (ft.In(1).Kind() != reflect.Ptr || ft.In(1).Elem().Kind() != reflect.Struct) {
+		return fmt.Errorf("web.Handle: request of %s must be a struct pointer", ft)
+	}
+	if ft.NumOut() < 1 || ft.NumOut() > 2 || ft.Out(ft.NumOut()-1) != errorType {
+		return fmt.Errorf("web.Handle: %s must return an optional response and an error", ft)
+	}
+	return nil
+}
+
+// 绑定并验证请求参数
+func bindRequest(c echo.Context, req interface{}) error {
+	if err := c.Bind(req); err != nil {
+		return err
+	}
+	if err := bindHeader(c.Request().Header, reflect.ValueOf(req).Elem()); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error()).SetInternal(err)
+	}
+	if c.Echo().Validator != nil {
+		if err := c.Validate(req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 绑定Header参数 (header 标签)
+func bindHeader(header http.Header, v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		fv := v.Field(i)
+		if !fv.CanSet() {
+			continue
+		}
+
+		if f.Anonymous && fv.Kind() == reflect.Struct {
+			if err := bindHeader(header, fv); err != nil {
+				return err
+			}
+			continue
+		}
+
+		name := f.Tag.Get("header")
+		if name == "" || name == "-" {
+			continue
+		}
+
+		values := header[textproto.CanonicalMIMEHeaderKey(name)]
+		if len(values) == 0 {
+			continue
+		}
+
+		if fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() == reflect.String {
+			fv.Set(reflect.ValueOf(values))
+			continue
+		}
+
+		if err := setHeaderField(fv, values[0]); err != nil {
+			return fmt.Errorf("invalid header %s: %s", name, err.Error())
+		}
+	}
+	return nil
+}
+
+func setHeaderField(fv reflect.Value, val string) error {
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(val)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(val, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(val, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(val, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported type %s", fv.Type())
+	}
+	return nil
+}
+
+func isNilValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+		return v.IsNil()
+	}
+	return false
+}
@@ -0,0 +1,174 @@
+package web
+
+import (
+	"github.com/cbwfree/micro-core/jwt"
+	"github.com/labstack/echo/v4"
+	log "github.com/micro/go-micro/v2/logger"
+	"net/http"
+	"strings"
+)
+
+const (
+	ctxAuthClaims = "_auth_claims" // JWT声明
+	ctxAuthData   = "_auth_data"   // JWT数据
+
+	DefaultAuthLookup = "header:" + echo.HeaderAuthorization
+	DefaultAuthScheme = "Bearer"
+)
+
+// 角色声明, claims.Data 实现此接口时可进行角色验证
+type RoleClaims interface {
+	AuthRoles() []string
+}
+
+// 权限范围声明, claims.Data 实现此接口时可进行权限范围验证
+type ScopeClaims interface {
+	AuthScopes() []string
+}
+
+type AuthConfig struct {
+	Token         string             // Token名称, 通过 jwt.Get 获取
+	Lookup        string             // Token来源, 多个来源使用,分隔. 格式: header:Authorization,cookie:token,query:token
+	Scheme        string             // Header中Token的前缀
+	NewData       func() interface{} // 创建 claims.Data 的类型化对象 (指针)
+	Optional      bool               // 是否允许未认证访问
+	RefreshHeader string             // 刷新Token的响应Header
+}
+
+// JWTAuth JWT认证中间件
+func JWTAuth(cfg AuthConfig) echo.MiddlewareFunc {
+	if cfg.Lookup == "" {
+		cfg.Lookup = DefaultAuthLookup
+	}
+	if cfg.Scheme == "" {
+		cfg.Scheme = DefaultAuthScheme
+	}
+	if cfg.RefreshHeader == "" {
+		cfg.RefreshHeader = echo.HeaderAuthorization
+	}
+
+	extractors := authExtractors(cfg.Lookup, cfg.Scheme)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			token := jwt.Get(cfg.Token)
+			if token == nil {
+				return echo.NewHTTPError(http.StatusInternalServerError, "invalid jwt token: "+cfg.Token)
+			}
+
+			var str string
+			for _, ext := range extractors {
+				if str = ext(c); str != "" {
+					break
+				}
+			}
+
+			if str == "" {
+				if cfg.Optional {
+					return next(c)
+				}
+				return echo.NewHTTPError(http.StatusUnauthorized)
+			}
+
+			var data interface{}
+			if cfg.NewData != nil {
+				data = cfg.NewData()
+			}
+
+			claims, refresh, err := token.ParseContext(c.Request().Context(), str, data)
+			if err != nil {
+				if cfg.Optional {
+					return next(c)
+				}
+				// 不向客户端返回Token校验失败的原因
+				log.Debugf("[%s] JWT Authenticate Failure: %s", RequestIdOf(c), err.Error())
+				return echo.NewHTTPError(http.StatusUnauthorized)
+			}
+
+			c.Set(ctxAuthClaims, claims)
+			c.Set(ctxAuthData, claims.Data)
+
+			// 返回刷新后的Token
+			if refresh != "" {
+				c.Response().Header().Set(cfg.RefreshHeader, cfg.Scheme+" "+refresh)
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// 根据Token来源创建Token提取函数
+func authExtractors(lookups string, scheme string) []func(c echo.Context) string {
+	var extractors []func(c echo.Context) string
+	for _, lookup := range strings.Split(lookups, ",") {
+		parts := strings.SplitN(strings.TrimSpace(lookup), ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		switch name := parts[1]; parts[0] {
+		case "header":
+			extractors = append(extractors, func(c echo.Context) string {
+				auth := c.Request().Header.Get(name)
+				if len(auth) > len(scheme)+1 && strings.EqualFold(auth[:len(scheme)], scheme) {
+					return strings.TrimSpace(auth[len(scheme)+1:])
+				}
+				return ""
+			})
+		case "cookie":
+			extractors = append(extractors, func(c echo.Context) string {
+				if ck, err := c.Cookie(name); err == nil {
+					return ck.Value
+				}
+				return ""
+			})
+		case "query":
+			extractors = append(extractors, func(c echo.Context) string {
+				return c.QueryParam(name)
+			})
+		}
+	}
+	return extractors
+}
+
+// AuthRequired 必须认证
+func AuthRequired(name string, newData func() interface{}) echo.MiddlewareFunc {
+	return JWTAuth(AuthConfig{Token: name, NewData: newData})
+}
+
+// AuthOptional 可选认证, 认证失败时继续处理请求
+func AuthOptional(name string, newData func() interface{}) echo.MiddlewareFunc {
+	return JWTAuth(AuthConfig{Token: name, NewData: newData, Optional: true})
+}
+
+// RequireRoles 验证角色 (满足任一角色即可)
+func RequireRoles(roles ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			ctx := ExtendCtx(c)
+			if !ctx.IsAuth() {
+				return echo.NewHTTPError(http.StatusUnauthorized)
+			}
+			if !ctx.HasRole(roles...) {
+				return echo.NewHTTPError(http.StatusForbidden)
+			}
+			return next(c)
+		}
+	}
+}
+
+// RequireScopes 验证权限范围 (必须满足全部权限)
+func RequireScopes(scopes ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			ctx := ExtendCtx(c)
+			if !ctx.IsAuth() {
+				return echo.NewHTTPError(http.StatusUnauthorized)
+			}
+			if !ctx.HasScope(scopes...) {
+				return echo.NewHTTPError(http.StatusForbidden)
+			}
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package web
+
+import (
+	"github.com/cbwfree/micro-core/jwt"
+	jwtgo "github.com/dgrijalva/jwt-go"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestJWTAuth(t *testing.T) {
+	jwt.New("web_auth_test", jwt.SigningMethod(jwtgo.SigningMethodHS256), jwt.SecretKey("secret"))
+	other := jwt.NewToken(jwt.SigningMethod(jwtgo.SigningMethodHS256), jwt.SecretKey("other"))
+
+	valid, err := jwt.Get("web_auth_test").EncryptFor("u1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged, err := other.EncryptFor("u1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := JWTAuth(AuthConfig{Token: "web_auth_test"})(func(c echo.Context) error {
+		return c.String(http.StatusOK, c.Get(ctxAuthClaims).(*jwt.Claims).Subject)
+	})
+
+	tests := []struct {
+		name   string
+		header string
+		code   int
+	}{
+		{"valid", "Bearer " + valid, http.StatusOK},
+		{"missing", "", http.StatusUnauthorized},
+		{"malformed", "Bearer abc", http.StatusUnauthorized},
+		{"bad signature", "Bearer " + forged, http.StatusUnauthorized},
+	}
+
+	e := echo.New()
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tt.header != "" {
+			req.Header.Set(echo.HeaderAuthorization, tt.header)
+		}
+		rec := httptest.NewRecorder()
+		err := h(e.NewContext(req, rec))
+
+		if tt.code == http.StatusOK {
+			if err != nil || rec.Body.String() != "u1" {
+				t.Fatalf("%s: unexpected result %q, %v", tt.name, rec.Body.String(), err)
+			}
+			continue
+		}
+
+		// 只返回 401, 不包含校验失败的原因
+		he, ok := err.(*echo.HTTPError)
+		if !ok || he.Code != tt.code || he.Message != http.StatusText(tt.code) || he.Internal != nil {
+			t.Fatalf("%s: unexpected error %#v", tt.name, err)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package web
+
+import (
+	"context"
+	"github.com/cbwfree/micro-core/captcha"
+	"github.com/labstack/echo/v4"
+	"gopkg.in/go-playground/validator.v9"
+	"net/http"
+	"reflect"
+)
+
+const (
+	HeaderCaptchaId = "X-Captcha-Id" // 验证码ID
+	HeaderCaptcha   = "X-Captcha"    // 验证码答案
+
+	DefaultCaptchaIdField = "captcha_id"
+	DefaultCaptchaField   = "captcha"
+)
+
+// CaptchaHandler 生成验证码, 请求参数 type=image 时直接返回PNG图片 (验证码ID通过 X-Captcha-Id 返回), 否则返回BASE64图片的JSON数据
+func CaptchaHandler(svc *captcha.Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := ExtendCtx(c)
+		c.Response().Header().Set("Cache-Control", "no-store")
+
+		if c.QueryParam("type") == "image" {
+			id, data, err := svc.Generate(c.Request().Context())
+			if err != nil {
+				return ctx.Error(err)
+			}
+			c.Response().Header().Set(HeaderCaptchaId, id)
+			c.Response().Header().Set(echo.HeaderContentType, "image/png")
+			c.Response().WriteHeader(http.StatusOK)
+			return data.WriteImage(c.Response())
+		}
+
+		res, err := svc.New(c.Request().Context())
+		if err != nil {
+			return ctx.Error(err)
+		}
+		return ctx.JsonSuccess(res)
+	}
+}
+
+// CaptchaRequired 验证码校验中间件, 验证码通过 Header (X-Captcha-Id, X-Captcha) 或表单参数 (captcha_id, captcha) 提交
+func CaptchaRequired(svc *captcha.Service) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			id := c.Request().Header.Get(HeaderCaptchaId)
+			if id == "" {
+				id = c.FormValue(DefaultCaptchaIdField)
+			}
+			answer := c.Request().Header.Get(HeaderCaptcha)
+			if answer == "" {
+				answer = c.FormValue(DefaultCaptchaField)
+			}
+
+			ok, err := svc.Verify(c.Request().Context(), id, answer)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return echo.NewHTTPError(http.StatusBadRequest, MsgCaptchaInvalid)
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// 验证码校验, Usage: captcha=CaptchaId (参数为验证码ID所在的字段名)
+func validCaptcha(svc *captcha.Service) validator.Func {
+	return func(fl validator.FieldLevel) bool {
+		parent := fl.Parent()
+		if parent.Kind() == reflect.Ptr {
+			parent = parent.Elem()
+		}
+		if parent.Kind() != reflect.Struct {
+			return false
+		}
+
+		idField := parent.FieldByName(fl.Param())
+		if !idField.IsValid() || idField.Kind() != reflect.String {
+			return false
+		}
+
+		ok, err := svc.Verify(context.Background(), idField.String(), fl.Field().String())
+		return err == nil && ok
+	}
+}
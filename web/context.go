@@ -2,11 +2,15 @@ package web
 
 import (
 	"github.com/cbwfree/micro-core/conv"
+	"github.com/cbwfree/micro-core/fn"
+	"github.com/cbwfree/micro-core/jwt"
+	mgo "github.com/cbwfree/micro-core/store/mongo"
 	"github.com/gorilla/sessions"
 	"github.com/labstack/echo-contrib/session"
 	"github.com/labstack/echo/v4"
 	"github.com/steambap/captcha"
 	"image/color"
+	"net/http"
 )
 
 type Context struct {
@@ -18,7 +22,17 @@ func (c *Context) Ctx() echo.Context {
 }
 
 func (c *Context) Error(err error) error {
-	return CtxResult(c.ctx, ParseError(err))
+	return CtxResult(c.ctx, ParseErrorLocale(err, c.Locale()))
+}
+
+// Locale 获取请求语言
+func (c *Context) Locale() string {
+	return ctxLocaleOf(c.ctx)
+}
+
+// T 翻译为请求语言的消息
+func (c *Context) T(key string, args ...interface{}) string {
+	return T(c.Locale(), key, args...)
 }
 
 func (c *Context) JsonError(code int, msg ...string) error {
@@ -43,66 +57,171 @@ func (c *Context) BindValid(req interface{}) error {
 	return nil
 }
 
+// BindQuery 按白名单解析查询参数为MongoDB查询条件
+func (c *Context) BindQuery(q *mgo.Query) (*mgo.QueryResult, error) {
+	res, err := q.Parse(c.ctx.QueryParams())
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	return res, nil
+}
+
+// IsAuth 是否已通过JWT认证
+func (c *Context) IsAuth() bool {
+	return c.Claims() != nil
+}
+
+// Claims 获取JWT声明
+func (c *Context) Claims() *jwt.Claims {
+	if claims, ok := c.ctx.Get(ctxAuthClaims).(*jwt.Claims); ok {
+		return claims
+	}
+	return nil
+}
+
+// AuthData 获取JWT数据 (类型由 AuthConfig.NewData 决定)
+func (c *Context) AuthData() interface{} {
+	return c.ctx.Get(ctxAuthData)
+}
+
+// HasRole 是否拥有任一角色
+func (c *Context) HasRole(roles ...string) bool {
+	rc, ok := c.AuthData().(RoleClaims)
+	if !ok {
+		return false
+	}
+	for _, r := range rc.AuthRoles() {
+		for _, role := range roles {
+			if r == role {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// HasScope 是否拥有全部权限范围
+func (c *Context) HasScope(scopes ...string) bool {
+	sc, ok := c.AuthData().(ScopeClaims)
+	if !ok {
+		return false
+	}
+	for _, scope := range scopes {
+		if !fn.InStrSlice(scope, sc.AuthScopes()) {
+			return false
+		}
+	}
+	return true
+}
+
+// 加载Session (每个请求仅加载一次)
+func (c *Context) sessState() *sessionState {
+	if st, ok := c.ctx.Get(ctxSessionState).(*sessionState); ok {
+		return st
+	}
+
+	st := new(sessionState)
+	if name, ok := c.ctx.Get(ctxSessionName).(string); !ok {
+		st.err = ErrSessionDisabled
+	} else {
+		st.s, st.err = session.Get(name, c.ctx)
+		if st.s != nil {
+			if ss, ok := st.s.Store().(*sessionStore); ok && ss.needTouch(st.s) {
+				st.dirty = true
+			}
+		}
+	}
+	c.ctx.Set(ctxSessionState, st)
+
+	return st
+}
+
+// 修改Session, 请求结束前统一保存
+func (c *Context) sessModify(closure func(s *sessions.Session)) {
+	st := c.sessState()
+	if st.s == nil {
+		return
+	}
+	closure(st.s)
+	st.dirty = true
+}
+
 func (c *Context) Session() *sessions.Session {
-	s, _ := session.Get("SESSION", c.Ctx())
-	return s
+	return c.sessState().s
 }
 
+// SessErr 获取Session加载错误
+func (c *Context) SessErr() error {
+	return c.sessState().err
+}
+
+// SessionDo 修改Session, 请求结束前统一保存
 func (c *Context) SessionDo(closure func(*sessions.Session) interface{}) interface{} {
-	s := c.Session()
-	res := closure(s)
-	_ = s.Save(c.ctx.Request(), c.ctx.Response())
+	var res interface{}
+	c.sessModify(func(s *sessions.Session) {
+		res = closure(s)
+	})
 	return res
 }
 
+// SessSave 立即保存Session
+func (c *Context) SessSave() error {
+	st := c.sessState()
+	if st.s == nil {
+		return st.err
+	}
+	st.dirty = true
+	return st.save(c.ctx)
+}
+
 func (c *Context) SessId() string {
-	res := c.SessionDo(func(ss *sessions.Session) interface{} {
-		return ss.ID
-	})
-	return res.(string)
+	if s := c.Session(); s != nil {
+		return s.ID
+	}
+	return ""
 }
 
 func (c *Context) SessOpts() *sessions.Options {
-	res := c.SessionDo(func(ss *sessions.Session) interface{} {
-		return ss.Options
-	})
-	return res.(*sessions.Options)
+	if s := c.Session(); s != nil {
+		return s.Options
+	}
+	return nil
 }
 
 func (c *Context) SessOptsSet(opts *sessions.Options) {
-	_ = c.SessionDo(func(ss *sessions.Session) interface{} {
-		ss.Options = opts
-		return nil
+	c.sessModify(func(s *sessions.Session) {
+		s.Options = opts
 	})
 }
 
 func (c *Context) SessFlashAdd(val interface{}, key ...string) {
-	_ = c.SessionDo(func(ss *sessions.Session) interface{} {
-		ss.AddFlash(val, key...)
-		return nil
+	c.sessModify(func(s *sessions.Session) {
+		s.AddFlash(val, key...)
 	})
 }
 
 func (c *Context) SessFlash(key ...string) []interface{} {
-	res := c.SessionDo(func(ss *sessions.Session) interface{} {
-		return ss.Flashes(key...)
-	})
-	return res.([]interface{})
+	var flashes []interface{}
+	if s := c.Session(); s != nil {
+		if flashes = s.Flashes(key...); len(flashes) > 0 {
+			c.sessState().dirty = true
+		}
+	}
+	return flashes
 }
 
 func (c *Context) SessGetValues() map[interface{}]interface{} {
-	res := c.SessionDo(func(ss *sessions.Session) interface{} {
-		return ss.Values
-	})
-	return res.(map[interface{}]interface{})
+	if s := c.Session(); s != nil {
+		return s.Values
+	}
+	return map[interface{}]interface{}{}
 }
 
 func (c *Context) SessSetValues(values map[interface{}]interface{}) {
-	_ = c.SessionDo(func(ss *sessions.Session) interface{} {
+	c.sessModify(func(s *sessions.Session) {
 		for k, v := range values {
-			ss.Values[k] = v
+			s.Values[k] = v
 		}
-		return nil
 	})
 }
 
@@ -115,6 +234,47 @@ func (c *Context) SessGet(key interface{}) interface{} {
 	return c.SessGetValues()[key]
 }
 
+func (c *Context) SessString(key interface{}) string {
+	return conv.String(c.SessGet(key))
+}
+
+func (c *Context) SessInt(key interface{}) int {
+	return conv.Int(c.SessGet(key))
+}
+
+func (c *Context) SessInt64(key interface{}) int64 {
+	return conv.Int64(c.SessGet(key))
+}
+
+func (c *Context) SessBool(key interface{}) bool {
+	return conv.Bool(c.SessGet(key))
+}
+
+// SessStruct 读取结构体 (使用 SessionCodec 解码)
+func (c *Context) SessStruct(key interface{}, out interface{}) error {
+	if err := c.SessErr(); err != nil {
+		return err
+	}
+	b, ok := c.SessGet(key).([]byte)
+	if !ok {
+		return ErrSessionNotFound
+	}
+	return SessionCodec.Unmarshal(b, out)
+}
+
+// SessSetStruct 保存结构体 (使用 SessionCodec 编码)
+func (c *Context) SessSetStruct(key interface{}, val interface{}) error {
+	if err := c.SessErr(); err != nil {
+		return err
+	}
+	b, err := SessionCodec.Marshal(val)
+	if err != nil {
+		return err
+	}
+	c.SessSet(key, b)
+	return nil
+}
+
 func (c *Context) SessSet(key, val interface{}) {
 	c.SessSetValues(map[interface{}]interface{}{
 		key: val,
@@ -122,24 +282,47 @@ func (c *Context) SessSet(key, val interface{}) {
 }
 
 func (c *Context) SessDel(key ...interface{}) {
-	c.SessionDo(func(s *sessions.Session) interface{} {
+	c.sessModify(func(s *sessions.Session) {
 		for _, k := range key {
-			if _, ok := s.Values[k]; ok {
-				delete(s.Values, k)
-			}
+			delete(s.Values, k)
 		}
-		return nil
 	})
 }
 
 func (c *Context) SessClean() {
-	c.SessionDo(func(s *sessions.Session) interface{} {
+	c.sessModify(func(s *sessions.Session) {
+		s.Values = make(map[interface{}]interface{})
+	})
+}
+
+// SessDestroy 销毁Session
+func (c *Context) SessDestroy() {
+	c.sessModify(func(s *sessions.Session) {
 		s.Values = make(map[interface{}]interface{})
-		return nil
+		opts := *s.Options
+		opts.MaxAge = -1
+		s.Options = &opts
 	})
 }
 
+// SessRegenerate 轮换Session ID (登录成功后调用)
+func (c *Context) SessRegenerate() error {
+	st := c.sessState()
+	if st.s == nil {
+		return st.err
+	}
+	if rg, ok := st.s.Store().(SessionRegenerator); ok {
+		if err := rg.Regenerate(c.ctx.Request(), st.s); err != nil {
+			return err
+		}
+	}
+	st.dirty = true
+	return nil
+}
+
 // 创建验证码
+//
+// Deprecated: 验证码保存在Session中, 请使用 captcha.Service 及 CaptchaHandler
 func (c *Context) CaptchaNew(key string, width, height int, setOpt ...captcha.SetOption) error {
 	var opt captcha.SetOption
 	if len(setOpt) > 0 && setOpt[0] != nil {
@@ -163,15 +346,16 @@ func (c *Context) CaptchaNew(key string, width, height int, setOpt ...captcha.Se
 	return data.WriteImage(c.ctx.Response().Writer)
 }
 
-// 验证验证码
+// 验证验证码 (验证后立即失效)
+//
+// Deprecated: 请使用 captcha.Service 及 CaptchaRequired
 func (c *Context) CaptchaCheck(key string, captcha string) bool {
-	codeText := c.SessionDo(func(s *sessions.Session) interface{} {
-		if c, ok := s.Values[key]; ok {
-			return c
-		}
-		return ""
-	})
-	return conv.String(codeText) == captcha
+	code := c.SessString(key)
+	if code == "" {
+		return false
+	}
+	c.SessDel(key)
+	return code == captcha
 }
 
 func ExtendCtx(ctx echo.Context) *Context {
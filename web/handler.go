@@ -6,7 +6,7 @@ import (
 
 // 统一错误处理
 func errorHandler(err error, ctx echo.Context) {
-	res := ParseError(err)
+	res := ParseErrorLocale(err, ctxLocaleOf(ctx))
 
 	// Send response
 	if !ctx.Response().Committed {
@@ -0,0 +1,171 @@
+package web
+
+import (
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	LocaleZh = "zh"
+	LocaleEn = "en"
+
+	ctxLocale = "_locale"
+
+	HeaderAcceptLanguage = "Accept-Language"
+
+	MsgNotFoundData    = "not_found_data"
+	MsgNotFoundCache   = "not_found_cache"
+	MsgNotFoundSession = "not_found_session"
+	MsgValidation      = "validation_failed"
+	MsgCaptchaInvalid  = "captcha_invalid"
+)
+
+// 自定义语言解析, 返回空字符串时使用 Accept-Language 协商
+type LocaleResolver func(c echo.Context) string
+
+var (
+	DefaultLocale = LocaleZh // 默认语言
+
+	catalogs = map[string]map[string]string{
+		LocaleZh: {
+			MsgNotFoundData:    "没有找到相关数据",
+			MsgNotFoundCache:   "没有找到缓存数据",
+			MsgNotFoundSession: "没有找到会话数据",
+			MsgValidation:      "数据验证失败",
+			MsgCaptchaInvalid:  "验证码错误",
+		},
+		LocaleEn: {
+			MsgNotFoundData:    "data not found",
+			MsgNotFoundCache:   "cache data not found",
+			MsgNotFoundSession: "session data not found",
+			MsgValidation:      "validation failed",
+			MsgCaptchaInvalid:  "invalid captcha",
+		},
+	}
+	catalogsMu sync.RWMutex
+)
+
+// RegisterMessages 注册 (或覆盖) 语言的消息目录
+func RegisterMessages(locale string, messages map[string]string) {
+	catalogsMu.Lock()
+	defer catalogsMu.Unlock()
+
+	locale = normalizeLocale(locale)
+	if catalogs[locale] == nil {
+		catalogs[locale] = make(map[string]string)
+	}
+	for k, v := range messages {
+		catalogs[locale][k] = v
+	}
+}
+
+// Locales 获取已注册的语言
+func Locales() []string {
+	catalogsMu.RLock()
+	defer catalogsMu.RUnlock()
+
+	var locales []string
+	for l := range catalogs {
+		locales = append(locales, l)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// T 翻译消息, 未找到时依次使用默认语言及消息Key
+func T(locale string, key string, args ...interface{}) string {
+	catalogsMu.RLock()
+	msg, ok := catalogs[normalizeLocale(locale)][key]
+	if !ok {
+		msg, ok = catalogs[DefaultLocale][key]
+	}
+	catalogsMu.RUnlock()
+
+	if !ok {
+		msg = key
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+// NegotiateLocale 根据 Accept-Language 选择已注册的语言
+func NegotiateLocale(accept string) string {
+	type lang struct {
+		tag string
+		q   float64
+	}
+
+	var langs []lang
+	for _, part := range strings.Split(accept, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		l := lang{tag: part, q: 1}
+		if i := strings.Index(part, ";"); i > 0 {
+			l.tag = strings.TrimSpace(part[:i])
+			if p := strings.TrimSpace(part[i+1:]); strings.HasPrefix(p, "q=") {
+				if q, err := strconv.ParseFloat(p[2:], 64); err == nil {
+					l.q = q
+				}
+			}
+		}
+		langs = append(langs, l)
+	}
+	sort.SliceStable(langs, func(i, j int) bool {
+		return langs[i].q > langs[j].q
+	})
+
+	catalogsMu.RLock()
+	defer catalogsMu.RUnlock()
+
+	for _, l := range langs {
+		tag := normalizeLocale(l.tag)
+		if _, ok := catalogs[tag]; ok {
+			return tag
+		}
+		// zh-CN => zh
+		if i := strings.Index(tag, "_"); i > 0 {
+			if _, ok := catalogs[tag[:i]]; ok {
+				return tag[:i]
+			}
+		}
+	}
+
+	return DefaultLocale
+}
+
+// 获取请求语言
+func ctxLocaleOf(c echo.Context) string {
+	if l, ok := c.Get(ctxLocale).(string); ok && l != "" {
+		return l
+	}
+	return NegotiateLocale(c.Request().Header.Get(HeaderAcceptLanguage))
+}
+
+// 语言中间件
+func localeMiddleware(resolver LocaleResolver) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			var locale string
+			if resolver != nil {
+				locale = normalizeLocale(resolver(c))
+			}
+			if locale == "" {
+				locale = NegotiateLocale(c.Request().Header.Get(HeaderAcceptLanguage))
+			}
+			c.Set(ctxLocale, locale)
+			return next(c)
+		}
+	}
+}
+
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.Replace(strings.TrimSpace(locale), "-", "_", -1))
+}
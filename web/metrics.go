@@ -0,0 +1,219 @@
+package web
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	DefaultDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+	DefaultSizeBuckets     = []float64{100, 1000, 10000, 100000, 1000000, 10000000}
+)
+
+// 直方图
+type histogram struct {
+	buckets []float64
+	counts  []uint64
+	sum     float64
+	count   uint64
+}
+
+func (h *histogram) observe(v float64) {
+	for i, b := range h.buckets {
+		if v <= b {
+			h.counts[i]++
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+func newHistogram(buckets []float64) *histogram {
+	return &histogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+}
+
+// 路由的统计数据
+type routeMetrics struct {
+	method   string
+	route    string
+	status   map[int]uint64 // 各状态码的请求数
+	duration *histogram     // 请求耗时 (秒)
+	size     *histogram     // 响应大小 (字节)
+}
+
+// Prometheus 格式的请求统计, 按路由统计请求数, 耗时, 响应大小及处理中的请求数
+type Metrics struct {
+	inFlight int64 // 处理中的请求数, 原子操作需64位对齐
+	sync.Mutex
+	namespace string
+	routes    map[string]*routeMetrics
+	known     map[string]bool // 已注册的路由
+	numRoutes int             // 已注册的路由数量
+}
+
+// 统计中间件
+func (m *Metrics) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			return m.handle(c, next)
+		}
+	}
+}
+
+func (m *Metrics) handle(c echo.Context, next echo.HandlerFunc) error {
+	atomic.AddInt64(&m.inFlight, 1)
+	defer atomic.AddInt64(&m.inFlight, -1)
+
+	start := time.Now()
+	err := next(c)
+	if err != nil {
+		c.Error(err) // 写入错误响应以获取状态码, 已写入的响应不会重复处理
+	}
+	m.observe(c.Request().Method, m.route(c), c.Response().Status, time.Since(start), c.Response().Size)
+
+	return err
+}
+
+// 请求匹配的路由, 未匹配时为 unknown, 避免按URI统计导致数据膨胀
+func (m *Metrics) route(c echo.Context) string {
+	path := c.Path()
+
+	m.Lock()
+	defer m.Unlock()
+
+	if !m.known[path] {
+		// 路由有变化时重新加载
+		if routes := c.Echo().Routes(); len(routes) != m.numRoutes {
+			m.known = make(map[string]bool, len(routes))
+			for _, r := range routes {
+				m.known[r.Path] = true
+			}
+			m.numRoutes = len(routes)
+		}
+		if !m.known[path] {
+			return "unknown"
+		}
+	}
+
+	return path
+}
+
+// 记录请求
+func (m *Metrics) observe(method, route string, status int, latency time.Duration, size int64) {
+	m.Lock()
+	defer m.Unlock()
+
+	key := method + " " + route
+	rm, ok := m.routes[key]
+	if !ok {
+		rm = &routeMetrics{
+			method:   method,
+			route:    route,
+			status:   make(map[int]uint64),
+			duration: newHistogram(DefaultDurationBuckets),
+			size:     newHistogram(DefaultSizeBuckets),
+		}
+		m.routes[key] = rm
+	}
+
+	rm.status[status]++
+	rm.duration.observe(latency.Seconds())
+	rm.size.observe(float64(size))
+}
+
+// 输出 Prometheus 文本格式
+func (m *Metrics) Handler(c echo.Context) error {
+	return c.Blob(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", m.Export())
+}
+
+// 导出 Prometheus 文本格式的统计数据
+func (m *Metrics) Export() []byte {
+	m.Lock()
+	defer m.Unlock()
+
+	keys := make([]string, 0, len(m.routes))
+	for k := range m.routes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+
+	name := m.name("requests_total")
+	fmt.Fprintf(&buf, "# HELP %s Total number of HTTP requests.\n# TYPE %s counter\n", name, name)
+	for _, k := range keys {
+		rm := m.routes[k]
+		codes := make([]int, 0, len(rm.status))
+		for code := range rm.status {
+			codes = append(codes, code)
+		}
+		sort.Ints(codes)
+		for _, code := range codes {
+			fmt.Fprintf(&buf, "%s{%s,code=\"%d\"} %d\n", name, rm.labels(), code, rm.status[code])
+		}
+	}
+
+	name = m.name("request_duration_seconds")
+	fmt.Fprintf(&buf, "# HELP %s HTTP request latency in seconds.\n# TYPE %s histogram\n", name, name)
+	for _, k := range keys {
+		writeHistogram(&buf, name, m.routes[k].labels(), m.routes[k].duration)
+	}
+
+	name = m.name("response_size_bytes")
+	fmt.Fprintf(&buf, "# HELP %s HTTP response size in bytes.\n# TYPE %s histogram\n", name, name)
+	for _, k := range keys {
+		writeHistogram(&buf, name, m.routes[k].labels(), m.routes[k].size)
+	}
+
+	name = m.name("requests_in_flight")
+	fmt.Fprintf(&buf, "# HELP %s Number of HTTP requests currently being served.\n# TYPE %s gauge\n", name, name)
+	fmt.Fprintf(&buf, "%s %d\n", name, atomic.LoadInt64(&m.inFlight))
+
+	return buf.Bytes()
+}
+
+func (m *Metrics) name(metric string) string {
+	if m.namespace == "" {
+		return metric
+	}
+	return m.namespace + "_" + metric
+}
+
+func (rm *routeMetrics) labels() string {
+	return fmt.Sprintf("method=\"%s\",route=\"%s\"", escapeLabel(rm.method), escapeLabel(rm.route))
+}
+
+func writeHistogram(buf *bytes.Buffer, name string, labels string, h *histogram) {
+	for i, b := range h.buckets {
+		fmt.Fprintf(buf, "%s_bucket{%s,le=\"%s\"} %d\n", name, labels, formatFloat(b), h.counts[i])
+	}
+	fmt.Fprintf(buf, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, labels, h.count)
+	fmt.Fprintf(buf, "%s_sum{%s} %s\n", name, labels, formatFloat(h.sum))
+	fmt.Fprintf(buf, "%s_count{%s} %d\n", name, labels, h.count)
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var labelReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(v string) string {
+	return labelReplacer.Replace(v)
+}
+
+// 创建请求统计, namespace 为指标名称前缀
+func NewMetrics(namespace string) *Metrics {
+	return &Metrics{
+		namespace: namespace,
+		routes:    make(map[string]*routeMetrics),
+	}
+}
@@ -0,0 +1,652 @@
+package web
+
+import (
+	"github.com/labstack/echo/v4"
+	log "github.com/micro/go-micro/v2/logger"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"html"
+	"net/http"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const OpenAPIVersion = "3.0.3"
+
+var (
+	apiDocs   = make(map[string]*APIDoc) // method + path => doc
+	apiDocsMu sync.RWMutex
+
+	timeType     = reflect.TypeOf(time.Time{})
+	objectIdType = reflect.TypeOf(primitive.ObjectID{})
+)
+
+// 接口文档
+type APIDoc struct {
+	OperationId string
+	Summary     string
+	Description string
+	Tags        []string
+	Request     interface{} // 请求参数类型, GET/DELETE/HEAD 作为查询参数, 其他作为JSON请求体
+	Response    interface{} // 返回数据类型 (Result.Data)
+	Auth        bool        // 是否需要Bearer认证
+	Deprecated  bool
+}
+
+// Doc 为已注册的路由添加接口文档
+//
+//	web.Doc(e.POST("/user", h), &web.APIDoc{Summary: "创建用户", Request: CreateUserReq{}, Response: User{}})
+func Doc(r *echo.Route, doc *APIDoc) *echo.Route {
+	apiDocsMu.Lock()
+	defer apiDocsMu.Unlock()
+
+	apiDocs[r.Method+" "+r.Path] = doc
+	return r
+}
+
+// OpenAPI 文档信息
+type OpenAPIInfo struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+type OpenAPIServer struct {
+	Url         string `json:"url"`
+	Description string `json:"description,omitempty"`
+}
+
+// OpenAPI 3 文档
+type OpenAPI struct {
+	Openapi    string                                  `json:"openapi"`
+	Info       OpenAPIInfo                             `json:"info"`
+	Servers    []OpenAPIServer                         `json:"servers,omitempty"`
+	Paths      map[string]map[string]*OpenAPIOperation `json:"paths"`
+	Components OpenAPIComponents                       `json:"components"`
+}
+
+type OpenAPIComponents struct {
+	Schemas         map[string]*Schema                `json:"schemas,omitempty"`
+	SecuritySchemes map[string]map[string]interface{} `json:"securitySchemes,omitempty"`
+}
+
+type OpenAPIOperation struct {
+	Summary     string                      `json:"summary,omitempty"`
+	Description string                      `json:"description,omitempty"`
+	Tags        []string                    `json:"tags,omitempty"`
+	OperationId string                      `json:"operationId,omitempty"`
+	Parameters  []*OpenAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *OpenAPIRequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*OpenAPIResponse `json:"responses"`
+	Security    []map[string][]string       `json:"security,omitempty"`
+	Deprecated  bool                        `json:"deprecated,omitempty"`
+}
+
+type OpenAPIParameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema"`
+}
+
+type OpenAPIMedia struct {
+	Schema *Schema `json:"schema"`
+}
+
+type OpenAPIRequestBody struct {
+	Required bool                     `json:"required,omitempty"`
+	Content  map[string]*OpenAPIMedia `json:"content"`
+}
+
+type OpenAPIResponse struct {
+	Description string                   `json:"description"`
+	Content     map[string]*OpenAPIMedia `json:"content,omitempty"`
+}
+
+// JSON Schema
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	ExclusiveMinimum     bool               `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum     bool               `json:"exclusiveMaximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+}
+
+// OpenAPI 文档生成器
+type openAPIBuilder struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string // 结构体类型对应的组件名称
+}
+
+// BuildOpenAPI 根据已注册的路由及接口文档生成 OpenAPI 文档
+func BuildOpenAPI(e *echo.Echo, info OpenAPIInfo, servers ...OpenAPIServer) *OpenAPI {
+	b := &openAPIBuilder{
+		schemas: make(map[string]*Schema),
+		names:   make(map[reflect.Type]string),
+	}
+	spec := &OpenAPI{
+		Openapi: OpenAPIVersion,
+		Info:    info,
+		Servers: servers,
+		Paths:   make(map[string]map[string]*OpenAPIOperation),
+	}
+
+	apiDocsMu.RLock()
+	defer apiDocsMu.RUnlock()
+
+	// 按路径排序, 保证组件名称稳定
+	routes := e.Routes()
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+
+	var secure bool
+	for _, r := range routes {
+		doc, ok := apiDocs[r.Method+" "+r.Path]
+		if !ok {
+			continue
+		}
+
+		path, params := openAPIPath(r.Path)
+		if spec.Paths[path] == nil {
+			spec.Paths[path] = make(map[string]*OpenAPIOperation)
+		}
+
+		op := b.operation(r.Method, doc, params)
+		if doc.Auth {
+			op.Security = []map[string][]string{{"bearerAuth": {}}}
+			secure = true
+		}
+		spec.Paths[path][strings.ToLower(r.Method)] = op
+	}
+
+	b.schemas["Result"] = b.result(nil)
+	spec.Components.Schemas = b.schemas
+	if secure {
+		spec.Components.SecuritySchemes = map[string]map[string]interface{}{
+			"bearerAuth": {"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+		}
+	}
+
+	return spec
+}
+
+// 将echo路由转换为OpenAPI路径, 返回路径参数
+func openAPIPath(path string) (string, []string) {
+	var params []string
+	parts := strings.Split(path, "/")
+	for i, p := range parts {
+		if strings.HasPrefix(p, ":") {
+			params = append(params, p[1:])
+			parts[i] = "{" + p[1:] + "}"
+		}
+	}
+	return strings.Join(parts, "/"), params
+}
+
+func (b *openAPIBuilder) operation(method string, doc *APIDoc, params []string) *OpenAPIOperation {
+	op := &OpenAPIOperation{
+		OperationId: doc.OperationId,
+		Summary:     doc.Summary,
+		Description: doc.Description,
+		Tags:        doc.Tags,
+		Deprecated:  doc.Deprecated,
+		Responses: map[string]*OpenAPIResponse{
+			"200": {
+				Description: "code 为 0 时成功, 否则 msg 为错误信息",
+				Content: map[string]*OpenAPIMedia{
+					echo.MIMEApplicationJSON: {Schema: b.result(doc.Response)},
+				},
+			},
+		},
+	}
+
+	for _, name := range params {
+		op.Parameters = append(op.Parameters, &OpenAPIParameter{
+			Name:     name,
+			In:       "path",
+			Required: true,
+			Schema:   &Schema{Type: "string"},
+		})
+	}
+
+	if doc.Request == nil {
+		return op
+	}
+
+	switch method {
+	case http.MethodGet, http.MethodDelete, http.MethodHead:
+		op.Parameters = append(op.Parameters, b.queryParameters(reflect.TypeOf(doc.Request))...)
+	default:
+		op.RequestBody = &OpenAPIRequestBody{
+			Required: true,
+			Content: map[string]*OpenAPIMedia{
+				echo.MIMEApplicationJSON: {Schema: b.schema(reflect.TypeOf(doc.Request))},
+			},
+		}
+	}
+
+	return op
+}
+
+// 统一返回结构
+func (b *openAPIBuilder) result(data interface{}) *Schema {
+	ds := &Schema{Nullable: true}
+	if data != nil {
+		ds = b.schema(reflect.TypeOf(data))
+	}
+	return &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"code": {Type: "integer", Description: "状态码, 0 为成功"},
+			"msg":  {Type: "string", Description: "提示信息"},
+			"data": ds,
+			"time": {Type: "integer", Format: "int64", Description: "服务器时间"},
+		},
+		Required: []string{"code", "msg", "time"},
+	}
+}
+
+// 查询参数
+func (b *openAPIBuilder) queryParameters(t reflect.Type) []*OpenAPIParameter {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var params []*OpenAPIParameter
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			params = append(params, b.queryParameters(f.Type)...)
+			continue
+		}
+
+		name := f.Tag.Get("query")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		s := b.schema(f.Type)
+		required := applyValidate(s, f.Type, f.Tag.Get("validate"))
+		params = append(params, &OpenAPIParameter{
+			Name:     name,
+			In:       "query",
+			Required: required,
+			Schema:   s,
+		})
+	}
+
+	return params
+}
+
+// 根据类型生成Schema, 结构体注册到 components 并返回引用
+func (b *openAPIBuilder) schema(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case objectIdType:
+		return &Schema{Type: "string", Pattern: "^[0-9a-f]{24}$"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: b.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: b.schema(t.Elem())}
+	case reflect.Struct:
+		return b.structRef(t)
+	default:
+		return &Schema{}
+	}
+}
+
+func (b *openAPIBuilder) structRef(t reflect.Type) *Schema {
+	if t.Name() == "" {
+		return b.structSchema(t)
+	}
+
+	name, ok := b.names[t]
+	if !ok {
+		name = b.schemaName(t)
+		b.names[t] = name
+		b.schemas[name] = &Schema{} // 占位, 防止循环引用
+		b.schemas[name] = b.structSchema(t)
+	}
+
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// 组件名称, 默认使用类型名称, 与其他包的同名类型冲突时依次使用 包名.类型名, 完整包路径.类型名
+func (b *openAPIBuilder) schemaName(t reflect.Type) string {
+	used := func(name string) bool {
+		_, ok := b.schemas[name]
+		return ok || name == "Result"
+	}
+
+	name := t.Name()
+	if !used(name) {
+		return name
+	}
+	if name = path.Base(t.PkgPath()) + "." + t.Name(); !used(name) {
+		return name
+	}
+	name = strings.Replace(t.PkgPath(), "/", ".", -1) + "." + t.Name()
+	for i := 2; used(name); i++ {
+		name = strings.Replace(t.PkgPath(), "/", ".", -1) + "." + t.Name() + strconv.Itoa(i)
+	}
+	return name
+}
+
+func (b *openAPIBuilder) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	b.structFields(s, t)
+	sort.Strings(s.Required)
+	return s
+}
+
+func (b *openAPIBuilder) structFields(s *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+
+		name, omitempty := jsonFieldName(f)
+		if name == "-" {
+			continue
+		}
+
+		// 匿名结构体字段展开
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			for ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				b.structFields(s, ft)
+				continue
+			}
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		fs := b.schema(f.Type)
+		if desc := f.Tag.Get("doc"); desc != "" {
+			fs.Description = desc
+		}
+		if applyValidate(fs, f.Type, f.Tag.Get("validate")) && !omitempty {
+			s.Required = append(s.Required, name)
+		}
+		s.Properties[name] = fs
+	}
+}
+
+func jsonFieldName(f reflect.StructField) (string, bool) {
+	tag := f.Tag.Get("json")
+	if tag == "" {
+		return "", false
+	}
+	parts := strings.Split(tag, ",")
+	var omitempty bool
+	for _, p := range parts[1:] {
+		if p == "omitempty" {
+			omitempty = true
+		}
+	}
+	return parts[0], omitempty
+}
+
+// 将验证标签转换为Schema约束, 返回是否必须
+func applyValidate(s *Schema, t reflect.Type, tag string) bool {
+	if tag == "" || tag == "-" {
+		return false
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	// 引用类型无法添加约束
+	target := s
+	if s.Ref != "" {
+		target = nil
+	}
+
+	var required bool
+	for _, rule := range strings.Split(tag, ",") {
+		if rule == "dive" {
+			// dive 之后的规则作用于元素
+			if s.Items != nil && t.Kind() != reflect.Map {
+				rest := tag[strings.Index(tag, "dive")+len("dive"):]
+				applyValidate(s.Items, t.Elem(), strings.TrimPrefix(rest, ","))
+			}
+			break
+		}
+
+		name, param := rule, ""
+		if i := strings.Index(rule, "="); i > 0 {
+			name, param = rule[:i], rule[i+1:]
+		}
+
+		if name == "required" {
+			required = true
+			continue
+		}
+		if target == nil {
+			continue
+		}
+
+		switch name {
+		case "min", "max", "len", "gt", "gte", "lt", "lte":
+			applyRange(target, t, name, param)
+		case "oneof":
+			for _, v := range strings.Fields(param) {
+				target.Enum = append(target.Enum, enumValue(target.Type, v))
+			}
+		case "eq":
+			target.Enum = []interface{}{enumValue(target.Type, param)}
+		case "email":
+			target.Format = "email"
+		case "url", "uri":
+			target.Format = "uri"
+		case "uuid", "uuid4":
+			target.Format = "uuid"
+		case "ipv4", "ipv6":
+			target.Format = name
+		case "datetime":
+			target.Format = "date-time"
+		case "mobile":
+			target.Pattern = mobileRegexp.String()
+		case "idcard":
+			target.Pattern = idCardRegexp.String()
+		case "uscc":
+			target.Pattern = usccRegexp.String()
+		case "bankcard":
+			target.Pattern = bankRegexp.String()
+		case "password":
+			minLen := 8
+			target.MinLength = &minLen
+		case "alpha":
+			target.Pattern = "^[a-zA-Z]+$"
+		case "alphanum":
+			target.Pattern = "^[a-zA-Z0-9]+$"
+		case "numeric":
+			target.Pattern = "^[-+]?[0-9]+(?:\\.[0-9]+)?$"
+		case "number":
+			target.Pattern = "^[0-9]+$"
+		}
+	}
+
+	return required
+}
+
+// 数值范围或长度约束
+func applyRange(s *Schema, t reflect.Type, name, param string) {
+	n, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return
+	}
+	i := int(n)
+
+	switch s.Type {
+	case "integer", "number":
+		switch name {
+		case "min", "gte":
+			s.Minimum = &n
+		case "max", "lte":
+			s.Maximum = &n
+		case "gt":
+			s.Minimum, s.ExclusiveMinimum = &n, true
+		case "lt":
+			s.Maximum, s.ExclusiveMaximum = &n, true
+		case "len":
+			s.Minimum, s.Maximum = &n, &n
+		}
+	case "string":
+		switch name {
+		case "min", "gte":
+			s.MinLength = &i
+		case "max", "lte":
+			s.MaxLength = &i
+		case "gt":
+			i++
+			s.MinLength = &i
+		case "lt":
+			i--
+			s.MaxLength = &i
+		case "len":
+			s.MinLength, s.MaxLength = &i, &i
+		}
+	case "array":
+		switch name {
+		case "min", "gte":
+			s.MinItems = &i
+		case "max", "lte":
+			s.MaxItems = &i
+		case "gt":
+			i++
+			s.MinItems = &i
+		case "lt":
+			i--
+			s.MaxItems = &i
+		case "len":
+			s.MinItems, s.MaxItems = &i, &i
+		}
+	}
+}
+
+func enumValue(typ string, v string) interface{} {
+	switch typ {
+	case "integer":
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return n
+		}
+	case "number":
+		if n, err := strconv.ParseFloat(v, 64); err == nil {
+			return n
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	}
+	return v
+}
+
+// Swagger UI 页面
+const swaggerUIPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{title}}</title>
+<link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@3/swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="https://unpkg.com/swagger-ui-dist@3/swagger-ui-bundle.js"></script>
+<script>
+window.onload = function () {
+	window.ui = SwaggerUIBundle({url: "{{url}}", dom_id: "#swagger-ui", deepLinking: true});
+};
+</script>
+</body>
+</html>`
+
+// 启用OpenAPI文档
+func (s *Server) enableOpenAPI() {
+	if s.opts.OpenAPIPath == "" {
+		return
+	}
+
+	var once sync.Once
+	var spec *OpenAPI
+
+	s.echo.GET(s.opts.OpenAPIPath, func(c echo.Context) error {
+		once.Do(func() {
+			spec = BuildOpenAPI(s.echo, s.opts.OpenAPIInfo)
+		})
+		return c.JSON(http.StatusOK, spec)
+	})
+
+	if s.opts.SwaggerPath != "" {
+		page := strings.NewReplacer(
+			"{{title}}", html.EscapeString(s.opts.OpenAPIInfo.Title),
+			"{{url}}", html.EscapeString(s.opts.OpenAPIPath),
+		).Replace(swaggerUIPage)
+		s.echo.GET(s.opts.SwaggerPath, func(c echo.Context) error {
+			return c.HTML(http.StatusOK, page)
+		})
+	}
+
+	log.Infof("HTTP Server Enable OpenAPI Service, Path: %s, UI: %s", s.opts.OpenAPIPath, s.opts.SwaggerPath)
+}
@@ -1,7 +1,12 @@
 package web
 
 import (
+	"github.com/cbwfree/micro-core/captcha"
+	"github.com/cbwfree/micro-core/jwt"
+	"github.com/gorilla/sessions"
 	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"net/http"
 	"time"
 )
 
@@ -20,14 +25,32 @@ type Options struct {
 	Timeout time.Duration
 	Root    string // Disk Data Root Dir
 
-	SessionStore  string // Session Save Folder
-	SessionSecret string // Session Save Secret
+	SessionStore    string            // Session Save Folder
+	SessionSecret   string            // Session Save Secret
+	SessionName     string            // Session Cookie 名称
+	SessionProvider sessions.Store    // Session存储, 设置后忽略 SessionStore
+	SessionCookie   *sessions.Options // Session Cookie 属性 (SameSite, Secure, Domain 等)
+	SessionIdle     time.Duration     // Session空闲超时
+	SessionAbsolute time.Duration     // Session绝对超时
 
 	SocketPath         string // WebSocket Uri Path
+	SocketOnConnect    OnConnectHandler
 	SocketOnReceive    OnReceiveHandler
 	SocketOnDisconnect OnDisconnectHandler
+	SocketRouter       *SocketRouter       // 消息路由
+	SocketCluster      *SocketCluster      // 集群推送
+	SocketPresence     PresenceStore       // 房间在线状态存储
+	SocketConnOpts     []SocketConnOption  // 客户端连接配置 (心跳, 发送队列, 超时等)
+	SocketAuth         *SocketAuthConfig   // 连接认证
+	SocketMaxConns     int                 // 节点最大连接数
+	SocketMaxUserConns int                 // 每个用户最大连接数
+	SocketResume       *SocketResumeConfig // 会话恢复
 
-	StaticRoot    string
+	SSEPath string      // Server-Sent Events Uri Path
+	SSEOpts []SSEOption // SSE 配置
+
+	StaticRoot    string     // 静态文件目录, 多个目录使用:分隔
+	StaticFS      []StaticFS // 静态文件系统 (内置文件等)
 	AllowOrigins  string
 	AllowMethods  []string
 	AllowHeaders  []string
@@ -35,6 +58,22 @@ type Options struct {
 
 	APIPrefix string
 	APIRoutes []Route
+
+	JWKSPath string      // JWKS 发布路径
+	JWKSKeys *jwt.KeySet // JWKS 秘钥集合
+
+	CaptchaPath    string           // 验证码生成路径
+	CaptchaService *captcha.Service // 验证码服务
+
+	OpenAPIPath string      // OpenAPI 文档路径
+	SwaggerPath string      // Swagger UI 路径
+	OpenAPIInfo OpenAPIInfo // OpenAPI 文档信息
+
+	LocaleResolver LocaleResolver // 自定义请求语言解析
+
+	LogSkipper       middleware.Skipper // 跳过访问日志的请求
+	MetricsPath      string             // Prometheus 统计路径
+	MetricsNamespace string             // 统计指标名称前缀
 }
 
 func (o *Options) With(opts ...Option) {
@@ -60,6 +99,35 @@ func WithSession(store string, secret string) Option {
 	}
 }
 
+// Session存储 (Redis, MongoDB, Cookie 等)
+func WithSessionProvider(store sessions.Store) Option {
+	return func(o *Options) {
+		o.SessionProvider = store
+	}
+}
+
+// Session Cookie 名称
+func WithSessionName(name string) Option {
+	return func(o *Options) {
+		o.SessionName = name
+	}
+}
+
+// Session Cookie 属性
+func WithSessionCookie(cookie *sessions.Options) Option {
+	return func(o *Options) {
+		o.SessionCookie = cookie
+	}
+}
+
+// Session超时, idle 为空闲超时, absolute 为绝对超时 (0 为不限制)
+func WithSessionTimeout(idle, absolute time.Duration) Option {
+	return func(o *Options) {
+		o.SessionIdle = idle
+		o.SessionAbsolute = absolute
+	}
+}
+
 func WithSocket(path string, receive OnReceiveHandler, disconnect OnDisconnectHandler) Option {
 	return func(o *Options) {
 		o.SocketPath = path
@@ -68,6 +136,88 @@ func WithSocket(path string, receive OnReceiveHandler, disconnect OnDisconnectHa
 	}
 }
 
+// 使用消息路由处理WebSocket消息
+func WithSocketRouter(path string, router *SocketRouter, disconnect ...OnDisconnectHandler) Option {
+	return func(o *Options) {
+		o.SocketPath = path
+		o.SocketRouter = router
+		o.SocketOnReceive = router.OnReceive
+		if len(disconnect) > 0 {
+			o.SocketOnDisconnect = disconnect[0]
+		}
+	}
+}
+
+// 启用房间在线状态存储, 用于跨节点统计房间人数
+func WithSocketPresence(store PresenceStore) Option {
+	return func(o *Options) {
+		o.SocketPresence = store
+	}
+}
+
+// 设置WebSocket客户端连接配置
+func WithSocketConn(opts ...SocketConnOption) Option {
+	return func(o *Options) {
+		o.SocketConnOpts = append(o.SocketConnOpts, opts...)
+	}
+}
+
+// 设置WebSocket连接事件, 在升级前执行, 可拒绝连接
+func WithSocketOnConnect(handler OnConnectHandler) Option {
+	return func(o *Options) {
+		o.SocketOnConnect = handler
+	}
+}
+
+// 启用WebSocket连接认证
+func WithSocketAuth(cfg SocketAuthConfig) Option {
+	return func(o *Options) {
+		if cfg.Lookup == "" {
+			cfg.Lookup = DefaultAuthLookup
+		}
+		if cfg.Scheme == "" {
+			cfg.Scheme = DefaultAuthScheme
+		}
+		o.SocketAuth = &cfg
+	}
+}
+
+// 设置WebSocket最大连接数, node 为节点最大连接数, user 为每个用户最大连接数, 为0时不限制
+func WithSocketMaxConns(node, user int) Option {
+	return func(o *Options) {
+		o.SocketMaxConns = node
+		o.SocketMaxUserConns = user
+	}
+}
+
+// 启用WebSocket会话恢复, buffer 为每个会话保存的待确认消息数量, grace 为断开后会话保留时间
+func WithSocketResume(store SocketSessionStore, buffer int, grace time.Duration) Option {
+	return func(o *Options) {
+		if buffer <= 0 {
+			buffer = DefaultResumeBuffer
+		}
+		if grace <= 0 {
+			grace = DefaultResumeGrace
+		}
+		o.SocketResume = &SocketResumeConfig{Store: store, Buffer: buffer, Grace: grace}
+	}
+}
+
+// 启用Server-Sent Events
+func WithSSE(path string, opts ...SSEOption) Option {
+	return func(o *Options) {
+		o.SSEPath = path
+		o.SSEOpts = append(o.SSEOpts, opts...)
+	}
+}
+
+// 启用WebSocket集群推送
+func WithSocketCluster(cluster *SocketCluster) Option {
+	return func(o *Options) {
+		o.SocketCluster = cluster
+	}
+}
+
 func WithAllowMethods(allow []string) Option {
 	return func(o *Options) {
 		o.AllowMethods = allow
@@ -97,3 +247,64 @@ func WithAPIRoutes(routes ...Route) Option {
 		o.APIRoutes = append(o.APIRoutes, routes...)
 	}
 }
+
+// 发布JWKS公钥集合
+func WithJWKS(path string, keys *jwt.KeySet) Option {
+	return func(o *Options) {
+		o.JWKSPath = path
+		o.JWKSKeys = keys
+	}
+}
+
+// 启用验证码服务, path 不为空时注册验证码生成路由
+func WithCaptcha(path string, svc *captcha.Service) Option {
+	return func(o *Options) {
+		o.CaptchaPath = path
+		o.CaptchaService = svc
+	}
+}
+
+// 启用OpenAPI文档, ui 不为空时提供 Swagger UI 页面
+func WithOpenAPI(path string, ui string, info OpenAPIInfo) Option {
+	return func(o *Options) {
+		o.OpenAPIPath = path
+		o.SwaggerPath = ui
+		o.OpenAPIInfo = info
+	}
+}
+
+// 自定义请求语言解析 (如从用户设置或查询参数中获取), 未解析到时使用 Accept-Language 协商
+func WithLocaleResolver(resolver LocaleResolver) Option {
+	return func(o *Options) {
+		o.LocaleResolver = resolver
+	}
+}
+
+// 添加静态文件系统, 可使用编译时生成的内置文件系统, cfg 为空时读取文件系统中的 config.json
+func WithStaticFS(fs http.FileSystem, cfg ...*StaticConfig) Option {
+	return func(o *Options) {
+		m := StaticFS{FS: fs}
+		if len(cfg) > 0 {
+			m.Config = cfg[0]
+		}
+		o.StaticFS = append(o.StaticFS, m)
+	}
+}
+
+// 设置跳过访问日志的请求
+func WithLogSkipper(skipper middleware.Skipper) Option {
+	return func(o *Options) {
+		o.LogSkipper = skipper
+	}
+}
+
+// 启用 Prometheus 格式的请求统计, namespace 为指标名称前缀 (默认 http)
+func WithMetrics(path string, namespace ...string) Option {
+	return func(o *Options) {
+		o.MetricsPath = path
+		o.MetricsNamespace = "http"
+		if len(namespace) > 0 {
+			o.MetricsNamespace = namespace[0]
+		}
+	}
+}
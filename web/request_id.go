@@ -0,0 +1,85 @@
+package web
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/micro/go-micro/v2/client/selector"
+	"github.com/micro/go-micro/v2/metadata"
+)
+
+const (
+	MetaRequestId = "X-Request-Id" // go-micro metadata 中的请求ID
+
+	ctxRequestId = "_request_id"
+
+	maxRequestIdLen = 128
+)
+
+// 请求ID中间件
+// 优先使用请求头 X-Request-Id, 否则生成新的ID, 写入响应头及请求 context 的 go-micro metadata
+// 使用 c.Request().Context() (或 Context.Context()) 调用 App.CallCtx 或 Context.Call 时, 请求ID随 metadata 传递到服务
+func RequestID() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			rid := req.Header.Get(echo.HeaderXRequestID)
+			if !validRequestId(rid) {
+				rid = uuid.New().String()
+			}
+
+			c.Set(ctxRequestId, rid)
+			c.Response().Header().Set(echo.HeaderXRequestID, rid)
+			c.SetRequest(req.WithContext(metadata.MergeContext(req.Context(), metadata.Metadata{MetaRequestId: rid}, true)))
+
+			return next(c)
+		}
+	}
+}
+
+// 获取请求ID
+func RequestIdOf(c echo.Context) string {
+	if rid, ok := c.Get(ctxRequestId).(string); ok {
+		return rid
+	}
+	return c.Response().Header().Get(echo.HeaderXRequestID)
+}
+
+// 从 context 中获取请求ID, 服务端可通过此方法获取调用方传递的请求ID
+func RequestIdFromContext(ctx context.Context) string {
+	rid, _ := metadata.Get(ctx, MetaRequestId)
+	return rid
+}
+
+// RequestId 获取请求ID
+func (c *Context) RequestId() string {
+	return RequestIdOf(c.ctx)
+}
+
+// Context 获取请求的 context, 包含请求ID等 go-micro metadata, 用于 App.CallCtx
+func (c *Context) Context() context.Context {
+	return c.ctx.Request().Context()
+}
+
+// RPC调用接口, srv.App 实现了此接口
+type Caller interface {
+	CallCtx(ctx context.Context, name string, method string, in interface{}, out interface{}, filter ...selector.Filter) error
+}
+
+// Call 使用请求的 context 调用RPC, 请求ID随 metadata 传递到服务
+func (c *Context) Call(caller Caller, name string, method string, in interface{}, out interface{}, filter ...selector.Filter) error {
+	return caller.CallCtx(c.Context(), name, method, in, out, filter...)
+}
+
+// 外部传入的请求ID只允许可见ASCII字符
+func validRequestId(rid string) bool {
+	if rid == "" || len(rid) > maxRequestIdLen {
+		return false
+	}
+	for i := 0; i < len(rid); i++ {
+		if rid[i] < 0x21 || rid[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
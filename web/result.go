@@ -50,23 +50,41 @@ func CtxResult(ctx echo.Context, res *Result) error {
 	return ctx.JSON(http.StatusOK, res)
 }
 
+// ParseError 解析错误 (使用默认语言)
 func ParseError(err error) *Result {
+	return ParseErrorLocale(err, DefaultLocale)
+}
+
+// ParseErrorLocale 解析错误, 并翻译为指定语言
+func ParseErrorLocale(err error, locale string) *Result {
 	var code int
 	var msg string
+	var data interface{}
 
 	if err == mongo.ErrNilDocument || err == mongo.ErrNoDocuments {
 		code = http.StatusNotFound
-		msg = "没有找到相关数据"
+		msg = T(locale, MsgNotFoundData)
 	} else if err == redis.Nil {
 		code = http.StatusNotFound
-		msg = "没有找到缓存数据"
+		msg = T(locale, MsgNotFoundCache)
+	} else if err == ErrSessionNotFound {
+		code = http.StatusNotFound
+		msg = T(locale, MsgNotFoundSession)
+	} else if ve, ok := err.(*ValidationErrors); ok { // 数据验证错误
+		fields := ve.Translate(locale)
+		code = http.StatusBadRequest
+		msg = T(locale, MsgValidation)
+		if len(fields) > 0 {
+			msg = fields[0].Message
+		}
+		data = fields
 	} else if he, ok := err.(*echo.HTTPError); ok { // Echo 错误
 		code = he.Code
 		if he.Internal != nil {
 			msg = fmt.Sprintf("%v, %v", err, he.Internal)
 		} else {
 			if m, ok := he.Message.(string); ok {
-				msg = m
+				msg = T(locale, m)
 			} else {
 				msg = he.Error()
 			}
@@ -79,5 +97,5 @@ func ParseError(err error) *Result {
 		msg = err.Error()
 	}
 
-	return NewResult(code, msg, nil)
+	return NewResult(code, msg, data)
 }
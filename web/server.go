@@ -1,16 +1,14 @@
 package web
 
 import (
-	"encoding/json"
-	"github.com/cbwfree/micro-core/conv"
+	"fmt"
 	"github.com/cbwfree/micro-core/fn"
 	"github.com/gorilla/sessions"
-	"github.com/labstack/echo-contrib/session"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	log "github.com/micro/go-micro/v2/logger"
-	"io/ioutil"
 	"net"
+	"net/http"
 	"path/filepath"
 	"strings"
 	"sync"
@@ -25,6 +23,9 @@ type Server struct {
 	opts *Options
 
 	socket *Socket
+	sse    *SSE
+
+	metrics *Metrics
 }
 
 func (s *Server) Echo() *echo.Echo {
@@ -35,6 +36,14 @@ func (s *Server) Socket() *Socket {
 	return s.socket
 }
 
+func (s *Server) SSE() *SSE {
+	return s.sse
+}
+
+func (s *Server) Metrics() *Metrics {
+	return s.metrics
+}
+
 func (s *Server) With(opts ...Option) {
 	s.opts.With(opts...)
 }
@@ -72,69 +81,112 @@ func (s *Server) enableCORS() {
 }
 
 // 启用静态文件
-func (s *Server) enableStatic() {
-	if len(s.opts.StaticRoot) == 0 {
-		return
+func (s *Server) enableStatic() error {
+	var mounts []StaticFS
+	if len(s.opts.StaticRoot) > 0 {
+		for _, root := range strings.Split(s.opts.StaticRoot, ":") {
+			mounts = append(mounts, StaticFS{FS: http.Dir(root)})
+		}
 	}
+	mounts = append(mounts, s.opts.StaticFS...)
 
-	for _, val := range strings.Split(s.opts.StaticRoot, ":") {
-		prefix := "/"
-		root := val
-		confFile := filepath.Join(val, "config.json")
+	for _, m := range mounts {
+		name := "embedded"
+		if dir, ok := m.FS.(http.Dir); ok {
+			name = string(dir)
+		}
 
 		// 读取配置文件
-		if fn.FileExist(confFile) {
-			b, err := ioutil.ReadFile(confFile)
-			if err != nil {
-				log.Fatal("Read web static config.json error: %v", err)
-				return
+		cfg := m.Config
+		if cfg == nil {
+			var err error
+			if cfg, err = loadStaticConfig(m.FS); err != nil {
+				return fmt.Errorf("web static %s config.json error: %w", name, err)
 			}
-
-			var res = make(map[string]interface{})
-			if err := json.Unmarshal(b, &res); err != nil {
-				log.Fatal("Parse web static config.json error: %v", err)
-				return
-			}
-
-			prefix = conv.String(res["baseUrl"])
 		}
 
-		var use func(mdd ...echo.MiddlewareFunc)
-		if prefix != "/" {
-			use = s.echo.Group(prefix).Use
+		h := newStaticHandler(m.FS, cfg)
+		if prefix := cfg.prefix(); prefix != "/" {
+			g := s.echo.Group(prefix)
+			g.Use(h.middleware)
+			g.Any("/", echo.NotFoundHandler) // 路由无法匹配 prefix/* 为空的请求
 		} else {
-			use = s.echo.Use
+			s.echo.Use(h.middleware)
 		}
 
-		use(middleware.StaticWithConfig(middleware.StaticConfig{
-			Root:   root,
-			Index:  "index.html",
-			HTML5:  true,  // SPA 单页面是否转发
-			Browse: false, // 是否启用目录浏览
-		}))
-
-		log.Infof("HTTP Server Enable Static Service, Prefix: %s, Path: %s", prefix, root)
+		log.Infof("HTTP Server Enable Static Service, Prefix: %s, Path: %s", cfg.prefix(), name)
 	}
+
+	return nil
+}
+
+// 启用多语言
+func (s *Server) enableLocale() {
+	s.echo.Use(localeMiddleware(s.opts.LocaleResolver))
 }
 
 // 启用Session
 func (s *Server) enableSession() {
-	if s.opts.SessionStore == "" {
+	store := s.opts.SessionProvider
+	if store == nil {
+		if s.opts.SessionStore == "" {
+			return
+		}
+
+		// 检查目录是否存在
+		dir := filepath.Join(s.opts.Root, s.opts.SessionStore)
+		if err := fn.Mkdir(dir); err != nil {
+			log.Fatalf("Enable Web Session Error: %s", err)
+			return
+		}
+
+		store = sessions.NewFilesystemStore(dir, []byte(s.opts.SessionSecret))
+		log.Infof("HTTP Server Session Save Path: %s", dir)
+	}
+
+	name := s.opts.SessionName
+	if name == "" {
+		name = DefaultSessionName
+	}
+
+	s.echo.Use(sessionMiddleware(name, &sessionStore{
+		Store:    store,
+		cookie:   s.opts.SessionCookie,
+		idle:     s.opts.SessionIdle,
+		absolute: s.opts.SessionAbsolute,
+	}))
+
+	log.Infof("HTTP Server Enable Session Service, Name: %s", name)
+}
+
+// 启用JWKS
+func (s *Server) enableJWKS() {
+	if s.opts.JWKSPath == "" || s.opts.JWKSKeys == nil {
 		return
 	}
 
-	// 检查目录是否存在
-	store := filepath.Join(s.opts.Root, s.opts.SessionStore)
-	if err := fn.Mkdir(store); err != nil {
-		log.Fatalf("Enable Web Session Error: %s", err)
+	s.echo.GET(s.opts.JWKSPath, func(c echo.Context) error {
+		c.Response().Header().Set("Cache-Control", "public, max-age=300")
+		return c.JSON(http.StatusOK, s.opts.JWKSKeys.JWKS())
+	})
+
+	log.Infof("HTTP Server Enable JWKS Service, Path: %s", s.opts.JWKSPath)
+}
+
+// 启用验证码
+func (s *Server) enableCaptcha() {
+	if s.opts.CaptchaService == nil {
 		return
 	}
 
-	s.echo.Use(session.Middleware(
-		sessions.NewFilesystemStore(store, []byte(s.opts.SessionSecret)),
-	))
+	if v, ok := s.echo.Validator.(*WebValidator); ok {
+		v.RegisterCaptcha(s.opts.CaptchaService)
+	}
 
-	log.Infof("HTTP Server Enable Session Service, Save Path: %s", store)
+	if s.opts.CaptchaPath != "" {
+		s.echo.GET(s.opts.CaptchaPath, CaptchaHandler(s.opts.CaptchaService))
+		log.Infof("HTTP Server Enable Captcha Service, Path: %s", s.opts.CaptchaPath)
+	}
 }
 
 // 启用WebSocket
@@ -145,6 +197,48 @@ func (s *Server) enableSocket() {
 
 	s.socket = NewSocket(s)
 	s.echo.GET(s.opts.SocketPath, s.socket.Handler)
+
+	if s.opts.SocketCluster != nil {
+		if err := s.opts.SocketCluster.Start(s.socket); err != nil {
+			log.Errorf("WebSocket Cluster Start Failure: %s", err.Error())
+		}
+	}
+}
+
+func (s *Server) enableSSE() {
+	if s.opts.SSEPath == "" {
+		return
+	}
+
+	s.sse = NewSSE(s)
+	s.echo.GET(s.opts.SSEPath, s.sse.Handler)
+}
+
+func (s *Server) enableMetrics() {
+	if s.opts.MetricsPath == "" {
+		return
+	}
+
+	s.metrics = NewMetrics(s.opts.MetricsNamespace)
+	s.echo.GET(s.opts.MetricsPath, s.metrics.Handler)
+}
+
+// 启用统计时记录请求
+func (s *Server) collect(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if s.metrics == nil {
+			return next(c)
+		}
+		return s.metrics.handle(c, next)
+	}
+}
+
+// 跳过统计接口及自定义的请求
+func (s *Server) skipLog(c echo.Context) bool {
+	if s.opts.MetricsPath != "" && c.Path() == s.opts.MetricsPath {
+		return true
+	}
+	return s.opts.LogSkipper != nil && s.opts.LogSkipper(c)
 }
 
 func (s *Server) Start() error {
@@ -156,10 +250,20 @@ func (s *Server) Start() error {
 	}
 
 	s.enableCORS()      // 启用跨域
+	s.enableLocale()    // 启用多语言
 	s.enableSession()   // 启用Session
 	s.enableSocket()    // 启用WebSocket
+	s.enableSSE()       // 启用Server-Sent Events
+	s.enableJWKS()      // 启用JWKS
+	s.enableCaptcha()   // 启用验证码
+	s.enableOpenAPI()   // 启用OpenAPI文档
+	s.enableMetrics()   // 启用请求统计
 	s.enableAPIRoutes() // 注册API路由
-	s.enableStatic()    // 启用静态文件
+
+	// 启用静态文件
+	if err := s.enableStatic(); err != nil {
+		return err
+	}
 
 	l, err := net.Listen("tcp", s.opts.Addr)
 	if err != nil {
@@ -194,6 +298,16 @@ func (s *Server) Close() error {
 		return nil
 	}
 
+	if s.opts.SocketCluster != nil {
+		if err := s.opts.SocketCluster.Stop(); err != nil {
+			log.Warnf("WebSocket Cluster Stop Failure: %s", err.Error())
+		}
+	}
+
+	if s.sse != nil {
+		s.sse.Close()
+	}
+
 	ch := make(chan error, 1)
 	s.exit <- ch
 	s.running = false
@@ -214,8 +328,10 @@ func NewServer(opts ...Option) *Server {
 	s.echo.HTTPErrorHandler = errorHandler // 统一错误处理
 	s.echo.Validator = NewWebValidator()   // 数据验证器
 
-	// 记录请求日志
-	s.echo.Use(middleware.Logger())
+	// 请求ID, 请求统计及访问日志 (访问日志在内层处理错误, 统计记录最终的状态码)
+	s.echo.Use(RequestID())
+	s.echo.Use(s.collect)
+	s.echo.Use(AccessLog(s.skipLog))
 	// 从 panic 链中的任意位置恢复程序， 打印堆栈的错误信息，并将错误集中交给 HTTPErrorHandler 处理。
 	s.echo.Use(middleware.Recover())
 	s.echo.Use(middleware.Secure())
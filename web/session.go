@@ -0,0 +1,307 @@
+package web
+
+import (
+	"context"
+	"encoding/base32"
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+	"github.com/labstack/echo-contrib/session"
+	"github.com/labstack/echo/v4"
+	log "github.com/micro/go-micro/v2/logger"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultSessionName = "SESSION"
+	DefaultSessionTTL  = 24 * time.Hour // 服务端Session默认有效期
+
+	ctxSessionName  = "_session_name"
+	ctxSessionState = "_session_state"
+	sessCreatedKey  = "_sess_created"
+	sessAccessedKey = "_sess_accessed"
+)
+
+var (
+	ErrSessionDisabled = errors.New("session is not enabled")
+	ErrSessionNotFound = errors.New("session value not found")
+)
+
+// Session结构体编解码
+type SessionEncoder interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+type jsonSessionEncoder struct{}
+
+func (jsonSessionEncoder) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonSessionEncoder) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// Session结构体编解码器, 默认使用JSON
+var SessionCodec SessionEncoder = jsonSessionEncoder{}
+
+// 请求内的Session状态
+type sessionState struct {
+	s     *sessions.Session
+	err   error
+	dirty bool
+}
+
+// 保存已修改的Session
+func (st *sessionState) save(c echo.Context) error {
+	if st.s == nil || !st.dirty {
+		return nil
+	}
+	st.dirty = false
+	return st.s.Save(c.Request(), c.Response())
+}
+
+// Session服务端存储后端
+type SessionBackend interface {
+	// Load 读取Session数据, 不存在时返回 nil, nil
+	Load(ctx context.Context, id string) ([]byte, error)
+	Save(ctx context.Context, id string, data []byte, ttl time.Duration) error
+	Delete(ctx context.Context, id string) error
+}
+
+// Session ID 轮换 (登录后调用, 防止会话固定攻击)
+type SessionRegenerator interface {
+	Regenerate(r *http.Request, s *sessions.Session) error
+}
+
+// 服务端Session存储, Cookie中仅保存签名后的Session ID
+type ServerSessionStore struct {
+	Codecs  []securecookie.Codec
+	Options *sessions.Options
+	backend SessionBackend
+}
+
+func (ss *ServerSessionStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(ss, name)
+}
+
+// New 创建Session, Cookie无效或数据不存在时返回新的Session
+func (ss *ServerSessionStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	s := sessions.NewSession(ss, name)
+	opts := *ss.Options
+	s.Options = &opts
+	s.IsNew = true
+
+	c, err := r.Cookie(name)
+	if err != nil {
+		return s, nil
+	}
+	if err := securecookie.DecodeMulti(name, c.Value, &s.ID, ss.Codecs...); err != nil {
+		s.ID = ""
+		return s, nil
+	}
+
+	b, err := ss.backend.Load(r.Context(), s.ID)
+	if err != nil {
+		return s, err
+	}
+	if b == nil || securecookie.DecodeMulti(name, string(b), &s.Values, ss.Codecs...) != nil {
+		s.ID = ""
+		return s, nil
+	}
+
+	s.IsNew = false
+	return s, nil
+}
+
+// Save 保存Session, MaxAge < 0 时删除Session
+func (ss *ServerSessionStore) Save(r *http.Request, w http.ResponseWriter, s *sessions.Session) error {
+	if s.Options.MaxAge < 0 {
+		if s.ID != "" {
+			if err := ss.backend.Delete(r.Context(), s.ID); err != nil {
+				return err
+			}
+		}
+		http.SetCookie(w, sessions.NewCookie(s.Name(), "", s.Options))
+		return nil
+	}
+
+	if s.ID == "" {
+		s.ID = newSessionId()
+	}
+
+	data, err := securecookie.EncodeMulti(s.Name(), s.Values, ss.Codecs...)
+	if err != nil {
+		return err
+	}
+
+	ttl := time.Duration(s.Options.MaxAge) * time.Second
+	if ttl <= 0 {
+		ttl = DefaultSessionTTL
+	}
+	if err := ss.backend.Save(r.Context(), s.ID, []byte(data), ttl); err != nil {
+		return err
+	}
+
+	encoded, err := securecookie.EncodeMulti(s.Name(), s.ID, ss.Codecs...)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, sessions.NewCookie(s.Name(), encoded, s.Options))
+
+	return nil
+}
+
+// Regenerate 删除旧的Session数据, 保存时生成新的Session ID
+func (ss *ServerSessionStore) Regenerate(r *http.Request, s *sessions.Session) error {
+	if s.ID != "" {
+		if err := ss.backend.Delete(r.Context(), s.ID); err != nil {
+			return err
+		}
+	}
+	s.ID = ""
+	return nil
+}
+
+// NewServerSessionStore 实例化服务端Session存储
+// keyPairs 为签名及加密秘钥对, 加密秘钥长度必须为 16, 24 或 32 字节
+func NewServerSessionStore(backend SessionBackend, keyPairs ...[]byte) *ServerSessionStore {
+	ss := &ServerSessionStore{
+		Codecs: securecookie.CodecsFromPairs(keyPairs...),
+		Options: &sessions.Options{
+			Path:     "/",
+			MaxAge:   int(DefaultSessionTTL / time.Second),
+			HttpOnly: true,
+		},
+		backend: backend,
+	}
+
+	// Session数据保存在服务端, 不限制编码长度
+	for _, c := range ss.Codecs {
+		if sc, ok := c.(*securecookie.SecureCookie); ok {
+			sc.MaxLength(0)
+		}
+	}
+
+	return ss
+}
+
+// NewCookieSessionStore 实例化Cookie Session存储, 同时提供签名及加密秘钥时Cookie内容将被加密
+func NewCookieSessionStore(hashKey, blockKey []byte) *sessions.CookieStore {
+	cs := sessions.NewCookieStore(hashKey, blockKey)
+	cs.Options.HttpOnly = true
+	return cs
+}
+
+// Session存储包装, 处理Cookie属性, 空闲超时及绝对超时
+type sessionStore struct {
+	sessions.Store
+	cookie   *sessions.Options
+	idle     time.Duration
+	absolute time.Duration
+}
+
+func (ss *sessionStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(ss, name)
+}
+
+func (ss *sessionStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	inner, err := ss.Store.New(r, name)
+
+	s := sessions.NewSession(ss, name)
+	if inner != nil {
+		s.ID = inner.ID
+		s.Values = inner.Values
+		s.Options = inner.Options
+		s.IsNew = inner.IsNew
+	}
+	if ss.cookie != nil {
+		opts := *ss.cookie
+		s.Options = &opts
+	}
+	if s.Options == nil {
+		s.Options = &sessions.Options{Path: "/"}
+	}
+
+	now := time.Now().Unix()
+	created, _ := s.Values[sessCreatedKey].(int64)
+	accessed, _ := s.Values[sessAccessedKey].(int64)
+
+	expired := !s.IsNew && ((ss.idle > 0 && accessed > 0 && now-accessed > int64(ss.idle/time.Second)) ||
+		(ss.absolute > 0 && created > 0 && now-created > int64(ss.absolute/time.Second)))
+	if expired {
+		if err := ss.Regenerate(r, s); err != nil {
+			return s, err
+		}
+		s.Values = make(map[interface{}]interface{})
+		s.IsNew = true
+	}
+	if _, ok := s.Values[sessCreatedKey]; !ok {
+		s.Values[sessCreatedKey] = now
+	}
+
+	return s, err
+}
+
+func (ss *sessionStore) Save(r *http.Request, w http.ResponseWriter, s *sessions.Session) error {
+	s.Values[sessAccessedKey] = time.Now().Unix()
+	return ss.Store.Save(r, w, s)
+}
+
+// 空闲超时需要刷新访问时间
+func (ss *sessionStore) needTouch(s *sessions.Session) bool {
+	if ss.idle <= 0 || s.IsNew {
+		return false
+	}
+	accessed, _ := s.Values[sessAccessedKey].(int64)
+	return time.Now().Unix()-accessed > int64(ss.idle/time.Second/10)
+}
+
+func (ss *sessionStore) Regenerate(r *http.Request, s *sessions.Session) error {
+	if rg, ok := ss.Store.(SessionRegenerator); ok {
+		if err := rg.Regenerate(r, s); err != nil {
+			return err
+		}
+	} else {
+		s.ID = ""
+	}
+	s.Values[sessCreatedKey] = time.Now().Unix()
+	return nil
+}
+
+// Session中间件, Session在响应写入前统一保存
+func sessionMiddleware(name string, store sessions.Store) echo.MiddlewareFunc {
+	mw := session.Middleware(store)
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		h := mw(next)
+		return func(c echo.Context) error {
+			c.Set(ctxSessionName, name)
+			c.Response().Before(func() {
+				if st, ok := c.Get(ctxSessionState).(*sessionState); ok {
+					if err := st.save(c); err != nil {
+						log.Errorf("Save Web Session Error: %s", err)
+					}
+				}
+			})
+
+			if err := h(c); err != nil {
+				return err
+			}
+
+			// 未写入响应时保存, 保存失败时返回错误
+			if st, ok := c.Get(ctxSessionState).(*sessionState); ok && !c.Response().Committed {
+				return st.save(c)
+			}
+
+			return nil
+		}
+	}
+}
+
+func newSessionId() string {
+	return strings.TrimRight(base32.StdEncoding.EncodeToString(securecookie.GenerateRandomKey(32)), "=")
+}
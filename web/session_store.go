@@ -0,0 +1,16 @@
+package web
+
+import (
+	mgo "github.com/cbwfree/micro-core/store/mongo"
+	rds "github.com/cbwfree/micro-core/store/redis"
+)
+
+// NewRedisSessionStore 实例化Redis Session存储
+func NewRedisSessionStore(rs *rds.Store, keyPairs ...[]byte) *ServerSessionStore {
+	return NewServerSessionStore(rs.SessionStore(), keyPairs...)
+}
+
+// NewMongoSessionStore 实例化MongoDB Session存储 (session 集合, TTL索引自动清理)
+func NewMongoSessionStore(ms *mgo.Store, keyPairs ...[]byte) *ServerSessionStore {
+	return NewServerSessionStore(mgo.NewMongoSessionStore(ms.D()), keyPairs...)
+}
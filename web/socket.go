@@ -1,10 +1,14 @@
 package web
 
 import (
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 	"github.com/labstack/echo/v4"
 	log "github.com/micro/go-micro/v2/logger"
+	"github.com/micro/go-micro/v2/metadata"
 	"net/http"
+	"net/textproto"
+	"strings"
 	"sync"
 )
 
@@ -14,14 +18,30 @@ type OnReceiveHandler func(s *Socket, sess *SocketConn, data []byte) error
 // WebSocket Disconnect Event
 type OnDisconnectHandler func(s *Socket, sess *SocketConn) error
 
+// WebSocket 连接事件监听
+type SocketListener interface {
+	OnConnect(s *Socket, sc *SocketConn)
+	OnDisconnect(s *Socket, sc *SocketConn)
+}
+
 type Socket struct {
 	wg           sync.WaitGroup
 	web          *Server             // Web Server
 	path         string              // WebSocket Path
 	upgrader     *websocket.Upgrader //
 	conns        *SocketConns
+	onConnect    OnConnectHandler
 	onReceive    OnReceiveHandler
 	onDisconnect OnDisconnectHandler
+	listeners    []SocketListener
+	connOpts     SocketConnOptions        // 客户端连接配置
+	mu           sync.Mutex               // 连接数统计锁
+	total        int                      // 连接总数
+	users        map[string]int           // 用户连接数
+	suspended    map[string]*socketResume // 已断开但未过期的会话
+	router       *SocketRouter            // 消息路由
+	presence     PresenceStore            // 房间在线状态存储
+	node         string                   // 节点ID
 }
 
 func (s *Socket) Web() *Server {
@@ -36,11 +56,40 @@ func (s *Socket) Conns() *SocketConns {
 	return s.conns
 }
 
+// 获取节点ID
+func (s *Socket) Node() string {
+	return s.node
+}
+
+// 添加连接事件监听, 需在服务启动前添加
+func (s *Socket) AddListener(l SocketListener) {
+	s.listeners = append(s.listeners, l)
+}
+
 // WebSocket Handler
 func (s *Socket) Handler(c echo.Context) error {
+	// 连接认证及连接事件
+	meta := metadata.Metadata{}
+	claims, waitAuth, err := s.authenticate(c, meta)
+	if err != nil {
+		return err
+	}
+	if s.onConnect != nil {
+		if err := s.onConnect(c, meta); err != nil {
+			return err
+		}
+	}
+
+	// 检查连接数限制
+	user := meta[MetaUserId]
+	if err := s.acquire(user); err != nil {
+		return echo.NewHTTPError(http.StatusTooManyRequests, err.Error())
+	}
+
 	// 将HTTP请求升级为WebSocket
 	conn, err := s.upgrader.Upgrade(c.Response(), c.Request(), nil)
 	if err != nil {
+		s.release(user)
 		return err
 	}
 
@@ -48,15 +97,55 @@ func (s *Socket) Handler(c echo.Context) error {
 	defer s.wg.Done()
 
 	// 创建客户端连接对象
-	sc := NewSocketConn(conn)
+	sc := newSocketConn(conn, s.negotiated(c.Request()), s.connOpts)
+	for k, v := range meta {
+		sc.SetMeta(k, v)
+	}
+	if codec := c.QueryParam("codec"); codec != "" {
+		sc.SetMeta(MetaSocketCodec, codec)
+	}
+
+	// 使用第一条消息认证
+	if waitAuth {
+		if claims, err = s.authenticateMessage(c, sc); err != nil && !s.web.opts.SocketAuth.Optional {
+			log.Debugf("[%s] Socket Authenticate Failure: %s", sc.Id(), err.Error())
+			s.release(user)
+			closeWith(conn, CloseUnauthorized, "unauthorized")
+			sc.Destroy()
+			return nil
+		}
+		if u := sc.GetMeta(MetaUserId); u != user {
+			s.release(user)
+			if err := s.acquire(u); err != nil {
+				closeWith(conn, CloseTooManyRequests, err.Error())
+				sc.Destroy()
+				return nil
+			}
+			user = u
+		}
+	}
+	defer s.release(user)
+
+	if claims != nil {
+		sc.SetValue(ctxSocketClaims, claims)
+	}
+
+	// 创建或恢复会话
+	if err := s.resume(c, sc); err != nil {
+		log.Warnf("[%s] Socket Session Resume Failure: %s", sc.Id(), err.Error())
+	}
+
 	s.conns.Put(sc)
+	for _, l := range s.listeners {
+		l.OnConnect(s, sc)
+	}
 
 	log.Debugf("[%s][%s] successfully connected...", sc.Id(), sc.RemoteAddr().String())
 
 	// 接收消息处理
 	for {
 		// 接收消息
-		_, data, err := conn.ReadMessage()
+		_, data, err := sc.ReadMessage()
 		if err != nil {
 			log.Errorf("[%s] Read Message Failure: %s", sc.Id(), err.Error())
 			break
@@ -70,8 +159,22 @@ func (s *Socket) Handler(c echo.Context) error {
 
 	// 读消息失败后清理客户端
 	sc.Destroy()
-	s.conns.Del(sc.Id())      // 清理session
-	_ = s.onDisconnect(s, sc) // 连接断开处理
+	if s.conns.Get(sc.Id()) != sc { // 已被恢复的会话替换
+		s.conns.Remove(sc)
+		log.Debugf("[%s][%s] replaced by resumed session ...", sc.Id(), sc.RemoteAddr().String())
+		return nil
+	}
+
+	rooms := sc.Groups()
+	s.LeaveAll(sc)     // 离开所有房间
+	s.conns.Remove(sc) // 清理session
+	for _, l := range s.listeners {
+		l.OnDisconnect(s, sc)
+	}
+	s.suspend(sc, rooms) // 保留会话
+	if s.onDisconnect != nil {
+		_ = s.onDisconnect(s, sc) // 连接断开处理
+	}
 
 	log.Debugf("[%s][%s] disconnected ...", sc.Id(), sc.RemoteAddr().String())
 
@@ -82,17 +185,47 @@ func (s *Socket) Close() {
 	s.conns.Clean()
 }
 
+// 协商的扩展, 目前仅支持 permessage-deflate
+func (s *Socket) negotiated(r *http.Request) []string {
+	if !s.upgrader.EnableCompression {
+		return nil
+	}
+	for _, h := range r.Header[textproto.CanonicalMIMEHeaderKey("Sec-WebSocket-Extensions")] {
+		for _, ext := range strings.Split(h, ",") {
+			if i := strings.IndexByte(ext, ';'); i >= 0 {
+				ext = ext[:i]
+			}
+			if strings.TrimSpace(ext) == extPermessageDeflate {
+				return []string{extPermessageDeflate}
+			}
+		}
+	}
+	return nil
+}
+
 func NewSocket(web *Server) *Socket {
+	connOpts := newSocketConnOptions(web.opts.SocketConnOpts...)
+	if cfg := web.opts.SocketResume; cfg != nil && cfg.Buffer >= connOpts.SendQueue {
+		cfg.Buffer = connOpts.SendQueue - 1 // 重放的消息不能超出发送队列
+	}
 	ws := &Socket{
 		web:   web,
 		path:  web.opts.SocketPath,
 		conns: newSocketConns(),
 		upgrader: &websocket.Upgrader{
-			HandshakeTimeout: web.opts.Timeout,
-			CheckOrigin:      func(_ *http.Request) bool { return true },
+			HandshakeTimeout:  web.opts.Timeout,
+			CheckOrigin:       checkOrigin(web.opts.AllowOrigins),
+			EnableCompression: connOpts.Compression,
 		},
+		connOpts:     connOpts,
+		onConnect:    web.opts.SocketOnConnect,
 		onReceive:    web.opts.SocketOnReceive,
 		onDisconnect: web.opts.SocketOnDisconnect,
+		router:       web.opts.SocketRouter,
+		presence:     web.opts.SocketPresence,
+		node:         uuid.New().String(),
+		users:        make(map[string]int),
+		suspended:    make(map[string]*socketResume),
 	}
 	return ws
 }
@@ -0,0 +1,256 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/cbwfree/micro-core/jwt"
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+	log "github.com/micro/go-micro/v2/logger"
+	"github.com/micro/go-micro/v2/metadata"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	MetaUserId = "Ws-User-Id" // 认证用户ID (jwt sub)
+
+	ctxSocketClaims = "_socket_claims" // 连接的JWT声明
+
+	SocketLookupMessage = "message" // 从连接后的第一条消息中获取Token
+
+	CloseUnauthorized    = 4401 // 认证失败关闭码
+	CloseTooManyRequests = 4429 // 超出连接数限制关闭码
+)
+
+var (
+	ErrSocketUnauthorized = errors.New("socket unauthorized")
+	ErrSocketMaxConns     = errors.New("socket connections exceed the limit")
+)
+
+// WebSocket 连接事件, 在升级前执行, 返回错误时拒绝连接 (echo.HTTPError 可指定HTTP状态码)
+// meta 为即将写入连接的meta信息, 可在此处补充
+type OnConnectHandler func(c echo.Context, meta metadata.Metadata) error
+
+// WebSocket 认证配置
+type SocketAuthConfig struct {
+	Token        string                                     // Token名称, 通过 jwt.Get 获取
+	Lookup       string                                     // Token来源, 多个来源使用,分隔. 格式: header:Authorization,query:token,message
+	Scheme       string                                     // Header中Token的前缀
+	NewData      func() interface{}                         // 创建 claims.Data 的类型化对象 (指针)
+	Optional     bool                                       // 是否允许未认证连接
+	Meta         func(claims *jwt.Claims) map[string]string // 从声明中生成连接meta信息, 默认写入 sub 到 MetaUserId
+	FirstMessage time.Duration                              // 等待第一条消息认证的超时时间
+}
+
+// 第一条认证消息, 也可直接发送Token字符串
+type socketAuthMessage struct {
+	Token string `json:"token"`
+}
+
+// 获取连接的JWT声明
+func (s *SocketConn) Claims() *jwt.Claims {
+	claims, _ := s.Value(ctxSocketClaims).(*jwt.Claims)
+	return claims
+}
+
+// 是否已认证
+func (s *SocketConn) IsAuth() bool {
+	return s.Claims() != nil
+}
+
+// 升级前认证, 返回是否需要等待第一条消息认证
+func (s *Socket) authenticate(c echo.Context, meta metadata.Metadata) (*jwt.Claims, bool, error) {
+	cfg := s.web.opts.SocketAuth
+	if cfg == nil {
+		return nil, false, nil
+	}
+
+	var str string
+	for _, ext := range authExtractors(cfg.Lookup, cfg.Scheme) {
+		if str = ext(c); str != "" {
+			break
+		}
+	}
+
+	if str == "" {
+		if cfg.lookupMessage() {
+			return nil, true, nil
+		}
+		if cfg.Optional {
+			return nil, false, nil
+		}
+		return nil, false, echo.NewHTTPError(http.StatusUnauthorized)
+	}
+
+	claims, err := s.parseToken(c, str, meta)
+	if err != nil {
+		if cfg.Optional {
+			return nil, false, nil
+		}
+		log.Debugf("[%s] Socket Authenticate Failure: %s", RequestIdOf(c), err.Error())
+		return nil, false, echo.NewHTTPError(http.StatusUnauthorized)
+	}
+
+	return claims, false, nil
+}
+
+// 使用连接后的第一条消息认证
+func (s *Socket) authenticateMessage(c echo.Context, sc *SocketConn) (*jwt.Claims, error) {
+	cfg := s.web.opts.SocketAuth
+
+	timeout := cfg.FirstMessage
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	_ = sc.conn.SetReadDeadline(time.Now().Add(timeout))
+
+	_, data, err := sc.ReadMessage()
+	if err != nil {
+		return nil, err
+	}
+	if sc.opts.PongWait <= 0 {
+		_ = sc.conn.SetReadDeadline(time.Time{})
+	}
+
+	str := strings.TrimSpace(string(data))
+	var msg socketAuthMessage
+	if err := json.Unmarshal(data, &msg); err == nil && msg.Token != "" {
+		str = msg.Token
+	}
+	if str == "" {
+		return nil, ErrSocketUnauthorized
+	}
+
+	meta := metadata.Metadata{}
+	claims, err := s.parseToken(c, str, meta)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range meta {
+		sc.SetMeta(k, v)
+	}
+
+	return claims, nil
+}
+
+// 解析Token, 并将声明写入meta信息
+func (s *Socket) parseToken(c echo.Context, str string, meta metadata.Metadata) (*jwt.Claims, error) {
+	cfg := s.web.opts.SocketAuth
+
+	token := jwt.Get(cfg.Token)
+	if token == nil {
+		return nil, errors.New("invalid jwt token: " + cfg.Token)
+	}
+
+	var data interface{}
+	if cfg.NewData != nil {
+		data = cfg.NewData()
+	}
+
+	claims, _, err := token.ParseContext(c.Request().Context(), str, data)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Meta != nil {
+		for k, v := range cfg.Meta(claims) {
+			meta[k] = v
+		}
+	} else if claims.Subject != "" {
+		meta[MetaUserId] = claims.Subject
+	}
+
+	return claims, nil
+}
+
+func (cfg *SocketAuthConfig) lookupMessage() bool {
+	for _, lookup := range strings.Split(cfg.Lookup, ",") {
+		if strings.TrimSpace(lookup) == SocketLookupMessage {
+			return true
+		}
+	}
+	return false
+}
+
+// 占用连接数, 超出节点或用户的连接数限制时返回错误
+func (s *Socket) acquire(user string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if max := s.web.opts.SocketMaxConns; max > 0 && s.total >= max {
+		return ErrSocketMaxConns
+	}
+	if max := s.web.opts.SocketMaxUserConns; max > 0 && user != "" && s.users[user] >= max {
+		return ErrSocketMaxConns
+	}
+
+	s.total++
+	if user != "" {
+		s.users[user]++
+	}
+	return nil
+}
+
+// 释放连接数
+func (s *Socket) release(user string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.total--
+	if user != "" {
+		if s.users[user]--; s.users[user] <= 0 {
+			delete(s.users, user)
+		}
+	}
+}
+
+// 根据 AllowOrigins 创建来源检查, 多个来源使用,分隔, 支持 * 及 *.example.com
+// 未设置 AllowOrigins 时不检查来源, 未携带 Origin 的请求 (非浏览器客户端) 总是允许
+func checkOrigin(allowOrigins string) func(r *http.Request) bool {
+	var origins []string
+	for _, o := range strings.Split(allowOrigins, ",") {
+		if o = strings.TrimSpace(o); o != "" {
+			origins = append(origins, strings.ToLower(o))
+		}
+	}
+
+	return func(r *http.Request) bool {
+		origin := r.Header.Get(echo.HeaderOrigin)
+		if len(origins) == 0 || origin == "" {
+			return true
+		}
+
+		u, err := url.Parse(origin)
+		if err != nil {
+			return false
+		}
+		origin = strings.ToLower(origin)
+		host := strings.ToLower(u.Host)
+
+		for _, allow := range origins {
+			switch {
+			case allow == "*", allow == origin, allow == host:
+				return true
+			case strings.HasPrefix(allow, "*."):
+				if strings.HasSuffix(host, allow[1:]) {
+					return true
+				}
+			case strings.Contains(allow, "://*."):
+				i := strings.Index(allow, "://*.")
+				if strings.HasPrefix(origin, allow[:i+3]) && strings.HasSuffix(host, allow[i+4:]) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
+
+// 以指定关闭码关闭连接
+func closeWith(conn *websocket.Conn, code int, text string) {
+	_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, text), time.Now().Add(time.Second))
+	_ = conn.Close()
+}
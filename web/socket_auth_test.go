@@ -0,0 +1,43 @@
+package web
+
+import (
+	"encoding/json"
+	"github.com/cbwfree/micro-core/jwt"
+	jwtgo "github.com/dgrijalva/jwt-go"
+	"github.com/gorilla/websocket"
+	"net/http"
+	"testing"
+)
+
+func TestSocketAuthenticate(t *testing.T) {
+	jwt.New("web_socket_auth_test", jwt.SigningMethod(jwtgo.SigningMethodHS256), jwt.SecretKey("secret"))
+	valid, _ := jwt.Get("web_socket_auth_test").EncryptFor("u1", nil)
+	forged, _ := jwt.NewToken(jwt.SigningMethod(jwtgo.SigningMethodHS256), jwt.SecretKey("other")).EncryptFor("u1", nil)
+
+	s, url := startSocketServer(t,
+		WithSocketRouter("/ws", NewSocketRouter()),
+		WithSocketAuth(SocketAuthConfig{Token: "web_socket_auth_test", Lookup: "query:token"}),
+	)
+	defer s.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial(url+"?token="+valid, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = conn.Close()
+
+	for _, token := range []string{"", "abc", forged} {
+		_, res, err := websocket.DefaultDialer.Dial(url+"?token="+token, nil)
+		if err == nil || res == nil {
+			t.Fatalf("%q: want handshake failure", token)
+		}
+
+		// 只返回 401, 不包含校验失败的原因
+		var body Result
+		_ = json.NewDecoder(res.Body).Decode(&body)
+		_ = res.Body.Close()
+		if body.Code != http.StatusUnauthorized || body.Msg != http.StatusText(http.StatusUnauthorized) {
+			t.Fatalf("%q: unexpected response %+v", token, body)
+		}
+	}
+}
@@ -0,0 +1,520 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/cbwfree/micro-core/conv"
+	"github.com/google/uuid"
+	"github.com/micro/go-micro/v2/broker"
+	log "github.com/micro/go-micro/v2/logger"
+	"sync"
+	"time"
+)
+
+const (
+	clusterToConn    = "conn"
+	clusterToMeta    = "meta"
+	clusterToGroup   = "group"
+	clusterBroadcast = "broadcast"
+)
+
+// Socket集群消息总线
+type SocketBus interface {
+	Publish(topic string, data []byte) error
+	Subscribe(topic string, handler func(data []byte)) (unsubscribe func() error, err error)
+}
+
+// Socket集群连接目录, 记录连接及索引 (meta, 分组) 所在的节点
+type SocketDirectory interface {
+	AddConn(ctx context.Context, node, connId string) error
+	// DelConn 移除节点登记的连接, 连接已登记到其他节点 (如会话已在其他节点恢复) 时只移除本节点的登记项
+	DelConn(ctx context.Context, node, connId string) error
+	AddIndex(ctx context.Context, node, index, connId string) error
+	// DelIndex 移除节点登记的索引项, 索引项已登记到其他节点时只移除本节点的登记项
+	DelIndex(ctx context.Context, node, index, connId string) error
+	ConnNode(ctx context.Context, connId string) (string, error)
+	IndexNodes(ctx context.Context, index string) ([]string, error)
+	Heartbeat(ctx context.Context, node string) error
+	DeadNodes(ctx context.Context, ttl time.Duration) ([]string, error)
+	// Purge 清理节点登记的全部目录项, 已指向其他节点的项 (如连接已恢复到其他节点) 需保留
+	Purge(ctx context.Context, node string) error
+}
+
+// 集群内传递的消息内容 (已使用路由默认编解码器编码)
+type RawPayload []byte
+
+type SocketClusterOption func(o *SocketClusterOptions)
+
+type SocketClusterOptions struct {
+	Node      string        // 节点ID, 默认随机生成
+	Topic     string        // 消息主题前缀
+	MetaKeys  []string      // 登记到目录的meta信息
+	Heartbeat time.Duration // 节点心跳间隔
+	TTL       time.Duration // 节点心跳超时, 超时后清理其目录信息
+}
+
+// 设置节点ID
+func ClusterNode(node string) SocketClusterOption {
+	return func(o *SocketClusterOptions) {
+		o.Node = node
+	}
+}
+
+// 设置消息主题前缀
+func ClusterTopic(topic string) SocketClusterOption {
+	return func(o *SocketClusterOptions) {
+		o.Topic = topic
+	}
+}
+
+// 设置登记到目录的meta信息
+func ClusterMetaKeys(keys ...string) SocketClusterOption {
+	return func(o *SocketClusterOptions) {
+		o.MetaKeys = append(o.MetaKeys, keys...)
+	}
+}
+
+// 设置节点心跳间隔及超时时间
+func ClusterHeartbeat(interval, ttl time.Duration) SocketClusterOption {
+	return func(o *SocketClusterOptions) {
+		o.Heartbeat = interval
+		o.TTL = ttl
+	}
+}
+
+// 集群消息
+type clusterMessage struct {
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Target  string `json:"target,omitempty"`
+	Key     string `json:"key,omitempty"`
+	Route   string `json:"route"`
+	Payload []byte `json:"payload,omitempty"`
+}
+
+// Socket集群推送
+// 连接及配置的meta信息登记到目录中, 推送消息时按目录查找连接所在节点, 通过消息总线转发
+type SocketCluster struct {
+	sync.RWMutex
+	opts   SocketClusterOptions
+	router *SocketRouter
+	bus    SocketBus
+	dir    SocketDirectory
+	socket *Socket
+	unsubs []func() error
+	exit   chan struct{}
+}
+
+// 获取节点ID
+func (sc *SocketCluster) Node() string {
+	return sc.opts.Node
+}
+
+// 获取配置
+func (sc *SocketCluster) Opts() SocketClusterOptions {
+	return sc.opts
+}
+
+// 推送消息到指定连接
+func (sc *SocketCluster) SendToConn(connId string, route string, v interface{}) error {
+	payload, err := sc.marshal(v)
+	if err != nil {
+		return err
+	}
+
+	if conn := sc.local(connId); conn != nil {
+		return sc.router.Push(conn, route, payload)
+	}
+
+	node, err := sc.dir.ConnNode(context.Background(), connId)
+	if err != nil || node == "" {
+		return err
+	}
+
+	// 会话已断开但未过期时保存到本节点的会话中
+	if node == sc.opts.Node {
+		if s := sc.localSocket(); s != nil && s.isSuspended(connId) {
+			return s.Send(connId, route, payload)
+		}
+		return nil
+	}
+
+	return sc.publish(sc.nodeTopic(node), &clusterMessage{To: clusterToConn, Target: connId, Route: route, Payload: payload})
+}
+
+// 推送消息到指定meta信息的连接, key 需通过 ClusterMetaKeys 登记
+func (sc *SocketCluster) SendToMeta(key string, value interface{}, route string, v interface{}) error {
+	val := conv.String(value)
+	msg := &clusterMessage{To: clusterToMeta, Target: val, Key: key, Route: route}
+	return sc.sendToIndex(metaIndex(key, val), msg, v)
+}
+
+// 推送消息到分组内的连接
+func (sc *SocketCluster) SendToGroup(group string, route string, v interface{}) error {
+	msg := &clusterMessage{To: clusterToGroup, Target: group, Route: route}
+	return sc.sendToIndex(groupIndex(group), msg, v)
+}
+
+// 广播消息到所有节点的连接
+func (sc *SocketCluster) Broadcast(route string, v interface{}) error {
+	payload, err := sc.marshal(v)
+	if err != nil {
+		return err
+	}
+
+	msg := &clusterMessage{To: clusterBroadcast, Route: route, Payload: payload}
+	sc.deliver(msg)
+
+	return sc.publish(sc.broadcastTopic(), msg)
+}
+
+// 连接加入分组
+func (sc *SocketCluster) Join(conn *SocketConn, group string) error {
+	return sc.localSocket().Join(conn, group)
+}
+
+// 连接退出分组
+func (sc *SocketCluster) Leave(conn *SocketConn, group string) error {
+	return sc.localSocket().Leave(conn, group)
+}
+
+// 设置连接meta信息并更新目录
+func (sc *SocketCluster) SetMeta(conn *SocketConn, key, value string) error {
+	ctx := context.Background()
+	if old := conn.GetMeta(key); old != "" && sc.indexed(key) {
+		if err := sc.dir.DelIndex(ctx, sc.opts.Node, metaIndex(key, old), conn.Id()); err != nil {
+			return err
+		}
+	}
+
+	conn.SetMeta(key, value)
+
+	if value != "" && sc.indexed(key) {
+		return sc.dir.AddIndex(ctx, sc.opts.Node, metaIndex(key, value), conn.Id())
+	}
+	return nil
+}
+
+// 连接建立时登记目录
+func (sc *SocketCluster) OnConnect(_ *Socket, conn *SocketConn) {
+	ctx := context.Background()
+	if err := sc.dir.AddConn(ctx, sc.opts.Node, conn.Id()); err != nil {
+		log.Warnf("[%s] Socket Cluster Register Failure: %s", conn.Id(), err.Error())
+	}
+	for _, key := range sc.opts.MetaKeys {
+		if val := conn.GetMeta(key); val != "" {
+			if err := sc.dir.AddIndex(ctx, sc.opts.Node, metaIndex(key, val), conn.Id()); err != nil {
+				log.Warnf("[%s] Socket Cluster Register Failure: %s", conn.Id(), err.Error())
+			}
+		}
+	}
+}
+
+// 连接断开时移除目录
+func (sc *SocketCluster) OnDisconnect(_ *Socket, conn *SocketConn) {
+	ctx := context.Background()
+	for _, key := range sc.opts.MetaKeys {
+		if val := conn.GetMeta(key); val != "" {
+			_ = sc.dir.DelIndex(ctx, sc.opts.Node, metaIndex(key, val), conn.Id())
+		}
+	}
+	// 可恢复的会话在过期时移除
+	if resumeOf(conn) != nil {
+		return
+	}
+	if err := sc.dir.DelConn(ctx, sc.opts.Node, conn.Id()); err != nil {
+		log.Warnf("[%s] Socket Cluster Unregister Failure: %s", conn.Id(), err.Error())
+	}
+}
+
+// 会话过期时移除本节点的目录登记, 会话已在其他节点恢复时保留其目录
+func (sc *SocketCluster) OnSessionExpire(_ *Socket, connId string) {
+	if err := sc.dir.DelConn(context.Background(), sc.opts.Node, connId); err != nil {
+		log.Warnf("[%s] Socket Cluster Unregister Failure: %s", connId, err.Error())
+	}
+}
+
+// 连接加入分组时登记目录
+func (sc *SocketCluster) OnJoin(_ *Socket, conn *SocketConn, group string) {
+	if err := sc.dir.AddIndex(context.Background(), sc.opts.Node, groupIndex(group), conn.Id()); err != nil {
+		log.Warnf("[%s] Socket Cluster Join Group %s Failure: %s", conn.Id(), group, err.Error())
+	}
+}
+
+// 连接退出分组时移除目录
+func (sc *SocketCluster) OnLeave(_ *Socket, conn *SocketConn, group string) {
+	if err := sc.dir.DelIndex(context.Background(), sc.opts.Node, groupIndex(group), conn.Id()); err != nil {
+		log.Warnf("[%s] Socket Cluster Leave Group %s Failure: %s", conn.Id(), group, err.Error())
+	}
+}
+
+// 启动集群, 订阅消息并开始心跳
+func (sc *SocketCluster) Start(s *Socket) error {
+	sc.Lock()
+	defer sc.Unlock()
+
+	if sc.exit != nil {
+		return nil
+	}
+
+	sc.socket = s
+	s.node = sc.opts.Node
+	s.AddListener(sc)
+
+	for _, topic := range []string{sc.nodeTopic(sc.opts.Node), sc.broadcastTopic()} {
+		unsub, err := sc.bus.Subscribe(topic, sc.onMessage)
+		if err != nil {
+			sc.unsubscribe()
+			return err
+		}
+		sc.unsubs = append(sc.unsubs, unsub)
+	}
+
+	if err := sc.dir.Heartbeat(context.Background(), sc.opts.Node); err != nil {
+		sc.unsubscribe()
+		return err
+	}
+
+	sc.exit = make(chan struct{})
+	go sc.heartbeat(sc.exit)
+
+	log.Infof("Socket Cluster Node %s Started", sc.opts.Node)
+
+	return nil
+}
+
+// 停止集群, 并清理本节点的目录信息
+func (sc *SocketCluster) Stop() error {
+	sc.Lock()
+	defer sc.Unlock()
+
+	if sc.exit == nil {
+		return nil
+	}
+
+	close(sc.exit)
+	sc.exit = nil
+	sc.unsubscribe()
+
+	return sc.dir.Purge(context.Background(), sc.opts.Node)
+}
+
+func (sc *SocketCluster) unsubscribe() {
+	for _, unsub := range sc.unsubs {
+		_ = unsub()
+	}
+	sc.unsubs = nil
+}
+
+// 节点心跳, 并清理超时节点的目录信息
+func (sc *SocketCluster) heartbeat(exit chan struct{}) {
+	ticker := time.NewTicker(sc.opts.Heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-exit:
+			return
+		case <-ticker.C:
+			ctx := context.Background()
+			if err := sc.dir.Heartbeat(ctx, sc.opts.Node); err != nil {
+				log.Warnf("Socket Cluster Heartbeat Failure: %s", err.Error())
+				continue
+			}
+
+			nodes, err := sc.dir.DeadNodes(ctx, sc.opts.TTL)
+			if err != nil {
+				log.Warnf("Socket Cluster Check Dead Nodes Failure: %s", err.Error())
+				continue
+			}
+			for _, node := range nodes {
+				if node == sc.opts.Node {
+					continue
+				}
+				if err := sc.dir.Purge(ctx, node); err != nil {
+					log.Warnf("Socket Cluster Purge Node %s Failure: %s", node, err.Error())
+				} else {
+					log.Infof("Socket Cluster Purge Dead Node %s", node)
+				}
+			}
+		}
+	}
+}
+
+// 按目录索引推送消息
+func (sc *SocketCluster) sendToIndex(index string, msg *clusterMessage, v interface{}) error {
+	payload, err := sc.marshal(v)
+	if err != nil {
+		return err
+	}
+	msg.Payload = payload
+
+	sc.deliver(msg)
+
+	nodes, err := sc.dir.IndexNodes(context.Background(), index)
+	if err != nil {
+		return err
+	}
+
+	for _, node := range nodes {
+		if node == sc.opts.Node {
+			continue
+		}
+		if err := sc.publish(sc.nodeTopic(node), msg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// 接收集群消息
+func (sc *SocketCluster) onMessage(data []byte) {
+	var msg clusterMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		log.Warnf("Socket Cluster Decode Message Failure: %s", err.Error())
+		return
+	}
+	if msg.From == sc.opts.Node {
+		return
+	}
+
+	sc.deliver(&msg)
+}
+
+// 推送消息到本节点的连接
+func (sc *SocketCluster) deliver(msg *clusterMessage) {
+	s := sc.localSocket()
+	if s == nil {
+		return
+	}
+
+	var conns []*SocketConn
+	switch msg.To {
+	case clusterToConn:
+		if conn := s.conns.Get(msg.Target); conn != nil {
+			conns = append(conns, conn)
+		} else if s.isSuspended(msg.Target) {
+			if err := s.Send(msg.Target, msg.Route, RawPayload(msg.Payload)); err != nil {
+				log.Debugf("[%s] Socket Cluster Push Failure: %s", msg.Target, err.Error())
+			}
+		}
+	case clusterToMeta:
+		conns = s.conns.GetByMeta(msg.Key, msg.Target)
+	case clusterToGroup:
+		conns = s.conns.GetByGroup(msg.Target)
+	case clusterBroadcast:
+		for _, conn := range s.conns.All() {
+			conns = append(conns, conn)
+		}
+	}
+
+	for _, conn := range conns {
+		if err := sc.router.Push(conn, msg.Route, RawPayload(msg.Payload)); err != nil {
+			log.Debugf("[%s] Socket Cluster Push Failure: %s", conn.Id(), err.Error())
+		}
+	}
+}
+
+func (sc *SocketCluster) publish(topic string, msg *clusterMessage) error {
+	msg.From = sc.opts.Node
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return sc.bus.Publish(topic, b)
+}
+
+func (sc *SocketCluster) marshal(v interface{}) (RawPayload, error) {
+	if v == nil {
+		return nil, nil
+	}
+	if raw, ok := v.(RawPayload); ok {
+		return raw, nil
+	}
+	return sc.router.opts.Codec.Marshal(v)
+}
+
+func (sc *SocketCluster) local(connId string) *SocketConn {
+	if s := sc.localSocket(); s != nil {
+		return s.conns.Get(connId)
+	}
+	return nil
+}
+
+func (sc *SocketCluster) localSocket() *Socket {
+	sc.RLock()
+	defer sc.RUnlock()
+
+	return sc.socket
+}
+
+func (sc *SocketCluster) indexed(key string) bool {
+	for _, k := range sc.opts.MetaKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+func (sc *SocketCluster) nodeTopic(node string) string {
+	return fmt.Sprintf("%s.node.%s", sc.opts.Topic, node)
+}
+
+func (sc *SocketCluster) broadcastTopic() string {
+	return fmt.Sprintf("%s.broadcast", sc.opts.Topic)
+}
+
+func metaIndex(key, value string) string {
+	return fmt.Sprintf("meta:%s:%s", key, value)
+}
+
+func groupIndex(group string) string {
+	return fmt.Sprintf("group:%s", group)
+}
+
+// NewSocketCluster 实例化Socket集群推送, 消息通过 router 编码后推送到客户端
+func NewSocketCluster(router *SocketRouter, bus SocketBus, dir SocketDirectory, opts ...SocketClusterOption) *SocketCluster {
+	sc := &SocketCluster{
+		opts: SocketClusterOptions{
+			Node:      uuid.New().String(),
+			Topic:     "micro.socket",
+			Heartbeat: 10 * time.Second,
+			TTL:       30 * time.Second,
+		},
+		router: router,
+		bus:    bus,
+		dir:    dir,
+	}
+	for _, o := range opts {
+		o(&sc.opts)
+	}
+	return sc
+}
+
+// go-micro broker 消息总线
+type brokerBus struct {
+	b broker.Broker
+}
+
+func (bb *brokerBus) Publish(topic string, data []byte) error {
+	return bb.b.Publish(topic, &broker.Message{Body: data})
+}
+
+func (bb *brokerBus) Subscribe(topic string, handler func(data []byte)) (func() error, error) {
+	sub, err := bb.b.Subscribe(topic, func(e broker.Event) error {
+		handler(e.Message().Body)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sub.Unsubscribe, nil
+}
+
+// NewBrokerBus 使用 go-micro broker 作为Socket集群消息总线
+func NewBrokerBus(b broker.Broker) SocketBus {
+	return &brokerBus{b: b}
+}
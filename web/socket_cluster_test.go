@@ -0,0 +1,295 @@
+package web
+
+import (
+	"context"
+	"github.com/labstack/echo/v4"
+	"github.com/micro/go-micro/v2/metadata"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 进程内消息总线
+type testBus struct {
+	sync.RWMutex
+	handlers map[string][]func(data []byte)
+}
+
+func (b *testBus) Publish(topic string, data []byte) error {
+	b.RLock()
+	handlers := b.handlers[topic]
+	b.RUnlock()
+
+	for _, h := range handlers {
+		h(data)
+	}
+	return nil
+}
+
+func (b *testBus) Subscribe(topic string, handler func(data []byte)) (func() error, error) {
+	b.Lock()
+	defer b.Unlock()
+
+	b.handlers[topic] = append(b.handlers[topic], handler)
+	return func() error {
+		b.Lock()
+		defer b.Unlock()
+		delete(b.handlers, topic)
+		return nil
+	}, nil
+}
+
+// 进程内连接目录, 与 Redis 实现相同, 只删除仍指向该节点的目录项
+type testDirectory struct {
+	sync.Mutex
+	hashes  map[string]map[string]string // 目录 (连接, 索引) -> 连接ID -> 节点ID
+	entries map[string]map[string]bool   // 节点 -> 登记项
+	beats   map[string]time.Time
+}
+
+const testConnKey = "conn"
+
+func newTestDirectory() *testDirectory {
+	return &testDirectory{
+		hashes:  make(map[string]map[string]string),
+		entries: make(map[string]map[string]bool),
+		beats:   make(map[string]time.Time),
+	}
+}
+
+func (d *testDirectory) set(node, key, connId string) {
+	d.Lock()
+	defer d.Unlock()
+
+	if d.hashes[key] == nil {
+		d.hashes[key] = make(map[string]string)
+	}
+	if d.entries[node] == nil {
+		d.entries[node] = make(map[string]bool)
+	}
+	d.hashes[key][connId] = node
+	d.entries[node][key+"|"+connId] = true
+}
+
+func (d *testDirectory) del(node, key, connId string) {
+	d.Lock()
+	defer d.Unlock()
+
+	if d.hashes[key][connId] == node {
+		delete(d.hashes[key], connId)
+	}
+	delete(d.entries[node], key+"|"+connId)
+}
+
+func (d *testDirectory) get(key, connId string) string {
+	d.Lock()
+	defer d.Unlock()
+	return d.hashes[key][connId]
+}
+
+func (d *testDirectory) conns(key string) []string {
+	d.Lock()
+	defer d.Unlock()
+
+	var ids []string
+	for id := range d.hashes[key] {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func (d *testDirectory) registered(node string) int {
+	d.Lock()
+	defer d.Unlock()
+	return len(d.entries[node])
+}
+
+func (d *testDirectory) AddConn(_ context.Context, node, connId string) error {
+	d.set(node, testConnKey, connId)
+	return nil
+}
+
+func (d *testDirectory) DelConn(_ context.Context, node, connId string) error {
+	d.del(node, testConnKey, connId)
+	return nil
+}
+
+func (d *testDirectory) AddIndex(_ context.Context, node, index, connId string) error {
+	d.set(node, index, connId)
+	return nil
+}
+
+func (d *testDirectory) DelIndex(_ context.Context, node, index, connId string) error {
+	d.del(node, index, connId)
+	return nil
+}
+
+func (d *testDirectory) ConnNode(_ context.Context, connId string) (string, error) {
+	return d.get(testConnKey, connId), nil
+}
+
+func (d *testDirectory) IndexNodes(_ context.Context, index string) ([]string, error) {
+	d.Lock()
+	defer d.Unlock()
+
+	var nodes []string
+	for _, node := range d.hashes[index] {
+		nodes = append(nodes, node)
+	}
+	return unique(nodes), nil
+}
+
+func (d *testDirectory) Heartbeat(_ context.Context, node string) error {
+	d.Lock()
+	defer d.Unlock()
+	d.beats[node] = time.Now()
+	return nil
+}
+
+func (d *testDirectory) DeadNodes(_ context.Context, ttl time.Duration) ([]string, error) {
+	d.Lock()
+	defer d.Unlock()
+
+	var nodes []string
+	for node, at := range d.beats {
+		if time.Since(at) > ttl {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes, nil
+}
+
+func (d *testDirectory) Purge(_ context.Context, node string) error {
+	d.Lock()
+	defer d.Unlock()
+
+	for e := range d.entries[node] {
+		i := strings.LastIndex(e, "|")
+		key, connId := e[:i], e[i+1:]
+		if d.hashes[key][connId] == node {
+			delete(d.hashes[key], connId)
+		}
+	}
+	delete(d.entries, node)
+	delete(d.beats, node)
+	return nil
+}
+
+func unique(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	var result []string
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// 启动集群节点, 连接的 uid 参数登记为 meta 信息, join 路由加入分组 g
+func startClusterNode(t *testing.T, node string, bus SocketBus, dir SocketDirectory) (*Server, *SocketCluster, string) {
+	router := NewSocketRouter()
+	cluster := NewSocketCluster(router, bus, dir, ClusterNode(node), ClusterMetaKeys("uid"))
+	router.HandleFunc("join", func(c *SocketContext) error {
+		if err := cluster.Join(c.Conn(), "g"); err != nil {
+			return err
+		}
+		return c.Reply(&echoReq{Text: "joined"})
+	})
+
+	s, url := startSocketServer(t,
+		WithSocketRouter("/ws", router),
+		WithSocketCluster(cluster),
+		WithSocketOnConnect(func(c echo.Context, meta metadata.Metadata) error {
+			meta["uid"] = c.QueryParam("uid")
+			return nil
+		}),
+	)
+	return s, cluster, url
+}
+
+// 接收消息, 忽略房间成员事件
+func recvSkipPresence(c *socketClient) *SocketMessage {
+	for {
+		if msg := c.recv(); !strings.HasPrefix(msg.Route, "room.") {
+			return msg
+		}
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSocketClusterPush(t *testing.T) {
+	bus := &testBus{handlers: make(map[string][]func(data []byte))}
+	dir := newTestDirectory()
+	a, ca, _ := startClusterNode(t, "a", bus, dir)
+	defer a.Close()
+	b, _, url := startClusterNode(t, "b", bus, dir)
+	defer b.Close()
+
+	c := dialSocket(t, url+"?uid=u1", JSONCodec{})
+	defer c.conn.Close()
+	c.send(&SocketMessage{Id: 1, Route: "join"}, nil)
+	if res := recvSkipPresence(c); res.ReqId != 1 || res.Code != 0 {
+		t.Fatalf("unexpected reply: %+v", res)
+	}
+
+	ids := dir.conns(metaIndex("uid", "u1"))
+	if len(ids) != 1 || dir.get(testConnKey, ids[0]) != "b" {
+		t.Fatalf("connection not registered: %v", ids)
+	}
+
+	// 节点 a 推送到节点 b 上的连接
+	sends := map[string]func() error{
+		"push.conn":  func() error { return ca.SendToConn(ids[0], "push.conn", &echoReq{Text: "conn"}) },
+		"push.meta":  func() error { return ca.SendToMeta("uid", "u1", "push.meta", &echoReq{Text: "meta"}) },
+		"push.group": func() error { return ca.SendToGroup("g", "push.group", &echoReq{Text: "group"}) },
+		"push.all":   func() error { return ca.Broadcast("push.all", &echoReq{Text: "all"}) },
+	}
+	for route, send := range sends {
+		if err := send(); err != nil {
+			t.Fatal(err)
+		}
+		if msg := recvSkipPresence(c); msg.Route != route {
+			t.Fatalf("want %s, got %+v", route, msg)
+		}
+	}
+}
+
+func TestSocketClusterDisconnectAfterResume(t *testing.T) {
+	bus := &testBus{handlers: make(map[string][]func(data []byte))}
+	dir := newTestDirectory()
+	b, _, url := startClusterNode(t, "b", bus, dir)
+	defer b.Close()
+
+	c := dialSocket(t, url+"?uid=u1", JSONCodec{})
+	c.send(&SocketMessage{Id: 1, Route: "join"}, nil)
+	recvSkipPresence(c)
+	id := dir.conns(metaIndex("uid", "u1"))[0]
+
+	// 会话已在节点 c 恢复, 目录项指向节点 c
+	ctx := context.Background()
+	_ = dir.AddConn(ctx, "c", id)
+	_ = dir.AddIndex(ctx, "c", metaIndex("uid", "u1"), id)
+	_ = dir.AddIndex(ctx, "c", groupIndex("g"), id)
+
+	// 节点 b 上的旧连接断开后只移除自己的登记项
+	_ = c.conn.Close()
+	waitFor(t, func() bool { return dir.registered("b") == 0 })
+
+	for _, key := range []string{testConnKey, metaIndex("uid", "u1"), groupIndex("g")} {
+		if node := dir.get(key, id); node != "c" {
+			t.Fatalf("%s: want node c, got %q", key, node)
+		}
+	}
+}
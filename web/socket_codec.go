@@ -0,0 +1,169 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang/protobuf/proto"
+	"github.com/gorilla/websocket"
+	"sync"
+)
+
+const (
+	MetaSocketCodec = "Ws-Codec" // 客户端使用的编解码器
+
+	CodecJSON  = "json"
+	CodecProto = "proto"
+)
+
+var ErrSocketCodec = errors.New("socket codec not support the payload type")
+
+// Socket消息信封
+type SocketMessage struct {
+	Id      uint64 `protobuf:"varint,1,opt,name=id,proto3" json:"id"`                              // 消息ID
+	Route   string `protobuf:"bytes,2,opt,name=route,proto3" json:"route"`                         // 路由
+	ReqId   uint64 `protobuf:"varint,3,opt,name=req_id,json=reqId,proto3" json:"req_id,omitempty"` // 关联的请求消息ID (响应消息)
+	Code    int32  `protobuf:"varint,4,opt,name=code,proto3" json:"code,omitempty"`                // 错误码
+	Msg     string `protobuf:"bytes,5,opt,name=msg,proto3" json:"msg,omitempty"`                   // 错误信息
+	Payload []byte `protobuf:"bytes,6,opt,name=payload,proto3" json:"payload,omitempty"`           // 消息内容
+	Seq     uint64 `protobuf:"varint,7,opt,name=seq,proto3" json:"seq,omitempty"`                  // 会话消息序号 (启用会话恢复时)
+}
+
+func (m *SocketMessage) Reset()         { *m = SocketMessage{} }
+func (m *SocketMessage) String() string { return proto.CompactTextString(m) }
+func (*SocketMessage) ProtoMessage()    {}
+
+// Socket消息编解码器
+// 内置 json, proto 及 msgpack 编解码, 其他格式可实现此接口后通过 RegisterSocketCodec 注册
+type SocketCodec interface {
+	Name() string
+	Encode(m *SocketMessage) ([]byte, error)    // 编码消息信封
+	Decode(data []byte, m *SocketMessage) error // 解码消息信封
+	Marshal(v interface{}) ([]byte, error)      // 编码消息内容
+	Unmarshal(data []byte, v interface{}) error // 解码消息内容
+}
+
+// 编解码器实现此接口时使用指定的消息帧类型, 否则使用连接的默认类型
+type SocketFrameTyper interface {
+	MessageType() int // websocket.TextMessage 或 websocket.BinaryMessage
+}
+
+var socketCodecs = struct {
+	sync.RWMutex
+	codecs map[string]SocketCodec
+}{
+	codecs: map[string]SocketCodec{
+		CodecJSON:    JSONCodec{},
+		CodecProto:   ProtoCodec{},
+		CodecMsgpack: MsgpackCodec{},
+	},
+}
+
+// RegisterSocketCodec 注册Socket消息编解码器
+func RegisterSocketCodec(codec SocketCodec) {
+	socketCodecs.Lock()
+	defer socketCodecs.Unlock()
+
+	socketCodecs.codecs[codec.Name()] = codec
+}
+
+// GetSocketCodec 获取Socket消息编解码器
+func GetSocketCodec(name string) (SocketCodec, bool) {
+	socketCodecs.RLock()
+	defer socketCodecs.RUnlock()
+
+	codec, ok := socketCodecs.codecs[name]
+	return codec, ok
+}
+
+// JSON编解码, 消息内容直接作为JSON对象嵌入信封
+type JSONCodec struct{}
+
+type jsonMessage struct {
+	Id      uint64          `json:"id"`
+	Route   string          `json:"route"`
+	ReqId   uint64          `json:"req_id,omitempty"`
+	Code    int32           `json:"code,omitempty"`
+	Msg     string          `json:"msg,omitempty"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+	Seq     uint64          `json:"seq,omitempty"`
+}
+
+func (JSONCodec) Name() string {
+	return CodecJSON
+}
+
+func (JSONCodec) MessageType() int {
+	return websocket.TextMessage
+}
+
+func (JSONCodec) Encode(m *SocketMessage) ([]byte, error) {
+	return json.Marshal(&jsonMessage{
+		Id:      m.Id,
+		Route:   m.Route,
+		ReqId:   m.ReqId,
+		Code:    m.Code,
+		Msg:     m.Msg,
+		Payload: m.Payload,
+		Seq:     m.Seq,
+	})
+}
+
+func (JSONCodec) Decode(data []byte, m *SocketMessage) error {
+	var jm jsonMessage
+	if err := json.Unmarshal(data, &jm); err != nil {
+		return err
+	}
+	*m = SocketMessage{
+		Id:      jm.Id,
+		Route:   jm.Route,
+		ReqId:   jm.ReqId,
+		Code:    jm.Code,
+		Msg:     jm.Msg,
+		Payload: jm.Payload,
+		Seq:     jm.Seq,
+	}
+	return nil
+}
+
+func (JSONCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (JSONCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// Protobuf编解码, 消息内容必须实现 proto.Message
+type ProtoCodec struct{}
+
+func (ProtoCodec) Name() string {
+	return CodecProto
+}
+
+func (ProtoCodec) Encode(m *SocketMessage) ([]byte, error) {
+	return proto.Marshal(m)
+}
+
+func (ProtoCodec) Decode(data []byte, m *SocketMessage) error {
+	return proto.Unmarshal(data, m)
+}
+
+func (ProtoCodec) Marshal(v interface{}) ([]byte, error) {
+	if b, ok := v.([]byte); ok {
+		return b, nil
+	}
+	pm, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T", ErrSocketCodec, v)
+	}
+	return proto.Marshal(pm)
+}
+
+func (ProtoCodec) Unmarshal(data []byte, v interface{}) error {
+	pm, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("%w: %T", ErrSocketCodec, v)
+	}
+	return proto.Unmarshal(data, pm)
+}
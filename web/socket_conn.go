@@ -2,27 +2,45 @@ package web
 
 import (
 	"context"
+	"errors"
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 	log "github.com/micro/go-micro/v2/logger"
 	"github.com/micro/go-micro/v2/metadata"
 	"net"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 const (
 	MetaClientId = "Ws-Client-Id" // 客户端ID
 )
 
+var ErrSendQueueFull = errors.New("socket send queue is full")
+
+const extPermessageDeflate = "permessage-deflate"
+
+// 待发送的消息帧
+type socketFrame struct {
+	typ  int
+	data []byte
+}
+
 // 客户端连接
 type SocketConn struct {
 	sync.RWMutex
-	id        string            // 客户端ID
-	meta      metadata.Metadata // metadata
-	conn      *websocket.Conn   // Socket连接
-	writeChan chan []byte       // 写入消息缓冲
-	isClose   bool              // 是否已关闭
-	isLinger  bool              // 是否丢弃未发送的数据
+	lastActive int64                  // 最后收到消息的时间
+	id         string                 // 客户端ID
+	meta       metadata.Metadata      // metadata
+	values     map[string]interface{} // 连接上下文数据
+	groups     map[string]struct{}    // 所属分组
+	conn       *websocket.Conn        // Socket连接
+	writeChan  chan socketFrame       // 写入消息缓冲
+	extensions []string               // 协商的扩展
+	isClose    bool                   // 是否已关闭
+	isLinger   bool                   // 是否丢弃未发送的数据
+	opts       SocketConnOptions      // 连接配置
 }
 
 // 获取客户端ID
@@ -30,6 +48,15 @@ func (s *SocketConn) Id() string {
 	return s.id
 }
 
+// 设置客户端ID, 会话恢复时使用固定的ID
+func (s *SocketConn) setId(id string) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.id = id
+	s.meta[MetaClientId] = id
+}
+
 // 获取客户端meta信息
 func (s *SocketConn) Meta() metadata.Metadata {
 	s.RLock()
@@ -54,6 +81,59 @@ func (s *SocketConn) SetMeta(key string, value string) {
 	s.meta[key] = value
 }
 
+// 获取连接上下文数据
+func (s *SocketConn) Value(key string) interface{} {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.values[key]
+}
+
+// 设置连接上下文数据
+func (s *SocketConn) SetValue(key string, value interface{}) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.values[key] = value
+}
+
+// 加入分组
+func (s *SocketConn) JoinGroup(group string) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.groups[group] = struct{}{}
+}
+
+// 退出分组
+func (s *SocketConn) LeaveGroup(group string) {
+	s.Lock()
+	defer s.Unlock()
+
+	delete(s.groups, group)
+}
+
+// 是否在分组中
+func (s *SocketConn) InGroup(group string) bool {
+	s.RLock()
+	defer s.RUnlock()
+
+	_, ok := s.groups[group]
+	return ok
+}
+
+// 获取所属分组
+func (s *SocketConn) Groups() []string {
+	s.RLock()
+	defer s.RUnlock()
+
+	groups := make([]string, 0, len(s.groups))
+	for g := range s.groups {
+		groups = append(groups, g)
+	}
+	return groups
+}
+
 // 获取客户端metadata信息
 func (s *SocketConn) MetaData() context.Context {
 	s.RLock()
@@ -74,22 +154,79 @@ func (s *SocketConn) RemoteAddr() net.Addr {
 
 // 判断是否关闭
 func (s *SocketConn) IsClose() bool {
+	s.RLock()
+	defer s.RUnlock()
+
 	return s.isClose
 }
 
-// 写入消息
+// 获取连接配置
+func (s *SocketConn) Opts() SocketConnOptions {
+	return s.opts
+}
+
+// 读取消息, 并刷新读超时及空闲时间
+func (s *SocketConn) ReadMessage() (int, []byte, error) {
+	mt, data, err := s.conn.ReadMessage()
+	if err != nil {
+		return mt, data, err
+	}
+
+	atomic.StoreInt64(&s.lastActive, time.Now().UnixNano())
+	if s.opts.PongWait > 0 {
+		_ = s.conn.SetReadDeadline(time.Now().Add(s.opts.PongWait))
+	}
+
+	return mt, data, nil
+}
+
+// 写入消息, 使用默认的消息类型, 发送队列已满时按溢出策略处理
 func (s *SocketConn) Write(payload []byte) error {
+	return s.WriteMessage(s.opts.MessageType, payload)
+}
+
+// 写入文本消息
+func (s *SocketConn) WriteText(payload []byte) error {
+	return s.WriteMessage(websocket.TextMessage, payload)
+}
+
+// 写入二进制消息
+func (s *SocketConn) WriteBinary(payload []byte) error {
+	return s.WriteMessage(websocket.BinaryMessage, payload)
+}
+
+// 写入指定类型的消息 (websocket.TextMessage 或 websocket.BinaryMessage)
+func (s *SocketConn) WriteMessage(messageType int, payload []byte) error {
 	s.Lock()
 	defer s.Unlock()
 	if s.isClose {
 		return nil
 	}
 
-	s.doWrite(payload)
-	return nil
+	return s.doWrite(socketFrame{typ: messageType, data: payload})
 }
 
-// 关闭连接
+// 获取协商的扩展
+func (s *SocketConn) Extensions() []string {
+	return s.extensions
+}
+
+// 是否启用压缩 (permessage-deflate)
+func (s *SocketConn) Compressed() bool {
+	for _, ext := range s.extensions {
+		if ext == extPermessageDeflate {
+			return true
+		}
+	}
+	return false
+}
+
+// 获取协商的子协议
+func (s *SocketConn) Subprotocol() string {
+	return s.conn.Subprotocol()
+}
+
+// 关闭连接, 发送完队列中的消息后关闭
 func (s *SocketConn) Close() {
 	s.Lock()
 	defer s.Unlock()
@@ -97,7 +234,7 @@ func (s *SocketConn) Close() {
 		return
 	}
 
-	s.doWrite(nil)
+	close(s.writeChan)
 	s.isClose = true
 }
 
@@ -110,13 +247,40 @@ func (s *SocketConn) Destroy() {
 }
 
 // 执行写入消息
-func (s *SocketConn) doWrite(buf []byte) {
-	s.writeChan <- buf
+func (s *SocketConn) doWrite(buf socketFrame) error {
+	select {
+	case s.writeChan <- buf:
+		return nil
+	default:
+	}
+
+	switch s.opts.Overflow {
+	case OverflowDropOldest:
+		for {
+			select {
+			case <-s.writeChan:
+			default:
+			}
+			select {
+			case s.writeChan <- buf:
+				return nil
+			default:
+			}
+		}
+	case OverflowDropNew:
+		return ErrSendQueueFull
+	default:
+		log.Warnf("[%s] Socket Send Queue Overflow, Disconnect ...", s.id)
+		s.doDestroy()
+		return ErrSendQueueFull
+	}
 }
 
 // 关闭操作
 func (s *SocketConn) doDestroy() {
-	_ = s.conn.UnderlyingConn().(*net.TCPConn).SetLinger(0)
+	if tc, ok := s.conn.UnderlyingConn().(*net.TCPConn); ok {
+		_ = tc.SetLinger(0)
+	}
 	_ = s.conn.Close()
 
 	if !s.isClose {
@@ -125,29 +289,16 @@ func (s *SocketConn) doDestroy() {
 	}
 }
 
-// 实例化客户端连接
-func NewSocketConn(conn *websocket.Conn) *SocketConn {
-	s := new(SocketConn)
-	s.conn = conn
-	s.id = uuid.New().String()
-	s.meta = map[string]string{
-		MetaClientId: s.id,
+// 异步处理推送消息及心跳
+func (s *SocketConn) writeLoop() {
+	var tick <-chan time.Time
+	if interval := s.tickInterval(); interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
 	}
-	s.writeChan = make(chan []byte)
-
-	// 异步处理推送消息
-	go func() {
-		for b := range s.writeChan {
-			if b == nil {
-				break
-			}
-
-			err := conn.WriteMessage(websocket.BinaryMessage, b)
-			if err != nil {
-				break
-			}
-		}
 
+	defer func() {
 		_ = s.conn.Close()
 
 		s.Lock()
@@ -157,5 +308,86 @@ func NewSocketConn(conn *websocket.Conn) *SocketConn {
 		log.Debugf("[%s] SocketConn Write Chan is Closed ...", s.id)
 	}()
 
+	for {
+		select {
+		case f, ok := <-s.writeChan:
+			s.setWriteDeadline()
+			if !ok {
+				_ = s.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+				return
+			}
+			if s.opts.Compression {
+				s.conn.EnableWriteCompression(len(f.data) >= s.opts.CompressionThreshold)
+			}
+			if err := s.conn.WriteMessage(f.typ, f.data); err != nil {
+				return
+			}
+		case now := <-tick:
+			if s.opts.IdleTimeout > 0 && now.Sub(time.Unix(0, atomic.LoadInt64(&s.lastActive))) > s.opts.IdleTimeout {
+				log.Debugf("[%s] SocketConn Idle Timeout ...", s.id)
+				_ = s.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "idle timeout"), time.Now().Add(time.Second))
+				return
+			}
+			if s.opts.PingInterval > 0 {
+				s.setWriteDeadline()
+				if err := s.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+					return
+				}
+			}
+		}
+	}
+}
+
+func (s *SocketConn) setWriteDeadline() {
+	if s.opts.WriteWait > 0 {
+		_ = s.conn.SetWriteDeadline(time.Now().Add(s.opts.WriteWait))
+	}
+}
+
+// 心跳及空闲检查间隔
+func (s *SocketConn) tickInterval() time.Duration {
+	interval := s.opts.PingInterval
+	if idle := s.opts.IdleTimeout / 2; idle > 0 && (interval == 0 || idle < interval) {
+		interval = idle
+	}
+	return interval
+}
+
+// 实例化客户端连接
+func NewSocketConn(conn *websocket.Conn, opts ...SocketConnOption) *SocketConn {
+	return newSocketConn(conn, nil, newSocketConnOptions(opts...))
+}
+
+func newSocketConn(conn *websocket.Conn, extensions []string, opts SocketConnOptions) *SocketConn {
+	s := new(SocketConn)
+	s.conn = conn
+	s.opts = opts
+	s.extensions = extensions
+	s.id = uuid.New().String()
+	s.meta = map[string]string{
+		MetaClientId: s.id,
+	}
+	s.values = make(map[string]interface{})
+	s.groups = make(map[string]struct{})
+	s.writeChan = make(chan socketFrame, s.opts.SendQueue)
+	s.lastActive = time.Now().UnixNano()
+
+	if s.opts.Compression {
+		if err := conn.SetCompressionLevel(s.opts.CompressionLevel); err != nil {
+			log.Warnf("[%s] Set Compression Level Failure: %s", s.id, err.Error())
+		}
+	}
+	if s.opts.MaxMessageSize > 0 {
+		conn.SetReadLimit(s.opts.MaxMessageSize)
+	}
+	if s.opts.PongWait > 0 {
+		_ = conn.SetReadDeadline(time.Now().Add(s.opts.PongWait))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(s.opts.PongWait))
+		})
+	}
+
+	go s.writeLoop()
+
 	return s
 }
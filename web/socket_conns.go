@@ -7,7 +7,8 @@ import (
 
 type SocketConns struct {
 	sync.RWMutex
-	conns map[string]*SocketConn
+	conns  map[string]*SocketConn
+	groups map[string]map[string]*SocketConn // 分组索引
 }
 
 // 获取客户端列表
@@ -56,11 +57,30 @@ func (s *SocketConns) Del(id string) {
 	s.Lock()
 	defer s.Unlock()
 
-	if _, ok := s.conns[id]; ok {
+	if sc, ok := s.conns[id]; ok {
+		for _, group := range sc.Groups() {
+			s.delGroup(sc, group)
+		}
 		delete(s.conns, id)
 	}
 }
 
+// 移除客户端连接, 仅当登记的连接为 sc 时移除 (会话恢复时可能已被新连接替换)
+func (s *SocketConns) Remove(sc *SocketConn) bool {
+	s.Lock()
+	defer s.Unlock()
+
+	for _, group := range sc.Groups() {
+		s.delGroup(sc, group)
+	}
+
+	if cur, ok := s.conns[sc.Id()]; ok && cur == sc {
+		delete(s.conns, sc.Id())
+		return true
+	}
+	return false
+}
+
 // 通过Meta Key获取
 func (s *SocketConns) GetByMeta(key string, value interface{}) []*SocketConn {
 	s.RLock()
@@ -79,6 +99,71 @@ func (s *SocketConns) GetByMeta(key string, value interface{}) []*SocketConn {
 	return conns
 }
 
+// 获取分组内的客户端
+func (s *SocketConns) GetByGroup(group string) []*SocketConn {
+	s.RLock()
+	defer s.RUnlock()
+
+	conns := make([]*SocketConn, 0, len(s.groups[group]))
+	for _, sc := range s.groups[group] {
+		conns = append(conns, sc)
+	}
+
+	return conns
+}
+
+// 分组内的客户端数量
+func (s *SocketConns) CountGroup(group string) int {
+	s.RLock()
+	defer s.RUnlock()
+
+	return len(s.groups[group])
+}
+
+// 客户端加入分组, 已在分组中时返回false
+func (s *SocketConns) JoinGroup(sc *SocketConn, group string) bool {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.groups[group][sc.Id()]; ok {
+		return false
+	}
+	if s.groups[group] == nil {
+		s.groups[group] = make(map[string]*SocketConn)
+	}
+	s.groups[group][sc.Id()] = sc
+	sc.JoinGroup(group)
+
+	return true
+}
+
+// 客户端退出分组, 不在分组中时返回false
+func (s *SocketConns) LeaveGroup(sc *SocketConn, group string) bool {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.delGroup(sc, group)
+}
+
+func (s *SocketConns) delGroup(sc *SocketConn, group string) bool {
+	members, ok := s.groups[group]
+	if !ok {
+		return false
+	}
+	if cur, ok := members[sc.Id()]; !ok || cur != sc {
+		sc.LeaveGroup(group)
+		return false
+	}
+
+	delete(members, sc.Id())
+	if len(members) == 0 {
+		delete(s.groups, group)
+	}
+	sc.LeaveGroup(group)
+
+	return true
+}
+
 // 通过Meta Key删除
 func (s *SocketConns) etByMeta(key string, value interface{}) {
 	s.RLock()
@@ -102,11 +187,13 @@ func (s *SocketConns) Clean() {
 		sc.Destroy()
 		delete(s.conns, id)
 	}
+	s.groups = make(map[string]map[string]*SocketConn)
 }
 
 // 实例化客户端连接管理器
 func newSocketConns() *SocketConns {
 	return &SocketConns{
-		conns: make(map[string]*SocketConn),
+		conns:  make(map[string]*SocketConn),
+		groups: make(map[string]map[string]*SocketConn),
 	}
 }
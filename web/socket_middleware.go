@@ -0,0 +1,85 @@
+package web
+
+import (
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const socketLimiterKey = "_socket_limiter"
+
+// SocketAuthRequired 要求连接已认证, 即指定的meta信息不能为空
+func SocketAuthRequired(metaKeys ...string) SocketMiddleware {
+	return func(next SocketHandlerFunc) SocketHandlerFunc {
+		return func(c *SocketContext) error {
+			for _, key := range metaKeys {
+				if c.Conn().GetMeta(key) == "" {
+					return echo.ErrUnauthorized
+				}
+			}
+			return next(c)
+		}
+	}
+}
+
+// SocketRateLimit 按连接限制消息频率 (令牌桶), rate 为每秒产生的令牌数, burst 为桶容量
+func SocketRateLimit(rate float64, burst int) SocketMiddleware {
+	if burst < 1 {
+		burst = 1
+	}
+	return func(next SocketHandlerFunc) SocketHandlerFunc {
+		return func(c *SocketContext) error {
+			if !socketLimiterOf(c.Conn(), c.Route(), rate, burst).Allow() {
+				return echo.NewHTTPError(http.StatusTooManyRequests)
+			}
+			return next(c)
+		}
+	}
+}
+
+// 获取连接指定路由的限流器
+func socketLimiterOf(sc *SocketConn, route string, rate float64, burst int) *tokenBucket {
+	sc.Lock()
+	defer sc.Unlock()
+
+	limiters, _ := sc.values[socketLimiterKey].(map[string]*tokenBucket)
+	if limiters == nil {
+		limiters = make(map[string]*tokenBucket)
+		sc.values[socketLimiterKey] = limiters
+	}
+
+	tb, ok := limiters[route]
+	if !ok {
+		tb = &tokenBucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+		limiters[route] = tb
+	}
+	return tb
+}
+
+// 令牌桶
+type tokenBucket struct {
+	sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func (tb *tokenBucket) Allow() bool {
+	tb.Lock()
+	defer tb.Unlock()
+
+	now := time.Now()
+	tb.tokens += now.Sub(tb.last).Seconds() * tb.rate
+	if tb.tokens > tb.burst {
+		tb.tokens = tb.burst
+	}
+	tb.last = now
+
+	if tb.tokens < 1 {
+		return false
+	}
+	tb.tokens--
+	return true
+}
@@ -0,0 +1,881 @@
+package web
+
+import (
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const CodecMsgpack = "msgpack"
+
+const msgpackMaxDepth = 100 // 最大嵌套层数
+
+var errMsgpackShort = errors.New("msgpack: unexpected end of data")
+
+// MessagePack编解码, 消息内容直接作为MessagePack对象嵌入信封
+// 结构体字段名依次使用 msgpack, json 标签及字段名, time.Time 编码为时间戳扩展类型 (-1)
+type MsgpackCodec struct{}
+
+func (MsgpackCodec) Name() string {
+	return CodecMsgpack
+}
+
+func (MsgpackCodec) Encode(m *SocketMessage) ([]byte, error) {
+	n := 2
+	for _, ok := range []bool{m.ReqId > 0, m.Code != 0, m.Msg != "", len(m.Payload) > 0, m.Seq > 0} {
+		if ok {
+			n++
+		}
+	}
+
+	e := &msgpackEncoder{}
+	e.writeMapLen(n)
+	e.writeString("id")
+	e.writeUint(m.Id)
+	e.writeString("route")
+	e.writeString(m.Route)
+	if m.ReqId > 0 {
+		e.writeString("req_id")
+		e.writeUint(m.ReqId)
+	}
+	if m.Code != 0 {
+		e.writeString("code")
+		e.writeInt(int64(m.Code))
+	}
+	if m.Msg != "" {
+		e.writeString("msg")
+		e.writeString(m.Msg)
+	}
+	if len(m.Payload) > 0 {
+		e.writeString("payload")
+		e.buf = append(e.buf, m.Payload...)
+	}
+	if m.Seq > 0 {
+		e.writeString("seq")
+		e.writeUint(m.Seq)
+	}
+	return e.buf, nil
+}
+
+func (MsgpackCodec) Decode(data []byte, m *SocketMessage) error {
+	d := &msgpackDecoder{data: data}
+	n, err := d.readMapLen()
+	if err != nil {
+		return err
+	}
+
+	*m = SocketMessage{}
+	for i := 0; i < n; i++ {
+		k, err := d.decode(0)
+		if err != nil {
+			return err
+		}
+		key, _ := k.(string)
+		if key == "payload" {
+			start := d.pos
+			if _, err := d.decode(0); err != nil {
+				return err
+			}
+			m.Payload = append([]byte(nil), data[start:d.pos]...)
+			continue
+		}
+
+		v, err := d.decode(0)
+		if err != nil {
+			return err
+		}
+		switch key {
+		case "id":
+			err = msgpackAssign(reflect.ValueOf(&m.Id).Elem(), v)
+		case "route":
+			err = msgpackAssign(reflect.ValueOf(&m.Route).Elem(), v)
+		case "req_id":
+			err = msgpackAssign(reflect.ValueOf(&m.ReqId).Elem(), v)
+		case "code":
+			err = msgpackAssign(reflect.ValueOf(&m.Code).Elem(), v)
+		case "msg":
+			err = msgpackAssign(reflect.ValueOf(&m.Msg).Elem(), v)
+		case "seq":
+			err = msgpackAssign(reflect.ValueOf(&m.Seq).Elem(), v)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (MsgpackCodec) Marshal(v interface{}) ([]byte, error) {
+	e := &msgpackEncoder{}
+	if err := e.encode(reflect.ValueOf(v), 0); err != nil {
+		return nil, err
+	}
+	return e.buf, nil
+}
+
+func (MsgpackCodec) Unmarshal(data []byte, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("%w: %T", ErrSocketCodec, v)
+	}
+
+	d := &msgpackDecoder{data: data}
+	x, err := d.decode(0)
+	if err != nil {
+		return err
+	}
+	return msgpackAssign(rv.Elem(), x)
+}
+
+// 结构体字段
+type msgpackField struct {
+	name      string
+	index     []int
+	omitEmpty bool
+}
+
+var msgpackFieldCache sync.Map // map[reflect.Type][]msgpackField
+
+func msgpackFields(t reflect.Type) []msgpackField {
+	if f, ok := msgpackFieldCache.Load(t); ok {
+		return f.([]msgpackField)
+	}
+
+	var fields []msgpackField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, ok := sf.Tag.Lookup("msgpack")
+		if !ok {
+			tag = sf.Tag.Get("json")
+		}
+		if tag == "-" {
+			continue
+		}
+		name, opts := tag, ""
+		if i := strings.Index(tag, ","); i >= 0 {
+			name, opts = tag[:i], tag[i+1:]
+		}
+
+		// 未命名的嵌入结构体展开字段
+		ft := sf.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if sf.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			for _, f := range msgpackFields(ft) {
+				f.index = append([]int{i}, f.index...)
+				fields = append(fields, f)
+			}
+			continue
+		}
+		if sf.PkgPath != "" { // 未导出字段
+			continue
+		}
+
+		if name == "" {
+			name = sf.Name
+		}
+		fields = append(fields, msgpackField{
+			name:      name,
+			index:     []int{i},
+			omitEmpty: strings.Contains(","+opts+",", ",omitempty,"),
+		})
+	}
+
+	msgpackFieldCache.Store(t, fields)
+	return fields
+}
+
+// 获取字段值, 嵌入的结构体指针为空时 alloc 为true则创建, 否则 (或无法创建时) 返回无效值
+func msgpackFieldValue(v reflect.Value, index []int, alloc bool) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !alloc || !v.CanSet() {
+					return reflect.Value{}
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+type msgpackEncoder struct {
+	buf []byte
+}
+
+func (e *msgpackEncoder) encode(v reflect.Value, depth int) error {
+	if depth > msgpackMaxDepth {
+		return errors.New("msgpack: exceeded max depth")
+	}
+	if !v.IsValid() {
+		e.buf = append(e.buf, 0xc0)
+		return nil
+	}
+
+	if v.Type() == timeType {
+		e.writeTime(v.Interface().(time.Time))
+		return nil
+	}
+	if v.Type().Implements(textMarshalerType) && !(v.Kind() == reflect.Ptr && v.IsNil()) {
+		b, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return err
+		}
+		e.writeString(string(b))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			e.buf = append(e.buf, 0xc3)
+		} else {
+			e.buf = append(e.buf, 0xc2)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.writeInt(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		e.writeUint(v.Uint())
+	case reflect.Float32:
+		e.buf = append(e.buf, 0xca)
+		e.buf = appendUint32(e.buf, math.Float32bits(float32(v.Float())))
+	case reflect.Float64:
+		e.buf = append(e.buf, 0xcb)
+		e.buf = appendUint64(e.buf, math.Float64bits(v.Float()))
+	case reflect.String:
+		e.writeString(v.String())
+	case reflect.Slice:
+		if v.IsNil() {
+			e.buf = append(e.buf, 0xc0)
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			e.writeBin(v.Bytes())
+			return nil
+		}
+		return e.encodeArray(v, depth)
+	case reflect.Array:
+		return e.encodeArray(v, depth)
+	case reflect.Map:
+		if v.IsNil() {
+			e.buf = append(e.buf, 0xc0)
+			return nil
+		}
+		e.writeMapLen(v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			if err := e.encode(iter.Key(), depth+1); err != nil {
+				return err
+			}
+			if err := e.encode(iter.Value(), depth+1); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		fields := msgpackFields(v.Type())
+		values := make([]reflect.Value, 0, len(fields))
+		names := make([]string, 0, len(fields))
+		for _, f := range fields {
+			fv := msgpackFieldValue(v, f.index, false)
+			if !fv.IsValid() || f.omitEmpty && isEmptyValue(fv) {
+				continue
+			}
+			values = append(values, fv)
+			names = append(names, f.name)
+		}
+		e.writeMapLen(len(values))
+		for i, fv := range values {
+			e.writeString(names[i])
+			if err := e.encode(fv, depth+1); err != nil {
+				return err
+			}
+		}
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			e.buf = append(e.buf, 0xc0)
+			return nil
+		}
+		return e.encode(v.Elem(), depth+1)
+	default:
+		return fmt.Errorf("%w: %s", ErrSocketCodec, v.Type())
+	}
+	return nil
+}
+
+func (e *msgpackEncoder) encodeArray(v reflect.Value, depth int) error {
+	e.writeArrayLen(v.Len())
+	for i := 0; i < v.Len(); i++ {
+		if err := e.encode(v.Index(i), depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *msgpackEncoder) writeInt(i int64) {
+	switch {
+	case i >= 0:
+		e.writeUint(uint64(i))
+	case i >= -32:
+		e.buf = append(e.buf, byte(i))
+	case i >= math.MinInt8:
+		e.buf = append(e.buf, 0xd0, byte(i))
+	case i >= math.MinInt16:
+		e.buf = append(e.buf, 0xd1)
+		e.buf = appendUint16(e.buf, uint16(i))
+	case i >= math.MinInt32:
+		e.buf = append(e.buf, 0xd2)
+		e.buf = appendUint32(e.buf, uint32(i))
+	default:
+		e.buf = append(e.buf, 0xd3)
+		e.buf = appendUint64(e.buf, uint64(i))
+	}
+}
+
+func (e *msgpackEncoder) writeUint(u uint64) {
+	switch {
+	case u < 0x80:
+		e.buf = append(e.buf, byte(u))
+	case u <= math.MaxUint8:
+		e.buf = append(e.buf, 0xcc, byte(u))
+	case u <= math.MaxUint16:
+		e.buf = append(e.buf, 0xcd)
+		e.buf = appendUint16(e.buf, uint16(u))
+	case u <= math.MaxUint32:
+		e.buf = append(e.buf, 0xce)
+		e.buf = appendUint32(e.buf, uint32(u))
+	default:
+		e.buf = append(e.buf, 0xcf)
+		e.buf = appendUint64(e.buf, u)
+	}
+}
+
+func (e *msgpackEncoder) writeString(s string) {
+	n := len(s)
+	switch {
+	case n < 32:
+		e.buf = append(e.buf, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		e.buf = append(e.buf, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		e.buf = append(e.buf, 0xda)
+		e.buf = appendUint16(e.buf, uint16(n))
+	default:
+		e.buf = append(e.buf, 0xdb)
+		e.buf = appendUint32(e.buf, uint32(n))
+	}
+	e.buf = append(e.buf, s...)
+}
+
+func (e *msgpackEncoder) writeBin(b []byte) {
+	n := len(b)
+	switch {
+	case n <= math.MaxUint8:
+		e.buf = append(e.buf, 0xc4, byte(n))
+	case n <= math.MaxUint16:
+		e.buf = append(e.buf, 0xc5)
+		e.buf = appendUint16(e.buf, uint16(n))
+	default:
+		e.buf = append(e.buf, 0xc6)
+		e.buf = appendUint32(e.buf, uint32(n))
+	}
+	e.buf = append(e.buf, b...)
+}
+
+func (e *msgpackEncoder) writeArrayLen(n int) {
+	switch {
+	case n < 16:
+		e.buf = append(e.buf, 0x90|byte(n))
+	case n <= math.MaxUint16:
+		e.buf = append(e.buf, 0xdc)
+		e.buf = appendUint16(e.buf, uint16(n))
+	default:
+		e.buf = append(e.buf, 0xdd)
+		e.buf = appendUint32(e.buf, uint32(n))
+	}
+}
+
+func (e *msgpackEncoder) writeMapLen(n int) {
+	switch {
+	case n < 16:
+		e.buf = append(e.buf, 0x80|byte(n))
+	case n <= math.MaxUint16:
+		e.buf = append(e.buf, 0xde)
+		e.buf = appendUint16(e.buf, uint16(n))
+	default:
+		e.buf = append(e.buf, 0xdf)
+		e.buf = appendUint32(e.buf, uint32(n))
+	}
+}
+
+// 时间戳扩展类型
+func (e *msgpackEncoder) writeTime(t time.Time) {
+	sec, nsec := uint64(t.Unix()), uint64(t.Nanosecond())
+	switch {
+	case sec>>34 == 0 && nsec == 0 && sec <= math.MaxUint32:
+		e.buf = append(e.buf, 0xd6, 0xff)
+		e.buf = appendUint32(e.buf, uint32(sec))
+	case sec>>34 == 0:
+		e.buf = append(e.buf, 0xd7, 0xff)
+		e.buf = appendUint64(e.buf, nsec<<34|sec)
+	default:
+		e.buf = append(e.buf, 0xc7, 12, 0xff)
+		e.buf = appendUint32(e.buf, uint32(nsec))
+		e.buf = appendUint64(e.buf, sec)
+	}
+}
+
+func appendUint16(b []byte, v uint16) []byte {
+	return append(b, byte(v>>8), byte(v))
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func appendUint64(b []byte, v uint64) []byte {
+	return append(b, byte(v>>56), byte(v>>48), byte(v>>40), byte(v>>32), byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
+
+// 解码为通用类型: nil, bool, int64, uint64, float64, string, []byte, time.Time, []interface{}, map[string]interface{}
+type msgpackDecoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *msgpackDecoder) read(n int) ([]byte, error) {
+	if n < 0 || d.pos+n > len(d.data) {
+		return nil, errMsgpackShort
+	}
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+func (d *msgpackDecoder) readUint(n int) (uint64, error) {
+	b, err := d.read(n)
+	if err != nil {
+		return 0, err
+	}
+	var u uint64
+	for _, c := range b {
+		u = u<<8 | uint64(c)
+	}
+	return u, nil
+}
+
+func (d *msgpackDecoder) readMapLen() (int, error) {
+	b, err := d.read(1)
+	if err != nil {
+		return 0, err
+	}
+	switch c := b[0]; {
+	case c&0xf0 == 0x80:
+		return int(c & 0x0f), nil
+	case c == 0xde:
+		n, err := d.readUint(2)
+		return int(n), err
+	case c == 0xdf:
+		n, err := d.readUint(4)
+		return int(n), err
+	}
+	return 0, errors.New("msgpack: message is not a map")
+}
+
+func (d *msgpackDecoder) decode(depth int) (interface{}, error) {
+	if depth > msgpackMaxDepth {
+		return nil, errors.New("msgpack: exceeded max depth")
+	}
+
+	b, err := d.read(1)
+	if err != nil {
+		return nil, err
+	}
+
+	c := b[0]
+	switch {
+	case c <= 0x7f:
+		return int64(c), nil
+	case c >= 0xe0:
+		return int64(int8(c)), nil
+	case c&0xf0 == 0x80:
+		return d.decodeMap(int(c&0x0f), depth)
+	case c&0xf0 == 0x90:
+		return d.decodeArray(int(c&0x0f), depth)
+	case c&0xe0 == 0xa0:
+		return d.decodeString(int(c & 0x1f))
+	}
+
+	switch c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := d.readUint(1 << (c - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		bin, err := d.read(int(n))
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(nil), bin...), nil
+	case 0xc7, 0xc8, 0xc9:
+		n, err := d.readUint(1 << (c - 0xc7))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeExt(int(n))
+	case 0xca:
+		u, err := d.readUint(4)
+		return float64(math.Float32frombits(uint32(u))), err
+	case 0xcb:
+		u, err := d.readUint(8)
+		return math.Float64frombits(u), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		return d.readUint(1 << (c - 0xcc))
+	case 0xd0:
+		u, err := d.readUint(1)
+		return int64(int8(u)), err
+	case 0xd1:
+		u, err := d.readUint(2)
+		return int64(int16(u)), err
+	case 0xd2:
+		u, err := d.readUint(4)
+		return int64(int32(u)), err
+	case 0xd3:
+		u, err := d.readUint(8)
+		return int64(u), err
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return d.decodeExt(1 << (c - 0xd4))
+	case 0xd9, 0xda, 0xdb:
+		n, err := d.readUint(1 << (c - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeString(int(n))
+	case 0xdc, 0xdd:
+		n, err := d.readUint(2 << (c - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeArray(int(n), depth)
+	case 0xde, 0xdf:
+		n, err := d.readUint(2 << (c - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeMap(int(n), depth)
+	}
+
+	return nil, fmt.Errorf("msgpack: invalid code 0x%x", c)
+}
+
+func (d *msgpackDecoder) decodeString(n int) (interface{}, error) {
+	b, err := d.read(n)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (d *msgpackDecoder) decodeArray(n int, depth int) (interface{}, error) {
+	if n > len(d.data)-d.pos { // 每个元素至少1字节
+		return nil, errMsgpackShort
+	}
+	arr := make([]interface{}, n)
+	for i := range arr {
+		v, err := d.decode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		arr[i] = v
+	}
+	return arr, nil
+}
+
+func (d *msgpackDecoder) decodeMap(n int, depth int) (interface{}, error) {
+	if n > (len(d.data)-d.pos)/2 { // 每个键值对至少2字节
+		return nil, errMsgpackShort
+	}
+	m := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		k, err := d.decode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		v, err := d.decode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		key, ok := k.(string)
+		if !ok {
+			key = fmt.Sprint(k)
+		}
+		m[key] = v
+	}
+	return m, nil
+}
+
+// 扩展类型, 仅支持时间戳 (-1), 其他类型返回原始数据
+func (d *msgpackDecoder) decodeExt(n int) (interface{}, error) {
+	t, err := d.read(1)
+	if err != nil {
+		return nil, err
+	}
+	b, err := d.read(n)
+	if err != nil {
+		return nil, err
+	}
+	if int8(t[0]) != -1 {
+		return append([]byte(nil), b...), nil
+	}
+
+	switch n {
+	case 4:
+		return time.Unix(int64(binary.BigEndian.Uint32(b)), 0), nil
+	case 8:
+		u := binary.BigEndian.Uint64(b)
+		return time.Unix(int64(u&(1<<34-1)), int64(u>>34)), nil
+	case 12:
+		return time.Unix(int64(binary.BigEndian.Uint64(b[4:])), int64(binary.BigEndian.Uint32(b))), nil
+	}
+	return nil, fmt.Errorf("msgpack: invalid timestamp length %d", n)
+}
+
+// 将通用类型赋值到目标值
+func msgpackAssign(dst reflect.Value, x interface{}) error {
+	if dst.Kind() == reflect.Ptr {
+		if x == nil {
+			dst.Set(reflect.Zero(dst.Type()))
+			return nil
+		}
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		return msgpackAssign(dst.Elem(), x)
+	}
+	if x == nil {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+
+	xv := reflect.ValueOf(x)
+	if xv.Type().AssignableTo(dst.Type()) {
+		dst.Set(xv)
+		return nil
+	}
+	if s, ok := x.(string); ok && dst.CanAddr() {
+		if u, ok := dst.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			return u.UnmarshalText([]byte(s))
+		}
+	}
+
+	mismatch := fmt.Errorf("msgpack: cannot unmarshal %T into %s", x, dst.Type())
+
+	switch dst.Kind() {
+	case reflect.Bool:
+		b, ok := x.(bool)
+		if !ok {
+			return mismatch
+		}
+		dst.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var i int64
+		switch n := x.(type) {
+		case int64:
+			i = n
+		case uint64:
+			if n > math.MaxInt64 {
+				return mismatch
+			}
+			i = int64(n)
+		case float64:
+			i = int64(n)
+		default:
+			return mismatch
+		}
+		if dst.OverflowInt(i) {
+			return mismatch
+		}
+		dst.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		var u uint64
+		switch n := x.(type) {
+		case int64:
+			if n < 0 {
+				return mismatch
+			}
+			u = uint64(n)
+		case uint64:
+			u = n
+		case float64:
+			if n < 0 {
+				return mismatch
+			}
+			u = uint64(n)
+		default:
+			return mismatch
+		}
+		if dst.OverflowUint(u) {
+			return mismatch
+		}
+		dst.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		switch n := x.(type) {
+		case int64:
+			dst.SetFloat(float64(n))
+		case uint64:
+			dst.SetFloat(float64(n))
+		case float64:
+			dst.SetFloat(n)
+		default:
+			return mismatch
+		}
+	case reflect.String:
+		switch s := x.(type) {
+		case string:
+			dst.SetString(s)
+		case []byte:
+			dst.SetString(string(s))
+		default:
+			return mismatch
+		}
+	case reflect.Slice:
+		if s, ok := x.(string); ok && dst.Type().Elem().Kind() == reflect.Uint8 {
+			dst.SetBytes([]byte(s))
+			return nil
+		}
+		arr, ok := x.([]interface{})
+		if !ok {
+			return mismatch
+		}
+		s := reflect.MakeSlice(dst.Type(), len(arr), len(arr))
+		for i, v := range arr {
+			if err := msgpackAssign(s.Index(i), v); err != nil {
+				return err
+			}
+		}
+		dst.Set(s)
+	case reflect.Array:
+		arr, ok := x.([]interface{})
+		if !ok {
+			return mismatch
+		}
+		for i := 0; i < dst.Len(); i++ {
+			var v interface{}
+			if i < len(arr) {
+				v = arr[i]
+			}
+			if err := msgpackAssign(dst.Index(i), v); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		m, ok := x.(map[string]interface{})
+		if !ok {
+			return mismatch
+		}
+		if dst.IsNil() {
+			dst.Set(reflect.MakeMapWithSize(dst.Type(), len(m)))
+		}
+		kt, vt := dst.Type().Key(), dst.Type().Elem()
+		for k, v := range m {
+			kv := reflect.New(kt).Elem()
+			if err := msgpackAssignKey(kv, k); err != nil {
+				return err
+			}
+			vv := reflect.New(vt).Elem()
+			if err := msgpackAssign(vv, v); err != nil {
+				return err
+			}
+			dst.SetMapIndex(kv, vv)
+		}
+	case reflect.Struct:
+		m, ok := x.(map[string]interface{})
+		if !ok {
+			return mismatch
+		}
+		for _, f := range msgpackFields(dst.Type()) {
+			v, ok := m[f.name]
+			if !ok {
+				for k, mv := range m { // 与 encoding/json 一致, 不区分大小写匹配
+					if strings.EqualFold(k, f.name) {
+						v, ok = mv, true
+						break
+					}
+				}
+			}
+			if !ok {
+				continue
+			}
+			fv := msgpackFieldValue(dst, f.index, true)
+			if !fv.IsValid() {
+				continue
+			}
+			if err := msgpackAssign(fv, v); err != nil {
+				return err
+			}
+		}
+	default:
+		return mismatch
+	}
+	return nil
+}
+
+// 将字符串形式的键赋值到map的键
+func msgpackAssignKey(dst reflect.Value, k string) error {
+	switch dst.Kind() {
+	case reflect.String:
+		dst.SetString(k)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(k, 10, 64)
+		if err != nil || dst.OverflowInt(i) {
+			return fmt.Errorf("msgpack: invalid map key %q for %s", k, dst.Type())
+		}
+		dst.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u, err := strconv.ParseUint(k, 10, 64)
+		if err != nil || dst.OverflowUint(u) {
+			return fmt.Errorf("msgpack: invalid map key %q for %s", k, dst.Type())
+		}
+		dst.SetUint(u)
+	default:
+		return msgpackAssign(dst, k)
+	}
+	return nil
+}
@@ -0,0 +1,296 @@
+package web
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type msgpackLevel string
+
+type msgpackBase struct {
+	Id      int64  `json:"id"`
+	Creator string `msgpack:"creator"`
+}
+
+type msgpackItem struct {
+	Name  string `json:"name"`
+	Count uint16 `json:"count"`
+}
+
+type msgpackDoc struct {
+	msgpackBase
+	Title   string            `json:"title"`
+	Level   msgpackLevel      `json:"level"`
+	Enabled bool              `json:"enabled"`
+	Score   float64           `json:"score"`
+	Ratio   float32           `json:"ratio"`
+	Small   int8              `json:"small"`
+	Big     uint64            `json:"big"`
+	Data    []byte            `json:"data"`
+	Tags    []string          `json:"tags"`
+	Items   []*msgpackItem    `json:"items"`
+	Attrs   map[string]string `json:"attrs"`
+	Ranks   map[int]int32     `json:"ranks"`
+	Pair    [2]int            `json:"pair"`
+	Parent  *msgpackItem      `json:"parent"`
+	Extra   interface{}       `json:"extra"`
+	Note    string            `json:"note,omitempty"`
+	Ignored string            `json:"-"`
+}
+
+func TestMsgpackSpec(t *testing.T) {
+	tests := []struct {
+		v    interface{}
+		want string
+	}{
+		{nil, "c0"},
+		{true, "c3"},
+		{false, "c2"},
+		{0, "00"},
+		{127, "7f"},
+		{128, "cc80"},
+		{256, "cd0100"},
+		{65536, "ce00010000"},
+		{uint64(math.MaxUint64), "cfffffffffffffffff"},
+		{-1, "ff"},
+		{-32, "e0"},
+		{-33, "d0df"},
+		{-129, "d1ff7f"},
+		{-32769, "d2ffff7fff"},
+		{int64(math.MinInt64), "d38000000000000000"},
+		{1.5, "cb3ff8000000000000"},
+		{float32(1.5), "ca3fc00000"},
+		{"", "a0"},
+		{"abc", "a3616263"},
+		{strings.Repeat("a", 32), "d920" + strings.Repeat("61", 32)},
+		{[]byte{1, 2}, "c4020102"},
+		{[]int{1, 2}, "920102"},
+		{map[string]bool{"a": true}, "81a161c3"},
+		{time.Unix(1, 0), "d6ff00000001"},
+		{time.Unix(1, 1), "d7ff0000000400000001"},
+		{time.Unix(1<<34, 0), "c70cff000000000000000400000000"},
+	}
+
+	var codec MsgpackCodec
+	for _, tt := range tests {
+		b, err := codec.Marshal(tt.v)
+		if err != nil {
+			t.Fatalf("%#v: %s", tt.v, err)
+		}
+		if got := hex.EncodeToString(b); got != tt.want {
+			t.Errorf("%#v: want %s, got %s", tt.v, tt.want, got)
+		}
+	}
+}
+
+func TestMsgpackRoundTrip(t *testing.T) {
+	src := msgpackDoc{
+		msgpackBase: msgpackBase{Id: -1 << 40, Creator: "admin"},
+		Title:       strings.Repeat("标题", 100),
+		Level:       "high",
+		Enabled:     true,
+		Score:       math.Pi,
+		Ratio:       0.25,
+		Small:       -100,
+		Big:         math.MaxUint64,
+		Data:        bytes.Repeat([]byte{0xff}, 300),
+		Tags:        []string{"a", "", "c"},
+		Items:       []*msgpackItem{{Name: "x", Count: 65535}, nil},
+		Attrs:       map[string]string{"k": "v"},
+		Ranks:       map[int]int32{-1: 1, 2: math.MinInt32},
+		Pair:        [2]int{1, -1},
+		Parent:      &msgpackItem{Name: "p"},
+		Extra:       map[string]interface{}{"list": []interface{}{int64(1), "s", nil}},
+		Ignored:     "ignored",
+	}
+
+	var codec MsgpackCodec
+	b, err := codec.Marshal(&src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var dst msgpackDoc
+	if err := codec.Unmarshal(b, &dst); err != nil {
+		t.Fatal(err)
+	}
+	src.Ignored = ""
+	if !reflect.DeepEqual(src, dst) {
+		t.Fatalf("want %+v\ngot  %+v", src, dst)
+	}
+
+	// 字段名不区分大小写, 多余字段忽略
+	b, _ = codec.Marshal(map[string]interface{}{"TITLE": "t", "unknown": 1, "ID": 3})
+	dst = msgpackDoc{}
+	if err := codec.Unmarshal(b, &dst); err != nil {
+		t.Fatal(err)
+	}
+	if dst.Title != "t" || dst.Id != 3 {
+		t.Fatalf("case insensitive match failed: %+v", dst)
+	}
+}
+
+func TestMsgpackTime(t *testing.T) {
+	var codec MsgpackCodec
+	for _, tm := range []time.Time{
+		time.Unix(0, 0),
+		time.Unix(1600000000, 0),
+		time.Unix(1600000000, 999999999),
+		time.Date(2600, 1, 1, 0, 0, 0, 1, time.UTC),
+		time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC),
+	} {
+		b, err := codec.Marshal(tm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got time.Time
+		if err := codec.Unmarshal(b, &got); err != nil {
+			t.Fatalf("%s: %s", tm, err)
+		}
+		if !got.Equal(tm) {
+			t.Errorf("want %s, got %s", tm, got)
+		}
+	}
+}
+
+func TestMsgpackEnvelope(t *testing.T) {
+	var codec MsgpackCodec
+	payload, _ := codec.Marshal(&msgpackItem{Name: "x", Count: 1})
+
+	tests := []*SocketMessage{
+		{Id: 1, Route: "chat.send"},
+		{Id: 2, Route: "chat.send", ReqId: 1, Code: -1, Msg: "error", Seq: math.MaxUint64},
+		{Id: 3, Route: "chat.push", Payload: payload, Seq: 10},
+	}
+	for _, m := range tests {
+		b, err := codec.Encode(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got SocketMessage
+		if err := codec.Decode(b, &got); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(m, &got) {
+			t.Errorf("want %+v, got %+v", m, &got)
+		}
+	}
+
+	// 消息内容原样嵌入, 可直接解码
+	b, _ := codec.Encode(tests[2])
+	var got SocketMessage
+	_ = codec.Decode(b, &got)
+	var item msgpackItem
+	if err := codec.Unmarshal(got.Payload, &item); err != nil || item.Name != "x" || item.Count != 1 {
+		t.Fatalf("payload: %+v, %v", item, err)
+	}
+}
+
+func TestMsgpackMalformed(t *testing.T) {
+	var codec MsgpackCodec
+	deep := strings.Repeat("91", msgpackMaxDepth+2) + "c0"
+
+	tests := []struct {
+		name string
+		data string
+		v    interface{}
+	}{
+		{"empty", "", new(interface{})},
+		{"invalid code", "c1", new(interface{})},
+		{"short str", "a3616", new(interface{})},
+		{"short uint", "cd01", new(interface{})},
+		{"huge array", "ddffffffff", new(interface{})},
+		{"huge map", "dfffffffff", new(interface{})},
+		{"huge bin", "c6ffffffff00", new(interface{})},
+		{"huge ext", "c9ffffffffff", new(interface{})},
+		{"bad timestamp", "d5ff0000", new(interface{})},
+		{"too deep", deep, new(interface{})},
+		{"string to int", "a161", new(int)},
+		{"int overflow", "cd0100", new(int8)},
+		{"negative to uint", "ff", new(uint)},
+		{"uint64 to int64", "cfffffffffffffffff", new(int64)},
+		{"array to struct", "90", new(msgpackItem)},
+		{"bad map key", "81a161c3", new(map[int]bool)},
+		{"nil target", "c0", nil},
+		{"non pointer", "c0", msgpackItem{}},
+	}
+	for _, tt := range tests {
+		data, _ := hex.DecodeString(tt.data)
+		if err := codec.Unmarshal(data, tt.v); err == nil {
+			t.Errorf("%s: want error", tt.name)
+		}
+	}
+
+	for _, data := range []string{"", "c0", "90", "82a26964", "81a7706179" + "6c6f6164", "de"} {
+		b, _ := hex.DecodeString(data)
+		if err := codec.Decode(b, new(SocketMessage)); err == nil {
+			t.Errorf("envelope %q: want error", data)
+		}
+	}
+}
+
+// 随机数据及变异的合法数据不应导致 panic, 成功解码的数据应能再次编解码
+func TestMsgpackFuzz(t *testing.T) {
+	var codec MsgpackCodec
+	seed, _ := codec.Marshal(&msgpackDoc{
+		Title: "fuzz",
+		Data:  []byte{1, 2, 3},
+		Tags:  []string{"a"},
+		Items: []*msgpackItem{{Name: "x"}},
+		Attrs: map[string]string{"k": "v"},
+		Extra: time.Unix(1, 1),
+	})
+	envelope, _ := codec.Encode(&SocketMessage{Id: 1, Route: "r", Payload: seed, Seq: 1})
+
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 20000; i++ {
+		var data []byte
+		switch i % 3 {
+		case 0:
+			data = make([]byte, r.Intn(64))
+			r.Read(data)
+		case 1:
+			data = append([]byte(nil), seed...)
+			for n := r.Intn(4) + 1; n > 0; n-- {
+				data[r.Intn(len(data))] = byte(r.Intn(256))
+			}
+			data = data[:r.Intn(len(data)+1)]
+		default:
+			data = append([]byte(nil), envelope...)
+			for n := r.Intn(4) + 1; n > 0; n-- {
+				data[r.Intn(len(data))] = byte(r.Intn(256))
+			}
+		}
+		msgpackFuzzOne(t, codec, data)
+	}
+}
+
+func msgpackFuzzOne(t *testing.T, codec MsgpackCodec, data []byte) {
+	defer func() {
+		if err := recover(); err != nil {
+			t.Fatalf("panic on %x: %v", data, err)
+		}
+	}()
+
+	_ = codec.Decode(data, new(SocketMessage))
+	_ = codec.Unmarshal(data, new(msgpackDoc))
+
+	var v interface{}
+	if err := codec.Unmarshal(data, &v); err != nil {
+		return
+	}
+	b, err := codec.Marshal(v)
+	if err != nil {
+		t.Fatalf("re-marshal %x: %s", data, err)
+	}
+	var v2 interface{}
+	if err := codec.Unmarshal(b, &v2); err != nil {
+		t.Fatalf("re-unmarshal %x: %s", b, err)
+	}
+}
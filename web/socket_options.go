@@ -0,0 +1,113 @@
+package web
+
+import (
+	"compress/flate"
+	"github.com/gorilla/websocket"
+	"time"
+)
+
+// 发送队列溢出策略
+type OverflowPolicy int
+
+const (
+	OverflowDisconnect OverflowPolicy = iota // 断开连接
+	OverflowDropOldest                       // 丢弃最早的消息
+	OverflowDropNew                          // 丢弃新消息
+)
+
+const (
+	DefaultSocketPingInterval = 30 * time.Second
+	DefaultSocketPongWait     = 60 * time.Second
+	DefaultSocketWriteWait    = 10 * time.Second
+	DefaultSocketSendQueue    = 256
+)
+
+type SocketConnOption func(o *SocketConnOptions)
+
+// 客户端连接配置
+type SocketConnOptions struct {
+	PingInterval   time.Duration  // Ping间隔, 为0时不发送Ping
+	PongWait       time.Duration  // 等待Pong (或任意消息) 的超时时间, 为0时不设置读超时
+	IdleTimeout    time.Duration  // 空闲超时, 超过该时间未收到业务消息时断开连接, 为0时不限制
+	WriteWait      time.Duration  // 写超时
+	SendQueue      int            // 发送队列长度
+	Overflow       OverflowPolicy // 发送队列溢出策略
+	MaxMessageSize int64          // 最大消息长度, 为0时不限制
+
+	MessageType          int  // 默认消息帧类型, websocket.BinaryMessage 或 websocket.TextMessage
+	Compression          bool // 是否启用 permessage-deflate 压缩 (需客户端支持)
+	CompressionLevel     int  // 压缩级别
+	CompressionThreshold int  // 压缩阈值, 消息长度小于该值时不压缩
+}
+
+func newSocketConnOptions(opts ...SocketConnOption) SocketConnOptions {
+	o := SocketConnOptions{
+		PingInterval: DefaultSocketPingInterval,
+		PongWait:     DefaultSocketPongWait,
+		WriteWait:    DefaultSocketWriteWait,
+		SendQueue:    DefaultSocketSendQueue,
+		MessageType:  websocket.BinaryMessage,
+
+		CompressionLevel: flate.BestSpeed,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.SendQueue < 1 {
+		o.SendQueue = 1
+	}
+	return o
+}
+
+// 设置心跳, ping 为Ping间隔, pongWait 为等待Pong的超时时间 (应大于Ping间隔)
+func SocketHeartbeat(ping, pongWait time.Duration) SocketConnOption {
+	return func(o *SocketConnOptions) {
+		o.PingInterval = ping
+		o.PongWait = pongWait
+	}
+}
+
+// 设置空闲超时
+func SocketIdleTimeout(t time.Duration) SocketConnOption {
+	return func(o *SocketConnOptions) {
+		o.IdleTimeout = t
+	}
+}
+
+// 设置写超时
+func SocketWriteWait(t time.Duration) SocketConnOption {
+	return func(o *SocketConnOptions) {
+		o.WriteWait = t
+	}
+}
+
+// 设置发送队列长度及溢出策略
+func SocketSendQueue(size int, policy OverflowPolicy) SocketConnOption {
+	return func(o *SocketConnOptions) {
+		o.SendQueue = size
+		o.Overflow = policy
+	}
+}
+
+// 设置最大消息长度
+func SocketMaxMessageSize(size int64) SocketConnOption {
+	return func(o *SocketConnOptions) {
+		o.MaxMessageSize = size
+	}
+}
+
+// 设置默认消息帧类型, websocket.BinaryMessage 或 websocket.TextMessage
+func SocketMessageType(messageType int) SocketConnOption {
+	return func(o *SocketConnOptions) {
+		o.MessageType = messageType
+	}
+}
+
+// 启用 permessage-deflate 压缩, level 为压缩级别 (flate.BestSpeed ~ flate.BestCompression), threshold 为压缩阈值 (字节)
+func SocketCompression(level int, threshold int) SocketConnOption {
+	return func(o *SocketConnOptions) {
+		o.Compression = true
+		o.CompressionLevel = level
+		o.CompressionThreshold = threshold
+	}
+}
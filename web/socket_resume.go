@@ -0,0 +1,319 @@
+package web
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	log "github.com/micro/go-micro/v2/logger"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	RouteSessionResume = "session.resume" // 会话信息 (连接后推送)
+	RouteSessionAck    = "session.ack"    // 客户端确认已收到的消息序号
+
+	DefaultResumeBuffer = 128
+	DefaultResumeGrace  = 2 * time.Minute
+
+	ctxSocketResume = "_socket_resume"
+)
+
+// 会话恢复存储, 保存会话的待确认消息
+// frame 为已编码的消息帧, 存储时原样保存
+type SocketSessionStore interface {
+	// Touch 延长会话有效期, 会话不存在时创建并记录所属用户 owner (未认证时为空)
+	Touch(ctx context.Context, session, owner string, ttl time.Duration) error
+	// Owner 获取会话所属用户, 会话不存在时为空
+	Owner(ctx context.Context, session string) (string, error)
+	Append(ctx context.Context, session string, seq uint64, frame []byte, limit int, ttl time.Duration) error
+	Replay(ctx context.Context, session string, after uint64) (frames [][]byte, last uint64, ok bool, err error)
+	Ack(ctx context.Context, session string, seq uint64) error
+	Delete(ctx context.Context, session string) error
+}
+
+// 会话恢复配置, 需使用消息路由 (WithSocketRouter), 仅通过消息路由发送的消息可以恢复
+type SocketResumeConfig struct {
+	Store  SocketSessionStore // 会话存储
+	Buffer int                // 每个会话最多保存的待确认消息数量, 应小于发送队列长度
+	Grace  time.Duration      // 断开后会话保留时间
+}
+
+// 会话信息, 客户端重连时通过 ?resume=token&last_seq=N 恢复会话, 仅会话所属用户 (MetaUserId) 可以恢复
+type SessionResume struct {
+	Token   string `json:"token"`
+	Seq     uint64 `json:"seq"`               // 当前最后的消息序号
+	Resumed bool   `json:"resumed,omitempty"` // 是否为恢复的会话
+}
+
+// 会话过期监听, 通过 Socket.AddListener 添加的监听实现此接口时生效
+type SocketSessionListener interface {
+	OnSessionExpire(s *Socket, connId string)
+}
+
+// 会话确认
+type SessionAck struct {
+	Seq uint64 `json:"seq"`
+}
+
+// 连接的会话状态
+type socketResume struct {
+	sync.Mutex
+	cfg   *SocketResumeConfig
+	token string
+	seq   uint64
+	codec SocketCodec // 会话使用的编解码器
+	mt    int         // 会话使用的消息帧类型
+	timer *time.Timer // 断开后的会话过期计时
+	rooms []string    // 断开前所在的房间
+}
+
+// 分配消息序号, 编码并保存消息
+func (r *socketResume) save(msg *SocketMessage) ([]byte, error) {
+	r.seq++
+	msg.Seq = r.seq
+
+	data, err := r.codec.Encode(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	frame := make([]byte, len(data)+1)
+	frame[0] = byte(r.mt)
+	copy(frame[1:], data)
+	if err := r.cfg.Store.Append(context.Background(), r.token, msg.Seq, frame, r.cfg.Buffer, r.cfg.Grace); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// 保存并发送消息
+func (r *socketResume) send(sc *SocketConn, msg *SocketMessage) error {
+	r.Lock()
+	defer r.Unlock()
+
+	data, err := r.save(msg)
+	if err != nil {
+		log.Warnf("[%s] Save Socket Session Message Failure: %s", sc.Id(), err.Error())
+		if data, err = r.codec.Encode(msg); err != nil {
+			return err
+		}
+	}
+
+	return sc.WriteMessage(r.mt, data)
+}
+
+// 会话断开期间保存消息, 客户端恢复后重放
+func (r *socketResume) buffer(msg *SocketMessage) error {
+	r.Lock()
+	defer r.Unlock()
+
+	_, err := r.save(msg)
+	return err
+}
+
+// 获取连接的会话令牌, 未启用会话恢复时为空
+func (s *SocketConn) ResumeToken() string {
+	if r := resumeOf(s); r != nil {
+		return r.token
+	}
+	return ""
+}
+
+func resumeOf(sc *SocketConn) *socketResume {
+	r, _ := sc.Value(ctxSocketResume).(*socketResume)
+	return r
+}
+
+// 创建或恢复会话, 并重放未确认的消息
+func (s *Socket) resume(c echo.Context, sc *SocketConn) error {
+	cfg := s.web.opts.SocketResume
+	if cfg == nil || s.router == nil {
+		return nil
+	}
+
+	ctx := c.Request().Context()
+	codec := s.router.codecOf(sc)
+	r := &socketResume{cfg: cfg, codec: codec, mt: frameType(sc, codec)}
+
+	var frames [][]byte
+	var rooms []string
+	var user = sc.GetMeta(MetaUserId)
+	if token := c.QueryParam("resume"); token != "" {
+		owner, err := cfg.Store.Owner(ctx, token)
+		if err != nil {
+			return err
+		}
+		after, _ := strconv.ParseUint(c.QueryParam("last_seq"), 10, 64)
+		replay, last, ok, err := cfg.Store.Replay(ctx, token, after)
+		if err != nil {
+			return err
+		}
+		// 只允许会话所属用户恢复, 否则创建新会话
+		if ok && owner != user {
+			log.Warnf("[%s] Socket Session Resume Rejected: session owner mismatch", sc.Id())
+			ok = false
+		}
+		if ok {
+			r.token = token
+			r.seq = last
+			frames = replay
+			if sr := s.takeSuspended(sessionConnId(token)); sr != nil {
+				r.seq = sr.seq
+				rooms = sr.rooms
+			}
+			if after > 0 {
+				_ = cfg.Store.Ack(ctx, token, after)
+			}
+		}
+	}
+
+	resumed := r.token != ""
+	if !resumed {
+		r.token = uuid.New().String()
+	}
+	if err := cfg.Store.Touch(ctx, r.token, user, cfg.Grace); err != nil {
+		return err
+	}
+
+	sc.setId(sessionConnId(r.token))
+	sc.SetValue(ctxSocketResume, r)
+
+	// 替换会话之前的连接, 并继承其所在的房间
+	if old := s.conns.Get(sc.Id()); old != nil && old != sc {
+		for _, group := range old.Groups() {
+			s.conns.LeaveGroup(old, group)
+			s.conns.JoinGroup(sc, group)
+		}
+		s.conns.Put(sc)
+		old.Destroy()
+	}
+
+	info := &SessionResume{Token: r.token, Seq: r.seq, Resumed: resumed}
+	if err := s.router.Push(sc, RouteSessionResume, info); err != nil {
+		return err
+	}
+
+	for _, f := range frames {
+		if len(f) < 2 {
+			continue
+		}
+		if err := sc.WriteMessage(int(f[0]), f[1:]); err != nil {
+			return err
+		}
+	}
+
+	// 重新加入断开前所在的房间
+	for _, room := range rooms {
+		if err := s.Join(sc, room); err != nil {
+			log.Warnf("[%s] Rejoin Room %s Failure: %s", sc.Id(), room, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// 连接断开后保留会话, 会话过期前发送到该连接的消息将保存到会话中, 恢复时重新加入 rooms
+func (s *Socket) suspend(sc *SocketConn, rooms []string) {
+	r := resumeOf(sc)
+	if r == nil {
+		return
+	}
+	r.rooms = rooms
+
+	_ = r.cfg.Store.Touch(context.Background(), r.token, sc.GetMeta(MetaUserId), r.cfg.Grace)
+
+	id := sc.Id()
+	s.mu.Lock()
+	s.suspended[id] = r
+	r.timer = time.AfterFunc(r.cfg.Grace, func() {
+		if s.takeSuspended(id) == r {
+			for _, l := range s.listeners {
+				if sl, ok := l.(SocketSessionListener); ok {
+					sl.OnSessionExpire(s, id)
+				}
+			}
+		}
+	})
+	s.mu.Unlock()
+}
+
+// 取出已断开的会话
+func (s *Socket) takeSuspended(id string) *socketResume {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.suspended[id]
+	if !ok {
+		return nil
+	}
+	r.timer.Stop()
+	delete(s.suspended, id)
+
+	return r
+}
+
+// 是否为已断开但未过期的会话
+func (s *Socket) isSuspended(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.suspended[id]
+	return ok
+}
+
+// Send 发送消息到指定连接, 需使用消息路由
+// 连接断开但会话未过期时保存到会话中, 客户端恢复后重放, 连接不存在时返回 ErrSocketClosed
+func (s *Socket) Send(connId string, route string, v interface{}) error {
+	if s.router == nil {
+		return ErrSocketClosed
+	}
+
+	if sc := s.conns.Get(connId); sc != nil {
+		return s.router.Push(sc, route, v)
+	}
+
+	s.mu.Lock()
+	r, ok := s.suspended[connId]
+	s.mu.Unlock()
+	if !ok {
+		return ErrSocketClosed
+	}
+
+	msg := &SocketMessage{Id: s.router.nextId(), Route: route}
+	if raw, ok := v.(RawPayload); ok {
+		msg.Payload = raw
+	} else if v != nil {
+		payload, err := r.codec.Marshal(v)
+		if err != nil {
+			return err
+		}
+		msg.Payload = payload
+	}
+
+	return r.buffer(msg)
+}
+
+// 处理客户端确认
+func (s *Socket) ack(sc *SocketConn, seq uint64) {
+	if r := resumeOf(sc); r != nil {
+		if err := r.cfg.Store.Ack(context.Background(), r.token, seq); err != nil {
+			log.Warnf("[%s] Ack Socket Session Failure: %s", sc.Id(), err.Error())
+		}
+	}
+}
+
+// 会话的连接ID, 同一会话使用固定的ID, 令牌不对外暴露
+func sessionConnId(token string) string {
+	return uuid.NewSHA1(uuid.NameSpaceOID, []byte(token)).String()
+}
+
+// 消息帧类型
+func frameType(sc *SocketConn, codec SocketCodec) int {
+	if ft, ok := codec.(SocketFrameTyper); ok {
+		return ft.MessageType()
+	}
+	return sc.opts.MessageType
+}
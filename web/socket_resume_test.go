@@ -0,0 +1,178 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"github.com/micro/go-micro/v2/metadata"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testFrame struct {
+	seq  uint64
+	data []byte
+}
+
+type testSession struct {
+	owner  string
+	last   uint64
+	frames []testFrame
+}
+
+// 进程内会话存储 (不处理过期)
+type testSessionStore struct {
+	sync.Mutex
+	sessions map[string]*testSession
+}
+
+func (s *testSessionStore) Touch(_ context.Context, session, owner string, _ time.Duration) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.sessions[session]; !ok {
+		s.sessions[session] = &testSession{owner: owner}
+	}
+	return nil
+}
+
+func (s *testSessionStore) Owner(_ context.Context, session string) (string, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if ss, ok := s.sessions[session]; ok {
+		return ss.owner, nil
+	}
+	return "", nil
+}
+
+func (s *testSessionStore) Append(_ context.Context, session string, seq uint64, frame []byte, limit int, _ time.Duration) error {
+	s.Lock()
+	defer s.Unlock()
+
+	ss, ok := s.sessions[session]
+	if !ok {
+		ss = &testSession{}
+		s.sessions[session] = ss
+	}
+	ss.last = seq
+	ss.frames = append(ss.frames, testFrame{seq: seq, data: frame})
+	if limit > 0 && len(ss.frames) > limit {
+		ss.frames = ss.frames[len(ss.frames)-limit:]
+	}
+	return nil
+}
+
+func (s *testSessionStore) Replay(_ context.Context, session string, after uint64) ([][]byte, uint64, bool, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	ss, ok := s.sessions[session]
+	if !ok {
+		return nil, 0, false, nil
+	}
+	var frames [][]byte
+	for _, f := range ss.frames {
+		if f.seq > after {
+			frames = append(frames, f.data)
+		}
+	}
+	return frames, ss.last, true, nil
+}
+
+func (s *testSessionStore) Ack(_ context.Context, session string, seq uint64) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if ss, ok := s.sessions[session]; ok {
+		i := 0
+		for i < len(ss.frames) && ss.frames[i].seq <= seq {
+			i++
+		}
+		ss.frames = ss.frames[i:]
+	}
+	return nil
+}
+
+func (s *testSessionStore) Delete(_ context.Context, session string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	delete(s.sessions, session)
+	return nil
+}
+
+// 接收会话信息
+func recvSession(t *testing.T, c *socketClient) (*SocketMessage, *SessionResume) {
+	msg := c.recv()
+	if msg.Route != RouteSessionResume {
+		t.Fatalf("want session info, got %+v", msg)
+	}
+	info := new(SessionResume)
+	if err := c.codec.Unmarshal(msg.Payload, info); err != nil {
+		t.Fatal(err)
+	}
+	return msg, info
+}
+
+func TestSocketResumeReplay(t *testing.T) {
+	store := &testSessionStore{sessions: make(map[string]*testSession)}
+	s, url := startSocketServer(t,
+		WithSocketRouter("/ws", NewSocketRouter()),
+		WithSocketResume(store, 16, time.Minute),
+		WithSocketOnConnect(func(c echo.Context, meta metadata.Metadata) error {
+			meta[MetaUserId] = c.QueryParam("uid")
+			return nil
+		}),
+	)
+	defer s.Close()
+
+	c := dialSocket(t, url+"?uid=u1", JSONCodec{})
+	first, info := recvSession(t, c)
+	if info.Resumed || info.Token == "" {
+		t.Fatalf("unexpected session: %+v", info)
+	}
+	id := sessionConnId(info.Token)
+
+	if err := s.Socket().Send(id, "news", &echoReq{Text: "1"}); err != nil {
+		t.Fatal(err)
+	}
+	received := c.recv()
+	if received.Route != "news" || received.Seq <= first.Seq {
+		t.Fatalf("unexpected message: %+v", received)
+	}
+
+	// 断开期间的消息保存到会话中
+	_ = c.conn.Close()
+	waitFor(t, func() bool { return s.Socket().isSuspended(id) })
+	for i := 2; i <= 3; i++ {
+		if err := s.Socket().Send(id, "news", &echoReq{Text: fmt.Sprint(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 恢复会话后按顺序重放未收到的消息
+	c = dialSocket(t, fmt.Sprintf("%s?uid=u1&resume=%s&last_seq=%d", url, info.Token, received.Seq), JSONCodec{})
+	defer c.conn.Close()
+	if _, resumed := recvSession(t, c); !resumed.Resumed || resumed.Token != info.Token {
+		t.Fatalf("session not resumed: %+v", resumed)
+	}
+	last := received.Seq
+	for i := 2; i <= 3; i++ {
+		msg := c.recv()
+		var body echoReq
+		_ = c.codec.Unmarshal(msg.Payload, &body)
+		if msg.Route != "news" || body.Text != fmt.Sprint(i) || msg.Seq <= last {
+			t.Fatalf("unexpected replay: %+v %+v", msg, body)
+		}
+		last = msg.Seq
+	}
+
+	// 其他用户不能恢复该会话
+	other := dialSocket(t, fmt.Sprintf("%s?uid=u2&resume=%s&last_seq=0", url, info.Token), JSONCodec{})
+	defer other.conn.Close()
+	if _, res := recvSession(t, other); res.Resumed || res.Token == info.Token {
+		t.Fatalf("session resumed by other user: %+v", res)
+	}
+}
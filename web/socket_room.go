@@ -0,0 +1,165 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	log "github.com/micro/go-micro/v2/logger"
+)
+
+const (
+	RouteRoomJoined = "room.joined" // 成员加入房间事件
+	RouteRoomLeft   = "room.left"   // 成员离开房间事件
+
+	PresenceJoined = "joined"
+	PresenceLeft   = "left"
+)
+
+// 房间在线状态存储, 用于跨节点统计房间人数
+type PresenceStore interface {
+	Join(ctx context.Context, node, room, connId string) error
+	Leave(ctx context.Context, node, room, connId string) error
+	Count(ctx context.Context, room string) (int, error)
+}
+
+// 房间成员变更监听, 通过 Socket.AddListener 添加的监听实现此接口时生效
+type SocketRoomListener interface {
+	OnJoin(s *Socket, sc *SocketConn, room string)
+	OnLeave(s *Socket, sc *SocketConn, room string)
+}
+
+// 房间成员变更事件
+type PresenceEvent struct {
+	Event    string `json:"event"`
+	Room     string `json:"room"`
+	ClientId string `json:"client_id"`
+	Count    int    `json:"count"`
+}
+
+// 加入房间
+func (s *Socket) Join(sc *SocketConn, room string) error {
+	if !s.conns.JoinGroup(sc, room) {
+		return nil
+	}
+
+	if s.presence != nil {
+		if err := s.presence.Join(context.Background(), s.node, room, sc.Id()); err != nil {
+			s.conns.LeaveGroup(sc, room) // 回滚本地成员
+			return err
+		}
+	}
+
+	for _, l := range s.listeners {
+		if rl, ok := l.(SocketRoomListener); ok {
+			rl.OnJoin(s, sc, room)
+		}
+	}
+
+	s.notifyPresence(PresenceJoined, room, sc)
+
+	return nil
+}
+
+// 离开房间
+func (s *Socket) Leave(sc *SocketConn, room string) error {
+	if !s.conns.LeaveGroup(sc, room) {
+		return nil
+	}
+
+	if s.presence != nil {
+		if err := s.presence.Leave(context.Background(), s.node, room, sc.Id()); err != nil {
+			s.conns.JoinGroup(sc, room) // 回滚本地成员
+			return err
+		}
+	}
+
+	for _, l := range s.listeners {
+		if rl, ok := l.(SocketRoomListener); ok {
+			rl.OnLeave(s, sc, room)
+		}
+	}
+
+	s.notifyPresence(PresenceLeft, room, sc)
+
+	return nil
+}
+
+// 离开所有房间
+func (s *Socket) LeaveAll(sc *SocketConn) {
+	for _, room := range sc.Groups() {
+		if err := s.Leave(sc, room); err != nil {
+			log.Warnf("[%s] Leave Room %s Failure: %s", sc.Id(), room, err.Error())
+		}
+	}
+}
+
+// 获取本节点的房间成员
+func (s *Socket) Members(room string) []*SocketConn {
+	return s.conns.GetByGroup(room)
+}
+
+// 获取房间人数, 启用在线状态存储时为所有节点的人数
+func (s *Socket) MemberCount(room string) int {
+	if s.presence != nil {
+		n, err := s.presence.Count(context.Background(), room)
+		if err == nil {
+			return n
+		}
+		log.Warnf("Count Room %s Presence Failure: %s", room, err.Error())
+	}
+	return s.conns.CountGroup(room)
+}
+
+// 推送消息到房间成员
+// 启用集群推送时推送到所有节点, 未设置消息路由时 v 必须为 []byte 或 RawPayload
+func (s *Socket) Broadcast(room string, route string, v interface{}) error {
+	if cluster := s.web.opts.SocketCluster; cluster != nil {
+		return cluster.SendToGroup(room, route, v)
+	}
+
+	for _, sc := range s.conns.GetByGroup(room) {
+		if err := s.push(sc, route, v); err != nil {
+			log.Debugf("[%s] Room %s Broadcast Failure: %s", sc.Id(), room, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// 推送消息到客户端
+func (s *Socket) push(sc *SocketConn, route string, v interface{}) error {
+	if s.router != nil {
+		return s.router.Push(sc, route, v)
+	}
+
+	switch b := v.(type) {
+	case []byte:
+		return sc.Write(b)
+	case RawPayload:
+		return sc.Write(b)
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return sc.Write(b)
+}
+
+// 通知房间成员变更
+func (s *Socket) notifyPresence(event string, room string, sc *SocketConn) {
+	route := RouteRoomJoined
+	if event == PresenceLeft {
+		route = RouteRoomLeft
+	}
+
+	ev := &PresenceEvent{
+		Event:    event,
+		Room:     room,
+		ClientId: sc.Id(),
+		Count:    s.MemberCount(room),
+	}
+
+	if err := s.Broadcast(room, route, ev); err != nil {
+		log.Warnf("[%s] Notify Room %s Presence Failure: %s", sc.Id(), room, err.Error())
+	}
+}
@@ -0,0 +1,452 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	log "github.com/micro/go-micro/v2/logger"
+	"net/http"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	ErrSocketRouteNotFound = errors.New("socket route not found")
+	ErrSocketTimeout       = errors.New("socket request timeout")
+	ErrSocketClosed        = errors.New("socket connection is closed")
+
+	socketCtxType = reflect.TypeOf((*SocketContext)(nil))
+)
+
+// Socket消息处理函数
+type SocketHandlerFunc func(c *SocketContext) error
+
+// Socket中间件
+type SocketMiddleware func(next SocketHandlerFunc) SocketHandlerFunc
+
+// Socket远程错误 (客户端响应的错误信息)
+type SocketError struct {
+	Code int32
+	Msg  string
+}
+
+func (e *SocketError) Error() string {
+	return fmt.Sprintf("socket error: code=%d, msg=%s", e.Code, e.Msg)
+}
+
+type SocketRouterOption func(o *SocketRouterOptions)
+
+type SocketRouterOptions struct {
+	Codec   SocketCodec   // 默认编解码器
+	Timeout time.Duration // 请求超时时间
+	Ordered bool          // 是否在读取协程中按顺序处理消息
+}
+
+// 设置默认编解码器
+func WithSocketCodec(codec SocketCodec) SocketRouterOption {
+	return func(o *SocketRouterOptions) {
+		o.Codec = codec
+	}
+}
+
+// 设置请求超时时间
+func WithSocketTimeout(t time.Duration) SocketRouterOption {
+	return func(o *SocketRouterOptions) {
+		o.Timeout = t
+	}
+}
+
+// 按顺序处理同一连接的消息
+// 此时处理函数中不能同步等待 Request 的响应, 否则会阻塞消息读取
+func WithSocketOrdered() SocketRouterOption {
+	return func(o *SocketRouterOptions) {
+		o.Ordered = true
+	}
+}
+
+// Socket消息上下文
+type SocketContext struct {
+	context.Context
+	router  *SocketRouter
+	socket  *Socket
+	conn    *SocketConn
+	codec   SocketCodec
+	msg     *SocketMessage
+	replied bool
+}
+
+// 获取Socket服务
+func (c *SocketContext) Socket() *Socket {
+	return c.socket
+}
+
+// 获取客户端连接
+func (c *SocketContext) Conn() *SocketConn {
+	return c.conn
+}
+
+// 获取消息信封
+func (c *SocketContext) Message() *SocketMessage {
+	return c.msg
+}
+
+// 获取消息路由
+func (c *SocketContext) Route() string {
+	return c.msg.Route
+}
+
+// 获取编解码器
+func (c *SocketContext) Codec() SocketCodec {
+	return c.codec
+}
+
+// 解析消息内容
+func (c *SocketContext) Bind(v interface{}) error {
+	if len(c.msg.Payload) == 0 {
+		return nil
+	}
+	if err := c.codec.Unmarshal(c.msg.Payload, v); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error()).SetInternal(err)
+	}
+	return nil
+}
+
+// 解析并验证消息内容
+func (c *SocketContext) BindValid(v interface{}) error {
+	if err := c.Bind(v); err != nil {
+		return err
+	}
+	if c.socket != nil && c.socket.web.echo.Validator != nil {
+		return c.socket.web.echo.Validator.Validate(v)
+	}
+	return nil
+}
+
+// 响应请求
+func (c *SocketContext) Reply(v interface{}) error {
+	c.replied = true
+	return c.router.send(c.conn, c.codec, &SocketMessage{Route: c.msg.Route, ReqId: c.msg.Id}, v)
+}
+
+// 响应错误
+func (c *SocketContext) ReplyError(err error) error {
+	c.replied = true
+	res := ParseError(err)
+	return c.router.send(c.conn, c.codec, &SocketMessage{
+		Route: c.msg.Route,
+		ReqId: c.msg.Id,
+		Code:  int32(res.Code),
+		Msg:   res.Msg,
+	}, nil)
+}
+
+// 向当前连接推送消息
+func (c *SocketContext) Push(route string, v interface{}) error {
+	return c.router.Push(c.conn, route, v)
+}
+
+// Socket消息路由
+type SocketRouter struct {
+	sync.RWMutex
+	opts       SocketRouterOptions
+	seq        uint64
+	routes     map[string]SocketHandlerFunc
+	middleware []SocketMiddleware
+	pending    map[string]chan *SocketMessage
+}
+
+// 获取配置
+func (r *SocketRouter) Opts() SocketRouterOptions {
+	return r.opts
+}
+
+// 添加全局中间件
+func (r *SocketRouter) Use(m ...SocketMiddleware) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.middleware = append(r.middleware, m...)
+}
+
+// 注册消息处理函数, 可指定路由中间件
+func (r *SocketRouter) HandleFunc(route string, h SocketHandlerFunc, m ...SocketMiddleware) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.routes[route] = applySocketMiddleware(h, m...)
+}
+
+// 注册类型化的消息处理函数
+// 支持的函数签名:
+//
+//	func(*web.SocketContext, *Req) (*Resp, error)
+//	func(*web.SocketContext, *Req) error
+//	func(*web.SocketContext) (*Resp, error)
+//	func(*web.SocketContext) error
+//
+// 消息内容按连接的编解码器解析为 Req 并验证, 返回的 Resp 作为响应消息发送
+func (r *SocketRouter) Handle(route string, fn interface{}, m ...SocketMiddleware) {
+	r.HandleFunc(route, SocketHandle(fn), m...)
+}
+
+// 向客户端推送消息
+func (r *SocketRouter) Push(sc *SocketConn, route string, v interface{}) error {
+	return r.send(sc, r.codecOf(sc), &SocketMessage{Route: route}, v)
+}
+
+// 向客户端发送请求并等待响应, out 为空时忽略响应内容
+func (r *SocketRouter) Request(ctx context.Context, sc *SocketConn, route string, in, out interface{}) error {
+	codec := r.codecOf(sc)
+	msg := &SocketMessage{Id: r.nextId(), Route: route}
+
+	key := pendingKey(sc.Id(), msg.Id)
+	ch := make(chan *SocketMessage, 1)
+
+	r.Lock()
+	r.pending[key] = ch
+	r.Unlock()
+
+	defer func() {
+		r.Lock()
+		delete(r.pending, key)
+		r.Unlock()
+	}()
+
+	if err := r.send(sc, codec, msg, in); err != nil {
+		return err
+	}
+
+	if _, ok := ctx.Deadline(); !ok && r.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.opts.Timeout)
+		defer cancel()
+	}
+
+	select {
+	case res := <-ch:
+		if res.Code != 0 {
+			return &SocketError{Code: res.Code, Msg: res.Msg}
+		}
+		if out == nil || len(res.Payload) == 0 {
+			return nil
+		}
+		return codec.Unmarshal(res.Payload, out)
+	case <-ctx.Done():
+		if ctx.Err() == context.DeadlineExceeded {
+			return ErrSocketTimeout
+		}
+		return ctx.Err()
+	}
+}
+
+// 接收消息处理, 可用于 WithSocket
+func (r *SocketRouter) OnReceive(s *Socket, sc *SocketConn, data []byte) error {
+	codec := r.codecOf(sc)
+
+	msg := new(SocketMessage)
+	if err := codec.Decode(data, msg); err != nil {
+		log.Warnf("[%s] Decode Socket Message Failure: %s", sc.Id(), err.Error())
+		return nil
+	}
+
+	// 客户端确认会话消息
+	if msg.Route == RouteSessionAck {
+		var ack SessionAck
+		if err := codec.Unmarshal(msg.Payload, &ack); err == nil && ack.Seq > 0 {
+			s.ack(sc, ack.Seq)
+		}
+		return nil
+	}
+
+	// 客户端对服务端请求的响应
+	if msg.ReqId > 0 {
+		r.RLock()
+		ch, ok := r.pending[pendingKey(sc.Id(), msg.ReqId)]
+		r.RUnlock()
+		if ok {
+			select { // 重复的响应直接丢弃, 避免阻塞读取
+			case ch <- msg:
+			default:
+			}
+		}
+		return nil
+	}
+
+	if r.opts.Ordered {
+		r.dispatch(s, sc, codec, msg)
+	} else {
+		go r.dispatch(s, sc, codec, msg)
+	}
+
+	return nil
+}
+
+// 执行消息处理
+func (r *SocketRouter) dispatch(s *Socket, sc *SocketConn, codec SocketCodec, msg *SocketMessage) {
+	r.RLock()
+	h, ok := r.routes[msg.Route]
+	middleware := r.middleware
+	r.RUnlock()
+
+	if !ok {
+		h = func(c *SocketContext) error {
+			return echo.NewHTTPError(http.StatusNotFound, ErrSocketRouteNotFound.Error())
+		}
+	}
+	h = applySocketMiddleware(h, middleware...)
+
+	c := &SocketContext{
+		Context: sc.MetaData(),
+		router:  r,
+		socket:  s,
+		conn:    sc,
+		codec:   codec,
+		msg:     msg,
+	}
+
+	defer func() {
+		if e := recover(); e != nil {
+			log.Errorf("[%s] Socket Route %s Panic: %v", sc.Id(), msg.Route, e)
+			_ = c.ReplyError(fmt.Errorf("%v", e))
+		}
+	}()
+
+	if err := h(c); err != nil && !c.replied {
+		if err := c.ReplyError(err); err != nil {
+			log.Warnf("[%s] Reply Socket Message Failure: %s", sc.Id(), err.Error())
+		}
+	}
+}
+
+// 编码并发送消息
+func (r *SocketRouter) send(sc *SocketConn, codec SocketCodec, msg *SocketMessage, v interface{}) error {
+	if sc.IsClose() {
+		return ErrSocketClosed
+	}
+
+	if raw, ok := v.(RawPayload); ok {
+		msg.Payload = raw
+	} else if v != nil {
+		payload, err := codec.Marshal(v)
+		if err != nil {
+			return err
+		}
+		msg.Payload = payload
+	}
+	if msg.Id == 0 {
+		msg.Id = r.nextId()
+	}
+
+	// 启用会话恢复时分配序号并保存
+	if rs := resumeOf(sc); rs != nil && msg.Route != RouteSessionResume {
+		return rs.send(sc, msg)
+	}
+
+	data, err := codec.Encode(msg)
+	if err != nil {
+		return err
+	}
+
+	return sc.WriteMessage(frameType(sc, codec), data)
+}
+
+// 获取连接使用的编解码器
+func (r *SocketRouter) codecOf(sc *SocketConn) SocketCodec {
+	if name := sc.GetMeta(MetaSocketCodec); name != "" {
+		if codec, ok := GetSocketCodec(name); ok {
+			return codec
+		}
+	}
+	return r.opts.Codec
+}
+
+func (r *SocketRouter) nextId() uint64 {
+	return atomic.AddUint64(&r.seq, 1)
+}
+
+func NewSocketRouter(opts ...SocketRouterOption) *SocketRouter {
+	r := &SocketRouter{
+		opts: SocketRouterOptions{
+			Codec:   JSONCodec{},
+			Timeout: 10 * time.Second,
+		},
+		routes:  make(map[string]SocketHandlerFunc),
+		pending: make(map[string]chan *SocketMessage),
+	}
+	for _, o := range opts {
+		o(&r.opts)
+	}
+	return r
+}
+
+// SocketHandle 将类型化的处理函数转换为 SocketHandlerFunc, 函数签名同 SocketRouter.Handle
+func SocketHandle(fn interface{}) SocketHandlerFunc {
+	fv := reflect.ValueOf(fn)
+	ft := fv.Type()
+	if err := checkSocketHandler(ft); err != nil {
+		panic(err)
+	}
+
+	var reqType reflect.Type
+	if ft.NumIn() == 2 {
+		reqType = ft.In(1).Elem()
+	}
+
+	return func(c *SocketContext) error {
+		args := []reflect.Value{reflect.ValueOf(c)}
+		if reqType != nil {
+			req := reflect.New(reqType)
+			if err := c.BindValid(req.Interface()); err != nil {
+				return err
+			}
+			args = append(args, req)
+		}
+
+		out := fv.Call(args)
+
+		if err, _ := out[len(out)-1].Interface().(error); err != nil {
+			return err
+		}
+		if c.replied {
+			return nil
+		}
+
+		var data interface{}
+		if len(out) == 2 && !isNilValue(out[0]) {
+			data = out[0].Interface()
+		}
+
+		return c.Reply(data)
+	}
+}
+
+// 检查处理函数签名
+func checkSocketHandler(ft reflect.Type) error {
+	if ft.Kind() != reflect.Func {
+		return fmt.Errorf("web.SocketHandle: %s is not a function", ft)
+	}
+	if ft.NumIn() < 1 || ft.NumIn() > 2 || ft.In(0) != socketCtxType {
+		return fmt.Errorf("web.SocketHandle: %s must accept *web.SocketContext and an optional request pointer", ft)
+	}
+	if ft.NumIn() == 2 && ft.In(1).Kind() != reflect.Ptr {
+		return fmt.Errorf("web.SocketHandle: request of %s must be a pointer", ft)
+	}
+	if ft.NumOut() < 1 || ft.NumOut() > 2 || ft.Out(ft.NumOut()-1) != errorType {
+		return fmt.Errorf("web.SocketHandle: %s must return an optional response and an error", ft)
+	}
+	return nil
+}
+
+func applySocketMiddleware(h SocketHandlerFunc, m ...SocketMiddleware) SocketHandlerFunc {
+	for i := len(m) - 1; i >= 0; i-- {
+		h = m[i](h)
+	}
+	return h
+}
+
+func pendingKey(connId string, reqId uint64) string {
+	return fmt.Sprintf("%s:%d", connId, reqId)
+}
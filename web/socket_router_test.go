@@ -0,0 +1,213 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// 启动测试服务, 返回WebSocket地址
+func startSocketServer(t *testing.T, opts ...Option) (*Server, string) {
+	opts = append(opts, func(o *Options) {
+		o.Addr = "127.0.0.1:0"
+	})
+	s := NewServer(opts...)
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	return s, fmt.Sprintf("ws://%s%s", s.echo.Listener.Addr().String(), s.opts.SocketPath)
+}
+
+// 测试客户端
+type socketClient struct {
+	t     *testing.T
+	conn  *websocket.Conn
+	codec SocketCodec
+}
+
+func dialSocket(t *testing.T, url string, codec SocketCodec) *socketClient {
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &socketClient{t: t, conn: conn, codec: codec}
+}
+
+func (c *socketClient) send(msg *SocketMessage, v interface{}) {
+	if v != nil {
+		payload, err := c.codec.Marshal(v)
+		if err != nil {
+			c.t.Fatal(err)
+		}
+		msg.Payload = payload
+	}
+	data, err := c.codec.Encode(msg)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if err := c.conn.WriteMessage(websocket.BinaryMessage, data); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func (c *socketClient) recv() *SocketMessage {
+	_ = c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, data, err := c.conn.ReadMessage()
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	msg := new(SocketMessage)
+	if err := c.codec.Decode(data, msg); err != nil {
+		c.t.Fatal(err)
+	}
+	return msg
+}
+
+type echoReq struct {
+	Text string `json:"text"`
+}
+
+func newTestRouter() *SocketRouter {
+	router := NewSocketRouter(WithSocketTimeout(200 * time.Millisecond))
+	router.Handle("echo", func(c *SocketContext, req *echoReq) (*echoReq, error) {
+		return req, nil
+	})
+	router.HandleFunc("fail", func(c *SocketContext) error {
+		return errors.New("failed")
+	})
+	router.HandleFunc("denied", func(c *SocketContext) error {
+		return echo.NewHTTPError(http.StatusUnauthorized)
+	})
+	// 服务端向客户端发起请求, 将客户端的响应返回
+	router.HandleFunc("ask", func(c *SocketContext) error {
+		var res echoReq
+		if err := c.router.Request(c, c.Conn(), "client.ask", &echoReq{Text: "q"}, &res); err != nil {
+			return c.Reply(&echoReq{Text: err.Error()})
+		}
+		return c.Reply(&res)
+	})
+	return router
+}
+
+func TestSocketRouterReply(t *testing.T) {
+	for _, codec := range []SocketCodec{JSONCodec{}, MsgpackCodec{}} {
+		t.Run(codec.Name(), func(t *testing.T) {
+			s, url := startSocketServer(t, WithSocketRouter("/ws", newTestRouter()))
+			defer s.Close()
+			c := dialSocket(t, url+"?codec="+codec.Name(), codec)
+			defer c.conn.Close()
+
+			c.send(&SocketMessage{Id: 7, Route: "echo"}, &echoReq{Text: "hello"})
+			res := c.recv()
+			if res.ReqId != 7 || res.Route != "echo" || res.Code != 0 {
+				t.Fatalf("unexpected reply: %+v", res)
+			}
+			var body echoReq
+			if err := codec.Unmarshal(res.Payload, &body); err != nil || body.Text != "hello" {
+				t.Fatalf("unexpected payload: %+v, %v", body, err)
+			}
+
+			c.send(&SocketMessage{Id: 8, Route: "fail"}, nil)
+			if res := c.recv(); res.ReqId != 8 || res.Code != http.StatusInternalServerError {
+				t.Fatalf("unexpected error reply: %+v", res)
+			}
+
+			c.send(&SocketMessage{Id: 9, Route: "denied"}, nil)
+			if res := c.recv(); res.ReqId != 9 || res.Code != http.StatusUnauthorized {
+				t.Fatalf("unexpected error reply: %+v", res)
+			}
+
+			c.send(&SocketMessage{Id: 10, Route: "missing"}, nil)
+			if res := c.recv(); res.ReqId != 10 || res.Code != http.StatusNotFound {
+				t.Fatalf("unexpected not found reply: %+v", res)
+			}
+		})
+	}
+}
+
+func TestSocketRouterRequest(t *testing.T) {
+	s, url := startSocketServer(t, WithSocketRouter("/ws", newTestRouter()))
+	defer s.Close()
+	c := dialSocket(t, url, JSONCodec{})
+	defer c.conn.Close()
+
+	c.send(&SocketMessage{Id: 1, Route: "ask"}, nil)
+	req := c.recv()
+	if req.Route != "client.ask" || req.Id == 0 {
+		t.Fatalf("unexpected request: %+v", req)
+	}
+
+	// 重复的响应及未知请求的响应不应阻塞读取
+	c.send(&SocketMessage{Id: 100, Route: req.Route, ReqId: req.Id}, &echoReq{Text: "a"})
+	c.send(&SocketMessage{Id: 101, Route: req.Route, ReqId: req.Id}, &echoReq{Text: "b"})
+	c.send(&SocketMessage{Id: 102, Route: req.Route, ReqId: 99999}, &echoReq{Text: "c"})
+
+	res := c.recv()
+	var body echoReq
+	_ = c.codec.Unmarshal(res.Payload, &body)
+	if res.ReqId != 1 || body.Text != "a" {
+		t.Fatalf("unexpected reply: %+v %+v", res, body)
+	}
+
+	// 读取未被阻塞, 后续请求正常处理
+	c.send(&SocketMessage{Id: 2, Route: "echo"}, &echoReq{Text: "next"})
+	if res := c.recv(); res.ReqId != 2 {
+		t.Fatalf("unexpected reply: %+v", res)
+	}
+}
+
+func TestSocketRouterRequestTimeout(t *testing.T) {
+	s, url := startSocketServer(t, WithSocketRouter("/ws", newTestRouter()))
+	defer s.Close()
+	c := dialSocket(t, url, JSONCodec{})
+	defer c.conn.Close()
+
+	c.send(&SocketMessage{Id: 1, Route: "ask"}, nil)
+	if req := c.recv(); req.Route != "client.ask" {
+		t.Fatalf("unexpected request: %+v", req)
+	}
+
+	res := c.recv()
+	var body echoReq
+	_ = c.codec.Unmarshal(res.Payload, &body)
+	if res.ReqId != 1 || body.Text != ErrSocketTimeout.Error() {
+		t.Fatalf("want timeout, got %+v %+v", res, body)
+	}
+}
+
+func TestSocketRouterRequestCancel(t *testing.T) {
+	router := NewSocketRouter()
+	done := make(chan error, 1)
+	router.HandleFunc("ask", func(c *SocketContext) error {
+		ctx, cancel := context.WithCancel(c)
+		cancel()
+		done <- router.Request(ctx, c.Conn(), "client.ask", nil, nil)
+		return nil
+	})
+	s, url := startSocketServer(t, WithSocketRouter("/ws", router))
+	defer s.Close()
+	c := dialSocket(t, url, JSONCodec{})
+	defer c.conn.Close()
+
+	c.send(&SocketMessage{Id: 1, Route: "ask"}, nil)
+	select {
+	case err := <-done:
+		if err != context.Canceled {
+			t.Fatalf("want context.Canceled, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("request not canceled")
+	}
+
+	router.RLock()
+	n := len(router.pending)
+	router.RUnlock()
+	if n != 0 {
+		t.Fatalf("pending requests not cleaned: %d", n)
+	}
+}
@@ -0,0 +1,494 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	log "github.com/micro/go-micro/v2/logger"
+	"github.com/micro/go-micro/v2/metadata"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const HeaderLastEventID = "Last-Event-ID"
+
+var (
+	ErrSSEClientClosed   = errors.New("sse client is closed")
+	ErrSSEClientNotFound = errors.New("sse client not found")
+	ErrSSEUnsupported    = errors.New("sse streaming unsupported")
+	ErrSSEEventName      = errors.New("sse event name must not contain line breaks")
+)
+
+// 推送接口, Socket 及 SSE 均实现此接口, 业务可同时推送到两种传输
+type Pusher interface {
+	Send(id string, route string, v interface{}) error
+	Broadcast(room string, route string, v interface{}) error
+}
+
+// 同时推送到多种传输
+type Pushers []Pusher
+
+// 推送到指定客户端, 任一传输推送成功时返回nil
+func (ps Pushers) Send(id string, route string, v interface{}) error {
+	var err error
+	for _, p := range ps {
+		if err = p.Send(id, route, v); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// 推送到所有传输的房间 (事件流)
+func (ps Pushers) Broadcast(room string, route string, v interface{}) error {
+	var err error
+	for _, p := range ps {
+		if e := p.Broadcast(room, route, v); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// SSE 连接事件监听
+type SSEListener interface {
+	OnConnect(s *SSE, c *SSEClient)
+	OnDisconnect(s *SSE, c *SSEClient)
+}
+
+// 事件流
+type sseStream struct {
+	events  []*SSEEvent           // 最近的事件, 用于重放
+	members map[string]*SSEClient // 订阅的客户端
+}
+
+// Server-Sent Events
+// 客户端通过 ?stream=a,b 订阅事件流, 重连时通过 Last-Event-ID 重放断开期间的事件
+type SSE struct {
+	sync.RWMutex
+	wg        sync.WaitGroup
+	web       *Server
+	path      string
+	opts      SSEOptions
+	seq       uint64                // 事件ID, 以启动时间为起点, 重启后仍递增
+	clients   map[string]*SSEClient // 客户端
+	streams   map[string]*sseStream // 事件流
+	listeners []SSEListener
+	exit      chan struct{}
+	closed    bool
+}
+
+func (s *SSE) Web() *Server {
+	return s.web
+}
+
+func (s *SSE) Path() string {
+	return s.path
+}
+
+func (s *SSE) Opts() SSEOptions {
+	return s.opts
+}
+
+// 添加连接事件监听, 需在服务启动前添加
+func (s *SSE) AddListener(l SSEListener) {
+	s.listeners = append(s.listeners, l)
+}
+
+// 获取客户端
+func (s *SSE) Get(id string) *SSEClient {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.clients[id]
+}
+
+// 获取客户端列表
+func (s *SSE) Clients() map[string]*SSEClient {
+	s.RLock()
+	defer s.RUnlock()
+
+	clients := make(map[string]*SSEClient, len(s.clients))
+	for k, v := range s.clients {
+		clients[k] = v
+	}
+	return clients
+}
+
+// 客户端总数
+func (s *SSE) Count() int {
+	s.RLock()
+	defer s.RUnlock()
+
+	return len(s.clients)
+}
+
+// 根据meta信息获取客户端
+func (s *SSE) GetByMeta(key string, value string) []*SSEClient {
+	s.RLock()
+	defer s.RUnlock()
+
+	var clients []*SSEClient
+	for _, c := range s.clients {
+		if c.GetMeta(key) == value {
+			clients = append(clients, c)
+		}
+	}
+	return clients
+}
+
+// 订阅事件流
+func (s *SSE) Join(c *SSEClient, stream string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.clients[c.Id()]; !ok {
+		return ErrSSEClientNotFound
+	}
+	s.join(c, stream, 0, false)
+	return nil
+}
+
+// 取消订阅事件流
+func (s *SSE) Leave(c *SSEClient, stream string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	s.leave(c, stream)
+	return nil
+}
+
+// 取消订阅所有事件流
+func (s *SSE) LeaveAll(c *SSEClient) {
+	s.Lock()
+	defer s.Unlock()
+
+	for _, stream := range c.Streams() {
+		s.leave(c, stream)
+	}
+}
+
+// 获取事件流的订阅者
+func (s *SSE) Members(stream string) []*SSEClient {
+	s.RLock()
+	defer s.RUnlock()
+
+	st, ok := s.streams[stream]
+	if !ok {
+		return nil
+	}
+
+	clients := make([]*SSEClient, 0, len(st.members))
+	for _, c := range st.members {
+		clients = append(clients, c)
+	}
+	return clients
+}
+
+// 获取事件流的订阅人数
+func (s *SSE) MemberCount(stream string) int {
+	s.RLock()
+	defer s.RUnlock()
+
+	if st, ok := s.streams[stream]; ok {
+		return len(st.members)
+	}
+	return 0
+}
+
+// 推送事件到指定客户端, 此类事件不保存, 无法重放
+func (s *SSE) Send(clientId string, event string, v interface{}) error {
+	if !validEventName(event) {
+		return ErrSSEEventName
+	}
+
+	data, err := sseData(v)
+	if err != nil {
+		return err
+	}
+
+	c := s.Get(clientId)
+	if c == nil {
+		return ErrSSEClientNotFound
+	}
+
+	return c.send(&SSEEvent{Event: event, Data: data})
+}
+
+// 推送事件到事件流, 事件保存到事件流的重放缓冲中
+func (s *SSE) Broadcast(stream string, event string, v interface{}) error {
+	if !validEventName(event) {
+		return ErrSSEEventName
+	}
+
+	data, err := sseData(v)
+	if err != nil {
+		return err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	s.seq++
+	e := &SSEEvent{Id: s.seq, Stream: stream, Event: event, Data: data}
+
+	st := s.stream(stream)
+	if s.opts.Buffer > 0 {
+		if len(st.events) >= s.opts.Buffer {
+			st.events = append(st.events[:0], st.events[len(st.events)-s.opts.Buffer+1:]...)
+		}
+		st.events = append(st.events, e)
+	}
+
+	for _, c := range st.members {
+		if err := c.send(e); err != nil {
+			log.Debugf("[%s] SSE Broadcast %s Failure: %s", c.Id(), stream, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// SSE Handler
+func (s *SSE) Handler(c echo.Context) error {
+	meta := metadata.Metadata{}
+	if s.opts.OnConnect != nil {
+		if err := s.opts.OnConnect(c, meta); err != nil {
+			return err
+		}
+	}
+
+	w := c.Response()
+	if _, ok := w.Writer.(http.Flusher); !ok {
+		return echo.NewHTTPError(http.StatusNotImplemented, ErrSSEUnsupported.Error())
+	}
+
+	// 订阅的事件流及最后收到的事件ID
+	var streams []string
+	for _, v := range c.QueryParams()["stream"] {
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				streams = append(streams, name)
+			}
+		}
+	}
+	lastId := c.Request().Header.Get(HeaderLastEventID)
+	if lastId == "" {
+		lastId = c.QueryParam("last_event_id")
+	}
+	after, parseErr := strconv.ParseUint(lastId, 10, 64)
+	replay := parseErr == nil
+
+	client := newSSEClient(uuid.New().String(), c.Request().RemoteAddr, meta, s.opts.Queue)
+
+	// 登记客户端并获取需重放的事件, 与推送互斥以保证事件不丢失
+	s.Lock()
+	if s.closed {
+		s.Unlock()
+		return echo.NewHTTPError(http.StatusServiceUnavailable)
+	}
+	s.wg.Add(1)
+	defer s.wg.Done()
+
+	s.clients[client.Id()] = client
+	var events []*SSEEvent
+	for _, name := range streams {
+		events = append(events, s.join(client, name, after, replay)...)
+	}
+	s.Unlock()
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Id < events[j].Id
+	})
+
+	for _, l := range s.listeners {
+		l.OnConnect(s, client)
+	}
+
+	log.Debugf("[%s][%s] sse successfully connected...", client.Id(), client.addr)
+
+	w.Header().Set(echo.HeaderContentType, "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	var err error
+	if s.opts.Retry > 0 {
+		_, err = fmt.Fprintf(w, "retry: %d\n\n", s.opts.Retry/time.Millisecond)
+	}
+	for _, e := range events {
+		if err != nil {
+			break
+		}
+		_, err = w.Write(e.encode())
+	}
+	if err == nil {
+		w.Flush()
+		err = s.serve(c, client)
+	}
+	if err != nil {
+		log.Debugf("[%s] SSE Write Failure: %s", client.Id(), err.Error())
+	}
+
+	// 清理客户端
+	client.Close()
+	s.remove(client)
+	for _, l := range s.listeners {
+		l.OnDisconnect(s, client)
+	}
+
+	log.Debugf("[%s][%s] sse disconnected ...", client.Id(), client.addr)
+
+	return nil
+}
+
+// 发送队列中的事件及保活注释, 直到客户端断开或服务关闭
+func (s *SSE) serve(c echo.Context, client *SSEClient) error {
+	w := c.Response()
+
+	var keepAlive <-chan time.Time
+	if s.opts.KeepAlive > 0 {
+		ticker := time.NewTicker(s.opts.KeepAlive)
+		defer ticker.Stop()
+		keepAlive = ticker.C
+	}
+
+	for {
+		select {
+		case e := <-client.queue:
+			if _, err := w.Write(e.encode()); err != nil {
+				return err
+			}
+			// 合并队列中的事件后再刷新
+			for n := len(client.queue); n > 0; n-- {
+				if _, err := w.Write((<-client.queue).encode()); err != nil {
+					return err
+				}
+			}
+			w.Flush()
+		case <-keepAlive:
+			if _, err := w.Write([]byte(": ping\n\n")); err != nil {
+				return err
+			}
+			w.Flush()
+		case <-client.done:
+			return nil
+		case <-c.Request().Context().Done():
+			return nil
+		case <-s.exit:
+			return nil
+		}
+	}
+}
+
+// 关闭所有客户端
+func (s *SSE) Close() {
+	s.Lock()
+	if s.closed {
+		s.Unlock()
+		return
+	}
+	s.closed = true
+	close(s.exit)
+	s.Unlock()
+
+	s.wg.Wait()
+}
+
+// 获取或创建事件流, 需持有锁
+func (s *SSE) stream(name string) *sseStream {
+	st, ok := s.streams[name]
+	if !ok {
+		st = &sseStream{members: make(map[string]*SSEClient)}
+		s.streams[name] = st
+	}
+	return st
+}
+
+// 订阅事件流, replay 为true时返回ID大于 after 的事件, 需持有锁
+func (s *SSE) join(c *SSEClient, name string, after uint64, replay bool) []*SSEEvent {
+	st := s.stream(name)
+	st.members[c.Id()] = c
+
+	c.Lock()
+	c.streams[name] = struct{}{}
+	c.Unlock()
+
+	if !replay {
+		return nil
+	}
+
+	var events []*SSEEvent
+	for _, e := range st.events {
+		if e.Id > after {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+// 取消订阅事件流, 需持有锁
+func (s *SSE) leave(c *SSEClient, name string) {
+	c.Lock()
+	delete(c.streams, name)
+	c.Unlock()
+
+	st, ok := s.streams[name]
+	if !ok {
+		return
+	}
+	delete(st.members, c.Id())
+	if len(st.members) == 0 && len(st.events) == 0 {
+		delete(s.streams, name)
+	}
+}
+
+// 移除客户端
+func (s *SSE) remove(c *SSEClient) {
+	s.Lock()
+	defer s.Unlock()
+
+	for _, name := range c.Streams() {
+		s.leave(c, name)
+	}
+	delete(s.clients, c.Id())
+}
+
+// 事件名称不能包含换行, 否则会注入额外的字段
+func validEventName(event string) bool {
+	return !strings.ContainsAny(event, "\r\n")
+}
+
+// 事件数据, []byte/RawPayload/string 原样发送, 其他类型编码为JSON
+func sseData(v interface{}) ([]byte, error) {
+	switch b := v.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		return b, nil
+	case RawPayload:
+		return b, nil
+	case string:
+		return []byte(b), nil
+	}
+	return json.Marshal(v)
+}
+
+func NewSSE(s *Server) *SSE {
+	return &SSE{
+		web:     s,
+		path:    s.opts.SSEPath,
+		opts:    newSSEOptions(s.opts.SSEOpts...),
+		seq:     uint64(time.Now().UnixNano()),
+		clients: make(map[string]*SSEClient),
+		streams: make(map[string]*sseStream),
+		exit:    make(chan struct{}),
+	}
+}
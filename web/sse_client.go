@@ -0,0 +1,166 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"github.com/micro/go-micro/v2/metadata"
+	"net"
+	"strconv"
+	"sync"
+)
+
+// SSE 事件
+type SSEEvent struct {
+	Id     uint64 // 事件ID, 仅事件流中的事件有ID
+	Stream string // 所属事件流
+	Event  string // 事件名称
+	Data   []byte // 事件数据
+}
+
+// 编码为 text/event-stream 格式
+func (e *SSEEvent) encode() []byte {
+	var buf bytes.Buffer
+	if e.Id > 0 {
+		buf.WriteString("id: ")
+		buf.WriteString(strconv.FormatUint(e.Id, 10))
+		buf.WriteByte('\n')
+	}
+	if e.Event != "" {
+		buf.WriteString("event: ")
+		buf.WriteString(e.Event)
+		buf.WriteByte('\n')
+	}
+	for _, line := range bytes.Split(e.Data, []byte("\n")) {
+		buf.WriteString("data: ")
+		buf.Write(bytes.TrimSuffix(line, []byte("\r")))
+		buf.WriteByte('\n')
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes()
+}
+
+// SSE 客户端
+type SSEClient struct {
+	sync.RWMutex
+	id      string              // 客户端ID
+	meta    metadata.Metadata   // metadata
+	streams map[string]struct{} // 订阅的事件流
+	addr    string              // 客户端地址
+	queue   chan *SSEEvent      // 发送队列
+	done    chan struct{}       // 关闭信号
+	isClose bool                // 是否已关闭
+}
+
+// 获取客户端ID
+func (c *SSEClient) Id() string {
+	return c.id
+}
+
+// 获取客户端meta信息
+func (c *SSEClient) Meta() metadata.Metadata {
+	c.RLock()
+	defer c.RUnlock()
+
+	return c.meta
+}
+
+// 获取客户端meta信息
+func (c *SSEClient) GetMeta(key string) string {
+	c.RLock()
+	defer c.RUnlock()
+
+	return c.meta[key]
+}
+
+// 设置meta信息
+func (c *SSEClient) SetMeta(key string, value string) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.meta[key] = value
+}
+
+// 获取客户端metadata信息
+func (c *SSEClient) MetaData() context.Context {
+	c.RLock()
+	defer c.RUnlock()
+
+	return metadata.NewContext(context.TODO(), c.meta)
+}
+
+// 获取订阅的事件流
+func (c *SSEClient) Streams() []string {
+	c.RLock()
+	defer c.RUnlock()
+
+	streams := make([]string, 0, len(c.streams))
+	for s := range c.streams {
+		streams = append(streams, s)
+	}
+	return streams
+}
+
+// 是否订阅了事件流
+func (c *SSEClient) InStream(stream string) bool {
+	c.RLock()
+	defer c.RUnlock()
+
+	_, ok := c.streams[stream]
+	return ok
+}
+
+// 获取客户端IP地址
+func (c *SSEClient) RemoteAddr() net.Addr {
+	addr, _ := net.ResolveTCPAddr("tcp", c.addr)
+	return addr
+}
+
+// 是否已关闭
+func (c *SSEClient) IsClose() bool {
+	c.RLock()
+	defer c.RUnlock()
+
+	return c.isClose
+}
+
+// 关闭客户端
+func (c *SSEClient) Close() {
+	c.Lock()
+	defer c.Unlock()
+
+	if !c.isClose {
+		c.isClose = true
+		close(c.done)
+	}
+}
+
+// 发送事件到客户端, 队列已满时关闭客户端, 客户端重连后通过 Last-Event-ID 重放
+func (c *SSEClient) send(e *SSEEvent) error {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.isClose {
+		return ErrSSEClientClosed
+	}
+
+	select {
+	case c.queue <- e:
+		return nil
+	default:
+	}
+
+	go c.Close()
+	return ErrSendQueueFull
+}
+
+func newSSEClient(id string, addr string, meta metadata.Metadata, queue int) *SSEClient {
+	meta[MetaClientId] = id
+	return &SSEClient{
+		id:      id,
+		meta:    meta,
+		streams: make(map[string]struct{}),
+		addr:    addr,
+		queue:   make(chan *SSEEvent, queue),
+		done:    make(chan struct{}),
+	}
+}
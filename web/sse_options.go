@@ -0,0 +1,72 @@
+package web
+
+import "time"
+
+const (
+	DefaultSSEBuffer    = 256
+	DefaultSSEQueue     = 64
+	DefaultSSEKeepAlive = 15 * time.Second
+	DefaultSSERetry     = 3 * time.Second
+)
+
+type SSEOption func(o *SSEOptions)
+
+// SSE 配置
+type SSEOptions struct {
+	Buffer    int              // 每个事件流保存的事件数量, 用于 Last-Event-ID 重放
+	Queue     int              // 每个客户端的发送队列长度, 队列已满时断开客户端 (客户端重连后重放)
+	KeepAlive time.Duration    // 保活注释的发送间隔, 为0时不发送
+	Retry     time.Duration    // 客户端重连间隔, 为0时不设置
+	OnConnect OnConnectHandler // 连接事件, 返回错误时拒绝连接
+}
+
+func newSSEOptions(opts ...SSEOption) SSEOptions {
+	o := SSEOptions{
+		Buffer:    DefaultSSEBuffer,
+		Queue:     DefaultSSEQueue,
+		KeepAlive: DefaultSSEKeepAlive,
+		Retry:     DefaultSSERetry,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.Queue < 1 {
+		o.Queue = 1
+	}
+	return o
+}
+
+// 设置事件流重放缓冲长度
+func SSEBuffer(size int) SSEOption {
+	return func(o *SSEOptions) {
+		o.Buffer = size
+	}
+}
+
+// 设置客户端发送队列长度
+func SSEQueue(size int) SSEOption {
+	return func(o *SSEOptions) {
+		o.Queue = size
+	}
+}
+
+// 设置保活注释的发送间隔
+func SSEKeepAlive(t time.Duration) SSEOption {
+	return func(o *SSEOptions) {
+		o.KeepAlive = t
+	}
+}
+
+// 设置客户端重连间隔
+func SSERetry(t time.Duration) SSEOption {
+	return func(o *SSEOptions) {
+		o.Retry = t
+	}
+}
+
+// 设置连接事件, 可用于认证及补充meta信息
+func SSEOnConnect(handler OnConnectHandler) SSEOption {
+	return func(o *SSEOptions) {
+		o.OnConnect = handler
+	}
+}
@@ -0,0 +1,74 @@
+package web
+
+import (
+	"bufio"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSSEEventName(t *testing.T) {
+	s, _ := startSocketServer(t, WithSSE("/sse"))
+	defer s.Close()
+
+	res, err := http.Get("http://" + s.echo.Listener.Addr().String() + "/sse?stream=s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	waitFor(t, func() bool { return s.SSE().MemberCount("s") == 1 })
+
+	// 包含换行的事件名称被拒绝
+	for _, name := range []string{"a\nid: 1", "a\rdata: x", "a\r\n"} {
+		if err := s.SSE().Broadcast("s", name, "x"); err != ErrSSEEventName {
+			t.Fatalf("%q: want ErrSSEEventName, got %v", name, err)
+		}
+		for id := range s.SSE().Clients() {
+			if err := s.SSE().Send(id, name, "x"); err != ErrSSEEventName {
+				t.Fatalf("%q: want ErrSSEEventName, got %v", name, err)
+			}
+		}
+	}
+
+	if err := s.SSE().Broadcast("s", "tick", "line1\nline2"); err != nil {
+		t.Fatal(err)
+	}
+
+	var lines []string
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r := bufio.NewReader(res.Body)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimSuffix(line, "\n")
+			switch {
+			case line == "" && len(lines) > 0:
+				return
+			case line == "", strings.HasPrefix(line, ":"), strings.HasPrefix(line, "retry:"):
+			default:
+				lines = append(lines, line)
+			}
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout")
+	}
+
+	// 只收到合法的事件
+	want := []string{"event: tick", "data: line1", "data: line2"}
+	if len(lines) != len(want)+1 || !strings.HasPrefix(lines[0], "id: ") {
+		t.Fatalf("unexpected event: %q", lines)
+	}
+	for i, line := range want {
+		if lines[i+1] != line {
+			t.Fatalf("unexpected event: %q", lines)
+		}
+	}
+}
@@ -0,0 +1,311 @@
+package web
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const staticConfigFile = "/config.json"
+
+// 预压缩文件, 按优先级排列
+var staticEncodings = []struct {
+	name string
+	ext  string
+}{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// 静态文件规则, 按顺序匹配第一条
+// Pattern 为 path.Match 格式, 不含 / 时匹配文件名, 以 /** 结尾时匹配目录下的所有文件
+type StaticRule struct {
+	Pattern      string            `json:"pattern"`
+	CacheControl string            `json:"cacheControl"` // Cache-Control 响应头
+	Headers      map[string]string `json:"headers"`      // 自定义响应头
+}
+
+// 匹配文件路径 (以 / 开头)
+func (r *StaticRule) match(name string) bool {
+	name = strings.TrimPrefix(name, "/")
+	pattern := strings.TrimPrefix(r.Pattern, "/")
+
+	if strings.HasSuffix(pattern, "/**") {
+		return strings.HasPrefix(name, strings.TrimSuffix(pattern, "**"))
+	}
+	if !strings.Contains(pattern, "/") {
+		name = path.Base(name)
+	}
+	ok, _ := path.Match(pattern, name)
+	return ok
+}
+
+// 静态文件配置, 对应静态目录下的 config.json
+type StaticConfig struct {
+	BaseUrl       string            `json:"baseUrl"`       // 访问路径前缀, 默认为 /
+	Index         string            `json:"index"`         // 默认文件, 默认为 index.html
+	SPA           *bool             `json:"spa"`           // 文件不存在时是否返回默认文件 (单页面应用), 默认启用
+	Precompressed *bool             `json:"precompressed"` // 是否使用预压缩的 .br/.gz 文件, 默认启用
+	Headers       map[string]string `json:"headers"`       // 所有文件的自定义响应头
+	Cache         []StaticRule      `json:"cache"`         // 缓存及响应头规则
+}
+
+func (cfg *StaticConfig) prefix() string {
+	if cfg.BaseUrl == "" {
+		return "/"
+	}
+	return cfg.BaseUrl
+}
+
+func (cfg *StaticConfig) index() string {
+	if cfg.Index == "" {
+		return "index.html"
+	}
+	return cfg.Index
+}
+
+func (cfg *StaticConfig) spa() bool {
+	return cfg.SPA == nil || *cfg.SPA
+}
+
+func (cfg *StaticConfig) precompressed() bool {
+	return cfg.Precompressed == nil || *cfg.Precompressed
+}
+
+func (cfg *StaticConfig) rule(name string) *StaticRule {
+	for i := range cfg.Cache {
+		if cfg.Cache[i].match(name) {
+			return &cfg.Cache[i]
+		}
+	}
+	return nil
+}
+
+// 静态文件系统, 可使用编译时生成的内置文件系统 (如 statik, vfsgen 等)
+type StaticFS struct {
+	FS     http.FileSystem
+	Config *StaticConfig // 为空时读取文件系统中的 config.json
+}
+
+// 读取文件系统中的 config.json, 不存在时使用默认配置
+func loadStaticConfig(fs http.FileSystem) (*StaticConfig, error) {
+	cfg := new(StaticConfig)
+
+	f, err := fs.Open(staticConfigFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cfg, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	b, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, cfg); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// 静态文件服务
+type staticHandler struct {
+	sync.Mutex
+	fs    http.FileSystem
+	cfg   *StaticConfig
+	etags map[string]string // 无修改时间的文件 (内置文件系统) 按内容生成的ETag
+}
+
+func (h *staticHandler) middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		req := c.Request()
+		if req.Method != http.MethodGet && req.Method != http.MethodHead {
+			return next(c)
+		}
+
+		p := req.URL.Path
+		if strings.HasSuffix(c.Path(), "*") { // 使用路径前缀时
+			p = c.Param("*")
+		}
+		p, err := url.PathUnescape(p)
+		if err != nil {
+			return err
+		}
+
+		if ok, err := h.serve(c, path.Clean("/"+p)); ok || err != nil {
+			return err
+		}
+
+		err = next(c)
+		if he, ok := err.(*echo.HTTPError); ok && he.Code == http.StatusNotFound && h.cfg.spa() {
+			if ok, err := h.serve(c, "/"+h.cfg.index()); ok || err != nil {
+				return err
+			}
+		}
+		return err
+	}
+}
+
+// 输出文件, 文件不存在时返回false
+func (h *staticHandler) serve(c echo.Context, name string) (bool, error) {
+	f, fi, err := h.open(name)
+	if err != nil {
+		return false, nil
+	}
+	if fi.IsDir() {
+		f.Close()
+		name = path.Join(name, h.cfg.index())
+		if f, fi, err = h.open(name); err != nil || fi.IsDir() {
+			if err == nil {
+				f.Close()
+			}
+			return false, nil
+		}
+	}
+	defer f.Close()
+
+	req := c.Request()
+	header := c.Response().Header()
+	for k, v := range h.cfg.Headers {
+		header.Set(k, v)
+	}
+	if rule := h.cfg.rule(name); rule != nil {
+		if rule.CacheControl != "" {
+			header.Set("Cache-Control", rule.CacheControl)
+		}
+		for k, v := range rule.Headers {
+			header.Set(k, v)
+		}
+	}
+
+	var content http.File = f
+	var encoding string
+	if h.cfg.precompressed() {
+		vary := false
+		for _, enc := range staticEncodings {
+			cf, cfi, err := h.open(name + enc.ext)
+			if err != nil || cfi.IsDir() {
+				if err == nil {
+					cf.Close()
+				}
+				continue
+			}
+			if !vary {
+				vary = true
+				header.Add(echo.HeaderVary, echo.HeaderAcceptEncoding)
+			}
+			if encoding != "" || !acceptEncoding(req, enc.name) {
+				cf.Close()
+				continue
+			}
+			defer cf.Close()
+			content, fi, encoding = cf, cfi, enc.name
+		}
+	}
+
+	ctype := mime.TypeByExtension(path.Ext(name))
+	if encoding != "" {
+		header.Set(echo.HeaderContentEncoding, encoding)
+		if ctype == "" {
+			ctype = echo.MIMEOctetStream
+		}
+	}
+	if ctype != "" {
+		header.Set(echo.HeaderContentType, ctype)
+	}
+
+	etag, err := h.etag(name, encoding, content, fi)
+	if err != nil {
+		return true, err
+	}
+	header.Set("ETag", etag)
+
+	// 处理 If-None-Match, If-Modified-Since 及 Range
+	http.ServeContent(c.Response(), req, name, fi.ModTime(), content)
+	return true, nil
+}
+
+func (h *staticHandler) open(name string) (http.File, os.FileInfo, error) {
+	f, err := h.fs.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	return f, fi, nil
+}
+
+// 生成ETag, 有修改时间时使用文件大小及修改时间, 否则使用文件内容的哈希 (缓存), 不同编码的文件使用不同的ETag
+func (h *staticHandler) etag(name string, encoding string, f http.File, fi os.FileInfo) (string, error) {
+	suffix := ""
+	if encoding != "" {
+		suffix = "-" + encoding
+	}
+	if !fi.ModTime().IsZero() {
+		return fmt.Sprintf(`"%x-%x%s"`, fi.ModTime().UnixNano(), fi.Size(), suffix), nil
+	}
+
+	key := name + suffix
+
+	h.Lock()
+	defer h.Unlock()
+
+	if etag, ok := h.etags[key]; ok {
+		return etag, nil
+	}
+
+	hash := sha1.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	etag := fmt.Sprintf(`"%x%s"`, hash.Sum(nil), suffix)
+	h.etags[key] = etag
+	return etag, nil
+}
+
+// 客户端是否接受指定的编码
+func acceptEncoding(req *http.Request, encoding string) bool {
+	for _, part := range strings.Split(req.Header.Get(echo.HeaderAcceptEncoding), ",") {
+		params := strings.Split(part, ";")
+		if strings.TrimSpace(params[0]) != encoding {
+			continue
+		}
+		for _, param := range params[1:] {
+			if param = strings.TrimSpace(param); strings.HasPrefix(param, "q=") {
+				q, err := strconv.ParseFloat(param[2:], 64)
+				return err == nil && q > 0
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func newStaticHandler(fs http.FileSystem, cfg *StaticConfig) *staticHandler {
+	return &staticHandler{
+		fs:    fs,
+		cfg:   cfg,
+		etags: make(map[string]string),
+	}
+}
@@ -47,49 +47,187 @@
 package web
 
 import (
+	"github.com/cbwfree/micro-core/captcha"
+	"github.com/go-playground/locales"
 	"github.com/go-playground/locales/en"
 	"github.com/go-playground/locales/zh"
 	"github.com/go-playground/universal-translator"
 	"github.com/labstack/echo/v4"
 	log "github.com/micro/go-micro/v2/logger"
 	"gopkg.in/go-playground/validator.v9"
-	translations "gopkg.in/go-playground/validator.v9/translations/zh"
+	enTranslations "gopkg.in/go-playground/validator.v9/translations/en"
+	zhTranslations "gopkg.in/go-playground/validator.v9/translations/zh"
 	"net/http"
-	"regexp"
+	"reflect"
+	"strings"
 )
 
-type webValidator struct {
-	translator ut.Translator
-	validator  *validator.Validate
+// 字段验证错误
+type FieldError struct {
+	Field   string `json:"field"`
+	Tag     string `json:"tag"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
 }
 
-func (wv *webValidator) Validate(i interface{}) error {
+// 验证错误, 包含全部字段的验证错误
+type ValidationErrors struct {
+	errs validator.ValidationErrors
+	wv   *WebValidator
+}
+
+func (ve *ValidationErrors) Error() string {
+	var msgs []string
+	for _, fe := range ve.Translate(DefaultLocale) {
+		msgs = append(msgs, fe.Message)
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Translate 翻译为指定语言的字段错误列表
+func (ve *ValidationErrors) Translate(locale string) []*FieldError {
+	trans := ve.wv.translator(locale)
+	fields := make([]*FieldError, 0, len(ve.errs))
+	for _, fe := range ve.errs {
+		fields = append(fields, &FieldError{
+			Field:   fe.Field(),
+			Tag:     fe.Tag(),
+			Param:   fe.Param(),
+			Message: fe.Translate(trans),
+		})
+	}
+	return fields
+}
+
+// Web数据验证器
+type WebValidator struct {
+	uni         *ut.UniversalTranslator
+	translators map[string]ut.Translator
+	validator   *validator.Validate
+}
+
+func (wv *WebValidator) Validate(i interface{}) error {
 	if err := wv.validator.Struct(i); err != nil {
 		if errs, ok := err.(validator.ValidationErrors); ok {
-			for _, e := range errs {
-				return echo.NewHTTPError(http.StatusBadRequest, e.Translate(wv.translator))
-			}
-		} else {
-			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+			return &ValidationErrors{errs: errs, wv: wv}
+		}
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	return nil
+}
+
+// 获取语言翻译器, 未注册的语言使用默认语言
+func (wv *WebValidator) translator(locale string) ut.Translator {
+	if trans, ok := wv.translators[normalizeLocale(locale)]; ok {
+		return trans
+	}
+	if trans, ok := wv.translators[DefaultLocale]; ok {
+		return trans
+	}
+	return wv.translators[LocaleEn]
+}
+
+// RegisterLocale 注册语言, register 用于注册内置标签的翻译 (参考 validator.v9/translations 包)
+func (wv *WebValidator) RegisterLocale(lt locales.Translator, register func(v *validator.Validate, trans ut.Translator) error) error {
+	if err := wv.uni.AddTranslator(lt, true); err != nil {
+		return err
+	}
+
+	locale := normalizeLocale(lt.Locale())
+	trans, _ := wv.uni.GetTranslator(lt.Locale())
+	if register != nil {
+		if err := register(wv.validator, trans); err != nil {
+			return err
+		}
+	}
+	wv.translators[locale] = trans
+
+	// 确保消息目录中存在该语言, 以便 Accept-Language 协商
+	RegisterMessages(locale, nil)
+
+	return nil
+}
+
+// RegisterTranslation 注册标签的多语言翻译, texts 为 语言 => 翻译文本, {0} 为字段名, {1} 为标签参数
+func (wv *WebValidator) RegisterTranslation(tag string, texts map[string]string) {
+	for locale, text := range texts {
+		trans, ok := wv.translators[normalizeLocale(locale)]
+		if !ok {
+			continue
+		}
+		if err := wv.validator.RegisterTranslation(tag, trans, regFunc(tag, text, true), tranFunc); err != nil {
+			log.Warnf("register validator translation error: %s, %s", tag, err)
 		}
 	}
+}
+
+// Validator 获取底层验证器
+func (wv *WebValidator) Validator() *validator.Validate {
+	return wv.validator
+}
+
+// RegisterValidation 注册自定义验证标签及其多语言翻译
+func (wv *WebValidator) RegisterValidation(tag string, fn validator.Func, texts map[string]string, callEvenIfNull ...bool) error {
+	if err := wv.validator.RegisterValidation(tag, fn, callEvenIfNull...); err != nil {
+		return err
+	}
+	wv.RegisterTranslation(tag, texts)
 	return nil
 }
 
-func NewWebValidator() *webValidator {
+// RegisterAlias 注册验证标签别名, 如 RegisterAlias("username", "required,min=4,max=20,alphanum")
+func (wv *WebValidator) RegisterAlias(alias, tags string, texts map[string]string) {
+	wv.validator.RegisterAlias(alias, tags)
+	wv.RegisterTranslation(alias, texts)
+}
+
+// RegisterStructValidation 注册结构体级别的跨字段验证, 验证函数中通过 sl.ReportError 报告错误
+func (wv *WebValidator) RegisterStructValidation(fn validator.StructLevelFunc, types ...interface{}) {
+	wv.validator.RegisterStructValidation(fn, types...)
+}
+
+// RegisterCaptcha 注册验证码校验 (captcha 标签)
+func (wv *WebValidator) RegisterCaptcha(svc *captcha.Service) {
+	_ = wv.validator.RegisterValidation("captcha", validCaptcha(svc))
+	wv.RegisterTranslation("captcha", map[string]string{
+		LocaleZh: "验证码错误",
+		LocaleEn: "invalid captcha",
+	})
+}
+
+func NewWebValidator() *WebValidator {
 	valid := validator.New()
+
+	// 使用JSON字段名作为错误字段名
+	valid.RegisterTagNameFunc(func(f reflect.StructField) string {
+		for _, tag := range []string{"json", "query", "form"} {
+			name := strings.SplitN(f.Tag.Get(tag), ",", 2)[0]
+			if name == "-" {
+				return ""
+			}
+			if name != "" {
+				return name
+			}
+		}
+		return f.Name
+	})
+
 	enLocale := en.New()
-	zhLocale := zh.New()
-	translator := ut.New(enLocale, enLocale, zhLocale)
-	trans, _ := translator.GetTranslator("zh")
-	// 注册默认转换语言, 中文语言需要自行参考 translations 包实现
-	_ = translations.RegisterDefaultTranslations(valid, trans)
+	wv := &WebValidator{
+		uni:         ut.New(enLocale, enLocale),
+		translators: make(map[string]ut.Translator),
+		validator:   valid,
+	}
+
+	_ = wv.RegisterLocale(enLocale, enTranslations.RegisterDefaultTranslations)
+	_ = wv.RegisterLocale(zh.New(), zhTranslations.RegisterDefaultTranslations)
 
 	// 自定义验证
-	_ = valid.RegisterValidation("mobile", validMobile)
-	_ = valid.RegisterTranslation("mobile", trans, regFunc("mobile", "{0}不是有效的手机号", false), tranFunc)
+	for _, rule := range customRules {
+		_ = wv.RegisterValidation(rule.tag, rule.fn, rule.texts)
+	}
 
-	return &webValidator{validator: valid, translator: trans}
+	return wv
 }
 
 func regFunc(tag string, translation string, override bool) validator.RegisterTranslationsFunc {
@@ -99,18 +237,10 @@ func regFunc(tag string, translation string, override bool) validator.RegisterTr
 }
 
 func tranFunc(ut ut.Translator, fe validator.FieldError) string {
-	t, err := ut.T(fe.Tag(), fe.Field())
+	t, err := ut.T(fe.Tag(), fe.Field(), fe.Param())
 	if err != nil {
 		log.Warnf("error translating FieldError: %#v", fe)
 		return fe.(error).Error()
 	}
 	return t
 }
-
-// ----- 自定义验证
-
-// validMobile 验证手机号
-func validMobile(fl validator.FieldLevel) bool {
-	reg := regexp.MustCompile(`^1\d{10}$`)
-	return reg.MatchString(fl.Field().String())
-}
@@ -0,0 +1,263 @@
+package web
+
+import (
+	"gopkg.in/go-playground/validator.v9"
+	"net"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// 自定义验证规则
+type customRule struct {
+	tag   string
+	fn    validator.Func
+	texts map[string]string
+}
+
+// 内置的自定义验证
+//
+//	mobile			手机号
+//	idcard			居民身份证号码 (18位, 校验出生日期及校验码)
+//	uscc			统一社会信用代码
+//	bankcard		银行卡号 (Luhn校验)
+//	password		强密码, 至少8位, 参数为需要包含的字符类型数量 (大写, 小写, 数字, 特殊字符), 默认为3, Usage: password=4
+//	iplist			IP或CIDR列表, 字符串使用,分隔, 也可以是字符串数组
+//	timeafter		时间晚于指定字段, 支持 time.Time, Unix时间戳及常用格式的时间字符串, Usage: timeafter=StartTime
+var customRules = []customRule{
+	{"mobile", validMobile, map[string]string{
+		LocaleZh: "{0}不是有效的手机号",
+		LocaleEn: "{0} must be a valid mobile number",
+	}},
+	{"idcard", validIdCard, map[string]string{
+		LocaleZh: "{0}不是有效的身份证号码",
+		LocaleEn: "{0} must be a valid ID card number",
+	}},
+	{"uscc", validUSCC, map[string]string{
+		LocaleZh: "{0}不是有效的统一社会信用代码",
+		LocaleEn: "{0} must be a valid unified social credit code",
+	}},
+	{"bankcard", validBankCard, map[string]string{
+		LocaleZh: "{0}不是有效的银行卡号",
+		LocaleEn: "{0} must be a valid bank card number",
+	}},
+	{"password", validPassword, map[string]string{
+		LocaleZh: "{0}强度不足, 至少8位且包含大小写字母, 数字及特殊字符中的多种",
+		LocaleEn: "{0} is too weak, it must be at least 8 characters and mix upper, lower, digit and special characters",
+	}},
+	{"iplist", validIPList, map[string]string{
+		LocaleZh: "{0}必须是有效的IP或CIDR列表",
+		LocaleEn: "{0} must be a list of valid IP addresses or CIDRs",
+	}},
+	{"timeafter", validTimeAfter, map[string]string{
+		LocaleZh: "{0}必须晚于{1}",
+		LocaleEn: "{0} must be after {1}",
+	}},
+}
+
+var (
+	mobileRegexp = regexp.MustCompile(`^1\d{10}$`)
+	idCardRegexp = regexp.MustCompile(`^\d{17}[\dXx]$`)
+	usccRegexp   = regexp.MustCompile(`^[0-9A-HJ-NPQRTUWXY]{2}\d{6}[0-9A-HJ-NPQRTUWXY]{10}$`)
+	bankRegexp   = regexp.MustCompile(`^\d{12,19}$`)
+
+	idCardWeights = []int{7, 9, 10, 5, 8, 4, 2, 1, 6, 3, 7, 9, 10, 5, 8, 4, 2}
+	idCardChecks  = "10X98765432"
+
+	usccChars   = "0123456789ABCDEFGHJKLMNPQRTUWXY"
+	usccWeights = []int{1, 3, 9, 27, 19, 26, 16, 17, 20, 29, 25, 13, 8, 24, 10, 30, 28}
+
+	timeLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02", "15:04:05", "15:04"}
+)
+
+// validMobile 验证手机号
+func validMobile(fl validator.FieldLevel) bool {
+	return mobileRegexp.MatchString(fl.Field().String())
+}
+
+// validIdCard 验证居民身份证号码
+func validIdCard(fl validator.FieldLevel) bool {
+	id := strings.ToUpper(fl.Field().String())
+	if !idCardRegexp.MatchString(id) {
+		return false
+	}
+
+	if _, err := time.Parse("20060102", id[6:14]); err != nil {
+		return false
+	}
+
+	var sum int
+	for i, w := range idCardWeights {
+		sum += int(id[i]-'0') * w
+	}
+
+	return idCardChecks[sum%11] == id[17]
+}
+
+// validUSCC 验证统一社会信用代码
+func validUSCC(fl validator.FieldLevel) bool {
+	code := strings.ToUpper(fl.Field().String())
+	if !usccRegexp.MatchString(code) {
+		return false
+	}
+
+	var sum int
+	for i, w := range usccWeights {
+		sum += strings.IndexByte(usccChars, code[i]) * w
+	}
+
+	check := 31 - sum%31
+	if check == 31 {
+		check = 0
+	}
+
+	return usccChars[check] == code[17]
+}
+
+// validBankCard 验证银行卡号 (Luhn)
+func validBankCard(fl validator.FieldLevel) bool {
+	card := fl.Field().String()
+	if !bankRegexp.MatchString(card) {
+		return false
+	}
+	return luhn(card)
+}
+
+func luhn(number string) bool {
+	var sum int
+	double := false
+	for i := len(number) - 1; i >= 0; i-- {
+		n := int(number[i] - '0')
+		if double {
+			if n *= 2; n > 9 {
+				n -= 9
+			}
+		}
+		sum += n
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// validPassword 验证强密码
+func validPassword(fl validator.FieldLevel) bool {
+	pwd := fl.Field().String()
+	if len(pwd) < 8 {
+		return false
+	}
+
+	need := 3
+	if n, err := strconv.Atoi(fl.Param()); err == nil && n > 0 {
+		need = n
+	}
+
+	var upper, lower, digit, special int
+	for _, r := range pwd {
+		switch {
+		case unicode.IsUpper(r):
+			upper = 1
+		case unicode.IsLower(r):
+			lower = 1
+		case unicode.IsDigit(r):
+			digit = 1
+		case unicode.IsSpace(r):
+			return false
+		default:
+			special = 1
+		}
+	}
+
+	return upper+lower+digit+special >= need
+}
+
+// validIPList 验证IP或CIDR列表
+func validIPList(fl validator.FieldLevel) bool {
+	var items []string
+	field := fl.Field()
+	switch field.Kind() {
+	case reflect.String:
+		items = strings.FieldsFunc(field.String(), func(r rune) bool {
+			return r == ',' || r == '\n' || r == ' '
+		})
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < field.Len(); i++ {
+			if field.Index(i).Kind() != reflect.String {
+				return false
+			}
+			items = append(items, strings.TrimSpace(field.Index(i).String()))
+		}
+	default:
+		return false
+	}
+
+	for _, item := range items {
+		if strings.Contains(item, "/") {
+			if _, _, err := net.ParseCIDR(item); err != nil {
+				return false
+			}
+		} else if net.ParseIP(item) == nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+// validTimeAfter 验证时间晚于指定字段, 任一字段为空时跳过
+func validTimeAfter(fl validator.FieldLevel) bool {
+	other, kind, ok := fl.GetStructFieldOK()
+	if !ok || kind == reflect.Invalid {
+		return false
+	}
+
+	end, ok := toTime(fl.Field())
+	if !ok {
+		return false
+	}
+	start, ok := toTime(other)
+	if !ok {
+		return false
+	}
+	if end.IsZero() || start.IsZero() {
+		return true
+	}
+
+	return end.After(start)
+}
+
+func toTime(v reflect.Value) (time.Time, bool) {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return time.Time{}, true
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		if t, ok := v.Interface().(time.Time); ok {
+			return t, true
+		}
+	case reflect.Int, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		n := v.Convert(reflect.TypeOf(int64(0))).Int()
+		if n == 0 {
+			return time.Time{}, true
+		}
+		return time.Unix(n, 0), true
+	case reflect.String:
+		s := strings.TrimSpace(v.String())
+		if s == "" {
+			return time.Time{}, true
+		}
+		for _, layout := range timeLayouts {
+			if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+				return t, true
+			}
+		}
+	}
+
+	return time.Time{}, false
+}